@@ -0,0 +1,71 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	v3config "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v3/config"
+	v4 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/dryrun"
+)
+
+const integrationUsage = `Usage: newrelic-infra-ctl integration test [-config <agent config>] <integrations config>
+
+Runs once the integrations of a v4 integrations config file, printing their parsed output and the data the agent
+would send for them, without talking to the agent or New Relic. Daemon integrations are stopped after their first
+payload or heartbeat. Exits with non-zero status if any problem is found.
+`
+
+// runIntegrationCommand runs the "integration" subcommands, returning the process exit code.
+func runIntegrationCommand(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] != "test" {
+		fmt.Fprint(os.Stderr, integrationUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("integration test", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, integrationUsage)
+		flags.PrintDefaults()
+	}
+	agentConfigFile := flags.String("config", "", "Agent configuration file, used to look for the integration executables and definitions")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	c, err := config.LoadConfig(*agentConfigFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't load agent configuration: %s\n", err)
+		return 1
+	}
+
+	sourceDirs := c.IntegrationsSourceDirs()
+	integrationCfg := v4.NewConfig(c.Verbose, c.Features, c.PassthroughEnvironment, nil, sourceDirs)
+	pluginRegistry := v3config.NewPluginRegistry(sourceDirs, c.PluginInstanceDirs)
+	if err := pluginRegistry.LoadPlugins(); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't load integration definitions: %s\n", err)
+		return 1
+	}
+
+	tester := dryrun.Tester{
+		Lookup:         v4.NewInstancesLookup(integrationCfg),
+		PassthroughEnv: c.PassthroughEnvironment,
+		PluginRegistry: pluginRegistry,
+		Out:            os.Stdout,
+	}
+	if err := tester.Test(ctx, flags.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "Integration test failed: %s\n", err)
+		return 1
+	}
+	fmt.Println("Integration test succeeded")
+	return 0
+}
@@ -61,6 +61,10 @@ func main() {
 		cancel()
 	}()
 
+	if flag.NArg() > 0 && flag.Arg(0) == "integration" {
+		os.Exit(runIntegrationCommand(ctx, flag.Args()[1:]))
+	}
+
 	client, err := getClient()
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to initialize the notification client.")
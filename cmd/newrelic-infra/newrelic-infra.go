@@ -8,7 +8,6 @@ import (
 	context2 "context"
 	"flag"
 	"fmt"
-	v3 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v3"
 	v3config "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v3/config"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/logs"
 	dm2 "github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/dm"
@@ -19,7 +18,6 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
@@ -40,10 +38,13 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/agent/status"
 	"github.com/newrelic/infrastructure-agent/internal/socketapi"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
-	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/files"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/allowlist"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/track"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 
 	"github.com/newrelic/infrastructure-agent/cmd/newrelic-infra/initialize"
@@ -56,7 +57,6 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/disk"
 	"github.com/newrelic/infrastructure-agent/pkg/fs/systemd"
-	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers/recover"
 	v4 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4"
 	wlog "github.com/newrelic/infrastructure-agent/pkg/log"
@@ -66,6 +66,7 @@ import (
 var (
 	configFile   string
 	validate     bool
+	dryRun       bool
 	showVersion  bool
 	debug        bool
 	cpuprofile   string
@@ -85,6 +86,7 @@ func elapsedTime() time.Duration {
 func init() {
 	flag.StringVar(&configFile, "config", "", "Overrides default configuration file")
 	flag.BoolVar(&validate, "validate", false, "Validate agent config and exit")
+	flag.BoolVar(&dryRun, "dry-run", false, "Writes the payloads to dry_run_file instead of sending them to New Relic")
 	flag.BoolVar(&showVersion, "version", false, "Shows version details")
 	flag.BoolVar(&debug, "debug", false, "Enables agent debugging functionality")
 	flag.StringVar(&cpuprofile, "cpuprofile", "", "Writes cpu profile to `file`")
@@ -169,6 +171,12 @@ func main() {
 
 	timedLog.Debug("Loading configuration.")
 
+	// applied as environment variable so it's considered while normalizing the configuration, e.g. the license key
+	// isn't required in dry run mode
+	if dryRun {
+		_ = os.Setenv("NRIA_DRY_RUN", "true")
+	}
+
 	cfg, err := config.LoadConfig(configFile)
 
 	if validate {
@@ -264,14 +272,7 @@ var aslog = wlog.WithComponent("AgentService").WithFields(logrus.Fields{
 })
 
 func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
-	pluginSourceDirs := []string{
-		c.CustomPluginInstallationDir,
-		filepath.Join(c.AgentDir, "custom-integrations"),
-		filepath.Join(c.AgentDir, config.DefaultIntegrationsDir),
-		filepath.Join(c.AgentDir, "bundled-plugins"),
-		filepath.Join(c.AgentDir, "plugins"),
-	}
-	pluginSourceDirs = helpers.RemoveEmptyAndDuplicateEntries(pluginSourceDirs)
+	pluginSourceDirs := c.IntegrationsSourceDirs()
 
 	integrationCfg := v4.NewConfig(
 		c.Verbose,
@@ -280,6 +281,9 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 		c.PluginInstanceDirs,
 		pluginSourceDirs,
 	)
+	integrationCfg.MaxConcurrentIntegrations = c.MaxConcurrentIntegrations
+	integrationCfg.StartJitter = c.IntegrationsStartJitter
+	integrationCfg.ValidatePayloads = c.IntegrationsPayloadValidation
 
 	userAgent := agent.GenerateUserAgent("New Relic Infrastructure Agent", buildVersion)
 	transport := backendhttp.BuildTransport(c, backendhttp.ClientTimeout)
@@ -288,13 +292,24 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 	ccSvcURL := fmt.Sprintf("%s%s", cmdChannelURL, c.CommandChannelEndpoint)
 	caClient := commandapi.NewClient(ccSvcURL, c.License, userAgent, httpClient.Do)
 	ffManager := feature_flags.NewManager(c.Features)
-	il := newInstancesLookup(integrationCfg)
+	il := v4.NewInstancesLookup(integrationCfg)
 
 	fatal := func(err error, message string) {
 		aslog.WithError(err).Error(message)
 		os.Exit(1)
 	}
 
+	if c.IntegrationsAllowList != "" {
+		publicKey, err := allowlist.ParsePublicKey(c.IntegrationsAllowListPublicKey)
+		if err != nil {
+			fatal(err, "Can't load the integrations allow-list.")
+		}
+		if integrationCfg.Verifier, err = allowlist.Load(c.IntegrationsAllowList, publicKey); err != nil {
+			fatal(err, "Can't load the integrations allow-list.")
+		}
+		aslog.WithField("file", c.IntegrationsAllowList).Info("Integrations allow-list enabled.")
+	}
+
 	aslog.Info("Checking network connectivity...")
 	err := waitForNetwork(c.CollectorURL, c.StartupConnectionTimeout, c.StartupConnectionRetries, transport)
 	if err != nil {
@@ -333,6 +348,15 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 
 	selfInstrumentation.InitSelfInstrumentation(c, agt.Context.HostnameResolver())
 
+	var sampleStore *sample.Store
+	var selfGauges *selfInstrumentation.GaugesRecorder
+	if c.StatusServerEnabled && c.StatusServerMetricsEnabled {
+		selfGauges = selfInstrumentation.NewGaugesRecorder(selfInstrumentation.SelfInstrumentation)
+		selfInstrumentation.SelfInstrumentation = selfGauges
+		sampleStore = sample.NewStore(httpapi.ExportedSamplesTTL, httpapi.ExportedSamples)
+		agt.Context.SetSampleStore(sampleStore)
+	}
+
 	defer agt.Terminate()
 
 	if err := initialize.AgentService(c); err != nil {
@@ -368,6 +392,9 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 		fatal(err, "Can't load plugins.")
 	}
 
+	// execution status of the integrations, exposed by the status API
+	integrationsMonitor := monitor.New(agt.Context.SendEvent)
+
 	integrationEmitter := emitter.NewIntegrationEmittor(agt, dmEmitter, ffManager)
 	integrationManager := v4.NewManager(
 		integrationCfg,
@@ -379,6 +406,7 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 		tracker,
 		agt.Context.IDLookup(),
 		pluginRegistry,
+		integrationsMonitor,
 	)
 
 	// Command channel handlers
@@ -429,6 +457,15 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 				apiSrv.Status.Enable("localhost", c.StatusServerPort)
 			}
 
+			apiSrv.ExposeIntegrations(integrationsMonitor)
+
+			if sampleStore != nil {
+				apiSrv.ExposeMetrics(prometheus.Gatherers{
+					httpapi.NewSamplesGatherer(sampleStore, selfGauges.Gauges),
+					instruments.GetGatherer(),
+				})
+			}
+
 			if err != nil {
 				aslog.WithError(err).Error("cannot run api server")
 			} else {
@@ -454,7 +491,9 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 		FluentBitParsersPath: c.FluentBitParsersPath,
 		FluentBitVerbose:     c.Verbose != 0 && trace.IsEnabled(trace.LOG_FWD),
 	}
-	if fbIntCfg.IsLogForwarderAvailable() {
+	if c.DryRun {
+		aslog.Info("Dry run mode enabled, payloads won't be sent to New Relic. Log forwarding is disabled.")
+	} else if fbIntCfg.IsLogForwarderAvailable() {
 		logCfgLoader := logs.NewFolderLoader(logFwCfg, agt.Context.Identity, agt.Context.HostnameResolver())
 		logSupervisor := v4.NewFBSupervisor(
 			fbIntCfg,
@@ -467,6 +506,19 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 	} else {
 		aslog.Debug("Log forwarder is not available for this platform. The agent will start without log forwarding support.")
 	}
+	// the logging configs with the native forwarder don't require Fluent Bit
+	if !c.DryRun && logFwCfg.ConfigsDir != "" {
+		nativeCfgLoader := logs.NewFolderLoader(logFwCfg, agt.Context.Identity, agt.Context.HostnameResolver())
+		nativeForwarder := logs.NewNativeForwarder(
+			nativeCfgLoader,
+			httpClient.Do,
+			userAgent,
+			dmEmitter,
+			agt.Context.AgentIDUpdateNotifier(),
+			agt.Context.HostnameChangeNotifier(),
+		)
+		go nativeForwarder.Run(agt.Context.Ctx)
+	}
 
 	ffHandle.SetOHIHandler(integrationManager)
 
@@ -532,27 +584,6 @@ func initInstrumentation(ctx context2.Context, agentMetricsEndpoint string) (ins
 	return instruments, nil
 }
 
-// newInstancesLookup creates an instance lookup that:
-// - looks in the v3 legacy definitions repository for defined commands
-// - looks in the definition folders (and bin/ subfolders) for executable names
-func newInstancesLookup(cfg v4.Configuration) integration.InstancesLookup {
-	const executablesSubFolder = "bin"
-
-	var execFolders []string
-	for _, df := range cfg.DefinitionFolders {
-		execFolders = append(execFolders, df)
-		execFolders = append(execFolders, filepath.Join(df, executablesSubFolder))
-	}
-	legacyDefinedCommands := v3.NewDefinitionsRepo(v3.LegacyConfig{
-		DefinitionFolders: cfg.DefinitionFolders,
-		Verbose:           cfg.Verbose,
-	})
-	return integration.InstancesLookup{
-		Legacy: legacyDefinedCommands.NewDefinitionCommand,
-		ByName: files.Executables{Folders: execFolders}.Path,
-	}
-}
-
 // configureLogFormat checks the config and sets the log format accordingly.
 func configureLogFormat(logFormat string) {
 	if logFormat == config.LogFormatJSON {
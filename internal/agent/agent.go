@@ -107,6 +107,9 @@ type AgentContext interface {
 	Context() context2.Context
 	SendData(PluginOutput)
 	SendEvent(event sample.Event, entityKey entity.Key)
+	// IncludeEvent returns whether the event is accepted by the metrics matchers, that is, whether SendEvent
+	// would send it.
+	IncludeEvent(event interface{}) bool
 	Unregister(ids.PluginID)
 	// Reconnecting tells the agent that this plugin must be re-executed when the agent reconnects after long time
 	// disconnected (> 24 hours).
@@ -153,6 +156,7 @@ type context struct {
 	EntityMap          entity.KnownIDs
 	idLookup           host.IDLookup
 	shouldIncludeEvent sampler.IncludeSampleMatchFn
+	sampleStore        *sample.Store // Optional store keeping the latest samples to be exposed locally
 }
 
 func (c *context) Context() context2.Context {
@@ -273,7 +277,10 @@ func NewAgent(
 	cloudHarvester.Initialize()
 
 	idLookupTable := NewIdLookup(hostnameResolver, cloudHarvester, cfg.DisplayName)
-	sampleMatchFn := sampler.NewSampleMatchFn(cfg.EnableProcessMetrics, cfg.IncludeMetricsMatchers, ffRetriever)
+	if err = sampler.ValidateMatchers(cfg.IncludeMetricsMatchers, cfg.ExcludeMetricsMatchers); err != nil {
+		return nil, fmt.Errorf("invalid metrics matchers configuration: %v", err)
+	}
+	sampleMatchFn := sampler.NewSampleMatchFn(cfg.EnableProcessMetrics, cfg.IncludeMetricsMatchers, cfg.ExcludeMetricsMatchers, ffRetriever)
 	ctx := NewContext(cfg, buildVersion, hostnameResolver, idLookupTable, sampleMatchFn)
 
 	agentKey, err := idLookupTable.AgentKey()
@@ -1017,6 +1024,10 @@ func (c *context) ActiveEntitiesChannel() chan string {
 	return c.activeEntities
 }
 
+func (c *context) IncludeEvent(event interface{}) bool {
+	return c.shouldIncludeEvent(event)
+}
+
 func (c *context) SendEvent(event sample.Event, entityKey entity.Key) {
 	_, txn := instrumentation.SelfInstrumentation.StartTransaction(context2.Background(), "agent.queue_event")
 	defer txn.End()
@@ -1057,7 +1068,17 @@ func (c *context) SendEvent(event sample.Event, entityKey entity.Key) {
 		alog.WithField(
 			"entityKey", entityKey,
 		).WithError(err).Error("could not queue event")
+		return
 	}
+
+	if c.sampleStore != nil {
+		c.sampleStore.Record(event)
+	}
+}
+
+// SetSampleStore sets a store to keep the latest samples sent by the agent. It must be called before the agent runs.
+func (c *context) SetSampleStore(store *sample.Store) {
+	c.sampleStore = store
 }
 
 func (c *context) Unregister(id ids.PluginID) {
@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -80,7 +81,8 @@ type metricsIngestSender struct {
 	agentIDProvide           id.Provide
 	connectEnabled           bool
 	getBackoffTimer          func(time.Duration) *time.Timer
-	postCount                uint64 // counts post requests for debugging purposes
+	postCount                uint64      // counts post requests for debugging purposes
+	spool                    *eventSpool // Optional on-disk storage for batches that could not be queued or sent
 }
 
 func newMetricsIngestSender(ctx *context, licenseKey, userAgent string, httpClient backendhttp.Client, connectEnabled bool) *metricsIngestSender {
@@ -108,6 +110,11 @@ func newMetricsIngestSender(ctx *context, licenseKey, userAgent string, httpClie
 		maxMetricsBatchSizeBytes = config.DefaultMaxMetricsBatchSizeBytes
 	}
 
+	var spool *eventSpool
+	if cfg.MetricsSpoolEnabled {
+		spool = newMetricsSpool(cfg)
+	}
+
 	return &metricsIngestSender{
 		eventQueue:               make(chan eventData, eventQueue),
 		batchQueue:               make(chan eventBatch, batchQueue),
@@ -123,7 +130,41 @@ func newMetricsIngestSender(ctx *context, licenseKey, userAgent string, httpClie
 		connectEnabled:           connectEnabled,
 		getBackoffTimer:          time.NewTimer,
 		postCount:                0,
+		spool:                    spool,
+	}
+}
+
+// newMetricsSpool creates the metrics spool under the agent data directory. Spooling is disabled, and the sender
+// keeps the in-memory only behaviour, if the spool cannot be created.
+func newMetricsSpool(cfg *config.Config) *eventSpool {
+	dataDir := cfg.AppDataDir
+	if dataDir == "" {
+		dataDir = cfg.AgentDir
+	}
+	spoolDir := filepath.Join(dataDir, "spool", "metrics")
+
+	maxAge, err := time.ParseDuration(cfg.MetricsSpoolMaxAge)
+	if err != nil {
+		ilog.WithError(err).WithField("actualValue", cfg.MetricsSpoolMaxAge).
+			WithField("defaultValue", config.DefaultMetricsSpoolMaxAge).
+			Warn("invalid metrics_spool_max_age value, using default")
+		maxAge, _ = time.ParseDuration(config.DefaultMetricsSpoolMaxAge)
+	}
+
+	maxSize := cfg.MetricsSpoolMaxSizeBytes
+	if maxSize <= 0 {
+		maxSize = config.DefaultMetricsSpoolMaxSizeBytes
+	}
+
+	spool, err := newEventSpool(spoolDir, maxSize, maxAge)
+	if err != nil {
+		ilog.WithError(err).Warn("cannot create metrics spool, spooling will be disabled")
+		return nil
+	}
+	if n := spool.Len(); n > 0 {
+		ilog.WithField("batches", n).WithField("dir", spoolDir).Info("Replaying spooled metrics.")
 	}
+	return spool
 }
 
 func (sender *metricsIngestSender) Debug() bool {
@@ -248,26 +289,22 @@ func (sender *metricsIngestSender) accumulateBatches() {
 
 			if batchBytes+len(event.data) > sender.maxMetricsBatchSizeBytes || len(batch) == MAX_EVENT_BATCH_COUNT {
 				// Current batch + this event would either be too many events or too many bytes, so queue the batch first.
-				select {
-				case sender.batchQueue <- batch:
-					batch = make(eventBatch, 0)
-					batchBytes = 0
-				case <-sender.stopChannel:
+				if stopped := sender.queueBatch(batch); stopped {
 					return
 				}
+				batch = make(eventBatch, 0)
+				batchBytes = 0
 			}
 			batch = append(batch, event)
 			batchBytes += len(event.data)
 		case <-sendTimer.C:
 			// Timer has fired - send any queued events to ensure a minimum delay in sending.
 			if len(batch) > 0 {
-				select {
-				case sender.batchQueue <- batch:
-					batch = make(eventBatch, 0)
-					batchBytes = 0
-				case <-sender.stopChannel:
+				if stopped := sender.queueBatch(batch); stopped {
 					return
 				}
+				batch = make(eventBatch, 0)
+				batchBytes = 0
 			}
 			sendTimer.Reset(sendTimerD)
 		case <-sender.stopChannel:
@@ -278,6 +315,33 @@ func (sender *metricsIngestSender) accumulateBatches() {
 	}
 }
 
+// queueBatch hands a batch over to sendBatches, blocking until there is room in the batch queue. When the spool
+// is enabled it never blocks: the batch is spooled if the queue is full or if there are already spooled batches
+// pending to be sent, so they are submitted in order. It returns true if the sender was stopped while waiting.
+func (sender *metricsIngestSender) queueBatch(batch eventBatch) (stopped bool) {
+	if sender.spool != nil {
+		if sender.spool.Len() == 0 {
+			select {
+			case sender.batchQueue <- batch:
+				return false
+			default:
+			}
+		}
+		err := sender.spool.Write(batch)
+		if err == nil {
+			return false
+		}
+		ilog.WithError(err).Warn("cannot spool metrics batch, waiting for the batch queue")
+	}
+
+	select {
+	case sender.batchQueue <- batch:
+		return false
+	case <-sender.stopChannel:
+		return true
+	}
+}
+
 // MetricPost entity item for the HTTP post to be sent to the ingest service.
 type MetricPost struct {
 	ExternalKeys []string          `json:"ExternalKeys,omitempty"`
@@ -338,96 +402,149 @@ func (mp *MetricPost) getTimestampLoggingFields() logrus.Fields {
 	return logrus.Fields{"timestamps": timestamps}
 }
 
-// Wait for queued batches and send any to the ingest API
+// Wait for queued batches and send any to the ingest API.
+// When the spool is enabled, spooled batches are sent before the queued ones, and batches failing to be
+// submitted are spooled to be retried later instead of being discarded.
 func (sender *metricsIngestSender) sendBatches() {
 	retryBO := backoff.NewDefaultBackoff()
 	for {
-		select {
+		var batch eventBatch
+		var spooled string
+		if sender.spool != nil {
+			spooled, batch = sender.spool.Oldest()
+		}
+		if spooled != "" {
+			select {
+			case <-sender.stopChannel:
+				return
+			default:
+			}
+		} else {
+			select {
+			case batch = <-sender.batchQueue:
+			case <-sender.stopChannel:
+				// Stop channel has been closed - exit.
+				// There might still be some batches in the queue, but they'll still be there in case we start the sender back up.
+				return
+			}
+		}
 
-		case batch := <-sender.batchQueue:
-			ctx := goContext.Background()
-			ctx, txn := instrumentation.SelfInstrumentation.StartTransaction(ctx, "sender.sendBatches")
+		ctx := goContext.Background()
+		ctx, txn := instrumentation.SelfInstrumentation.StartTransaction(ctx, "sender.sendBatches")
 
-			pclog := ilog.WithField("postCount", sender.postCount)
-			sender.postCount++
+		pclog := ilog.WithField("postCount", sender.postCount)
+		sender.postCount++
 
-			agentKey := ""
-			dataByEntity := make(map[entity.Key]*MetricPost)
+		agentKey := ""
+		dataByEntity := make(map[entity.Key]*MetricPost)
 
-			ctx, seg := txn.StartSegment(ctx, "getAgentId")
-			agentID := sender.agentID()
-			seg.End()
+		ctx, seg := txn.StartSegment(ctx, "getAgentId")
+		agentID := sender.agentID()
+		seg.End()
 
-			ctx, seg = txn.StartSegment(ctx, "rebuildEvents")
-			// We need to rebuild the array of events as a []json.RawMessage, or else JSON marshalling won't handle them correctly.
-			for _, event := range batch {
-				entityData := dataByEntity[event.entityKey]
-				if entityData == nil {
-					entityData = newMetricPost(event.entityKey, event.entityID, agentID, event.agentKey)
-					dataByEntity[event.entityKey] = entityData
-				}
-				entityData.Events = append(entityData.Events, event.data)
-				if event.agentKey != "" {
-					agentKey = event.agentKey
-				}
+		ctx, seg = txn.StartSegment(ctx, "rebuildEvents")
+		// We need to rebuild the array of events as a []json.RawMessage, or else JSON marshalling won't handle them correctly.
+		for _, event := range batch {
+			entityData := dataByEntity[event.entityKey]
+			if entityData == nil {
+				entityData = newMetricPost(event.entityKey, event.entityID, agentID, event.agentKey)
+				dataByEntity[event.entityKey] = entityData
 			}
-			seg.End()
-
-			ctx, seg = txn.StartSegment(ctx, "prepareBulkPost")
-			var bulkPost MetricPostBatch
-			for _, entityData := range dataByEntity {
-				metric := instrumentation.NewGauge("agent.postEventsNum", float64(len(entityData.Events)))
-				instrumentation.SelfInstrumentation.RecordMetric(ctx, metric)
-				pclog.WithFieldsF(entityData.getLoggingField).
-					WithFieldsF(entityData.getTimestampLoggingFields).
-					WithField("numEvents", len(entityData.Events)).
-					Debug("Sending events to metrics-ingest.")
-				bulkPost = append(bulkPost, entityData)
+			entityData.Events = append(entityData.Events, event.data)
+			if event.agentKey != "" {
+				agentKey = event.agentKey
 			}
-			pclog.Debug("Preparing metrics post.")
-			seg.End()
+		}
+		seg.End()
+
+		ctx, seg = txn.StartSegment(ctx, "prepareBulkPost")
+		var bulkPost MetricPostBatch
+		for _, entityData := range dataByEntity {
+			metric := instrumentation.NewGauge("agent.postEventsNum", float64(len(entityData.Events)))
+			instrumentation.SelfInstrumentation.RecordMetric(ctx, metric)
+			pclog.WithFieldsF(entityData.getLoggingField).
+				WithFieldsF(entityData.getTimestampLoggingFields).
+				WithField("numEvents", len(entityData.Events)).
+				Debug("Sending events to metrics-ingest.")
+			bulkPost = append(bulkPost, entityData)
+		}
+		pclog.Debug("Preparing metrics post.")
+		seg.End()
 
-			err := sender.doPost(ctx, bulkPost, agentKey)
+		err := sender.doPost(ctx, bulkPost, agentKey)
 
-			if err == nil {
-				pclog.Debug("Metrics post succeeded.")
-				sender.sendErrorCount = 0
-				retryBO.Reset()
-				txn.End()
-				continue
+		if err == nil {
+			pclog.Debug("Metrics post succeeded.")
+			sender.sendErrorCount = 0
+			retryBO.Reset()
+			if spooled != "" {
+				sender.spool.Remove(spooled)
 			}
+			txn.End()
+			continue
+		}
 
-			sender.sendErrorCount++
-			pclog.WithError(err).WithField("sendErrorCount", sender.sendErrorCount).Error("metric sender can't process")
+		sender.sendErrorCount++
+		pclog.WithError(err).WithField("sendErrorCount", sender.sendErrorCount).Error("metric sender can't process")
 
-			e, ok := err.(*errRetry)
-			if !ok {
-				txn.NoticeError(err)
-				txn.End()
-				continue
+		if sender.spool != nil {
+			if batchRejected(err) {
+				// Retrying a rejected batch would block the spooled batches behind it until it expires.
+				pclog.WithError(err).Warn("metrics batch rejected by the ingest service, discarding it")
+				if spooled != "" {
+					sender.spool.Remove(spooled)
+				}
+			} else if spooled == "" {
+				if spoolErr := sender.spool.Write(batch); spoolErr != nil {
+					pclog.WithError(spoolErr).Warn("cannot spool metrics batch, discarding it")
+				}
 			}
+		}
 
-			if e.retryPolicy.After > 0 {
-				pclog.WithField("retryAfter", e.retryPolicy.After).Debug("Metric sender retry requested.")
-				retryBO.Reset()
-				sender.backoff(e.retryPolicy.After)
-				txn.NoticeError(e)
-				txn.AddAttribute("retryAfter", e.retryPolicy.After)
-				txn.End()
-				continue
+		e, ok := err.(*errRetry)
+		if !ok {
+			if sender.spool != nil {
+				// Avoid hammering an unreachable backend with the spooled batches.
+				retryBOAfter := retryBO.Duration()
+				pclog.WithField("retryBackoffAfter", retryBOAfter).Debug("Metric sender backoff requested.")
+				sender.backoff(retryBOAfter)
+				txn.AddAttribute("retryBackoffAfter", retryBOAfter)
 			}
-			retryBOAfter := retryBO.DurationWithMax(e.retryPolicy.MaxBackOff)
-			pclog.WithField("retryBackoffAfter", retryBOAfter).Debug("Metric sender backoff and retry requested.")
-			sender.backoff(retryBOAfter)
-			txn.AddAttribute("retryBackoffAfter", retryBOAfter)
+			txn.NoticeError(err)
+			txn.End()
+			continue
+		}
+
+		if e.retryPolicy.After > 0 {
+			pclog.WithField("retryAfter", e.retryPolicy.After).Debug("Metric sender retry requested.")
+			retryBO.Reset()
+			sender.backoff(e.retryPolicy.After)
 			txn.NoticeError(e)
+			txn.AddAttribute("retryAfter", e.retryPolicy.After)
 			txn.End()
-		case <-sender.stopChannel:
-			// Stop channel has been closed - exit.
-			// There might still be some batches in the queue, but they'll still be there in case we start the sender back up.
-			return
+			continue
 		}
+		retryBOAfter := retryBO.DurationWithMax(e.retryPolicy.MaxBackOff)
+		pclog.WithField("retryBackoffAfter", retryBOAfter).Debug("Metric sender backoff and retry requested.")
+		sender.backoff(retryBOAfter)
+		txn.AddAttribute("retryBackoffAfter", retryBOAfter)
+		txn.NoticeError(e)
+		txn.End()
+	}
+}
+
+// batchRejected returns true when the ingest service refused the batch with a client error that will not
+// go away by sending the same batch again.
+func batchRejected(err error) bool {
+	e, ok := err.(*errRetry)
+	if !ok {
+		return false
+	}
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
 	}
+	return e.StatusCode >= 400 && e.StatusCode < 500
 }
 
 func (s *metricsIngestSender) agentID() entity.ID {
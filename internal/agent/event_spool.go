@@ -0,0 +1,230 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/disk"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+)
+
+const (
+	spoolFileExt  = ".json"
+	spoolFilePerm = 0640
+	spoolDirPerm  = 0755
+)
+
+// spooledEvent is the on-disk representation of an eventData.
+type spooledEvent struct {
+	EntityKey entity.Key      `json:"entityKey"`
+	EntityID  entity.ID       `json:"entityID,omitempty"`
+	AgentKey  string          `json:"agentKey"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// spoolEntry references a batch stored in the spool.
+type spoolEntry struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// eventSpool stores event batches on disk, one file per batch, so they survive backend outages and agent
+// restarts. Files are named after a monotonically increasing sequence number, so batches are read back in
+// the same order they were written. The spool is bounded both in total size and in the age of its batches:
+// when the size cap is reached the oldest batches are discarded, and batches older than the max age are
+// discarded instead of being read.
+type eventSpool struct {
+	lock      sync.Mutex
+	dir       string
+	maxSize   int64
+	maxAge    time.Duration
+	entries   []spoolEntry // ordered from oldest to newest
+	totalSize int64
+	nextSeq   uint64
+	now       func() time.Time
+}
+
+// newEventSpool creates an spool on the given directory, loading any batch left there by a previous run.
+func newEventSpool(dir string, maxSize int64, maxAge time.Duration) (*eventSpool, error) {
+	if err := disk.MkdirAll(dir, spoolDirPerm); err != nil {
+		return nil, fmt.Errorf("cannot create spool directory %s: %v", dir, err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read spool directory %s: %v", dir, err)
+	}
+
+	s := &eventSpool{
+		dir:     dir,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		now:     time.Now,
+	}
+
+	for _, f := range files {
+		seq, ok := spoolSeq(f.Name())
+		if f.IsDir() || !ok {
+			continue
+		}
+		s.entries = append(s.entries, spoolEntry{name: f.Name(), size: f.Size(), modTime: f.ModTime()})
+		s.totalSize += f.Size()
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(s.entries, func(i, j int) bool {
+		return s.entries[i].name < s.entries[j].name
+	})
+
+	return s, nil
+}
+
+// spoolSeq returns the sequence number of a spool file name.
+func spoolSeq(name string) (uint64, bool) {
+	if !strings.HasSuffix(name, spoolFileExt) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolFileExt), 10, 64)
+	return seq, err == nil
+}
+
+// Len returns the amount of batches stored in the spool.
+func (s *eventSpool) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.entries)
+}
+
+// Size returns the amount of bytes stored in the spool.
+func (s *eventSpool) Size() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.totalSize
+}
+
+// Write stores a batch at the end of the spool, discarding the oldest batches when the size cap is exceeded.
+func (s *eventSpool) Write(batch eventBatch) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	events := make([]spooledEvent, 0, len(batch))
+	for _, e := range batch {
+		events = append(events, spooledEvent{
+			EntityKey: e.entityKey,
+			EntityID:  e.entityID,
+			AgentKey:  e.agentKey,
+			Data:      e.data,
+		})
+	}
+	content, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("cannot marshal spooled batch: %v", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.maxSize > 0 && int64(len(content)) > s.maxSize {
+		return fmt.Errorf("batch is larger than the spool size (%d > %d)", len(content), s.maxSize)
+	}
+
+	name := fmt.Sprintf("%020d%s", s.nextSeq, spoolFileExt)
+	if err = disk.WriteFile(filepath.Join(s.dir, name), content, spoolFilePerm); err != nil {
+		return fmt.Errorf("cannot write spooled batch: %v", err)
+	}
+	s.nextSeq++
+	s.entries = append(s.entries, spoolEntry{name: name, size: int64(len(content)), modTime: s.now()})
+	s.totalSize += int64(len(content))
+
+	for s.maxSize > 0 && s.totalSize > s.maxSize && len(s.entries) > 1 {
+		ilog.WithField("file", s.entries[0].name).Warn("Metrics spool is full, discarding oldest batch.")
+		s.removeOldest()
+	}
+
+	return nil
+}
+
+// Oldest returns the oldest batch in the spool, discarding the expired and unreadable ones on the way. It
+// returns an empty name if the spool is empty. The batch is kept until it is removed through Remove.
+func (s *eventSpool) Oldest() (name string, batch eventBatch) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for len(s.entries) > 0 {
+		entry := s.entries[0]
+		if s.maxAge > 0 && s.now().Sub(entry.modTime) > s.maxAge {
+			ilog.WithField("file", entry.name).Debug("Discarding expired spooled batch.")
+			s.removeOldest()
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(s.dir, entry.name))
+		if err != nil {
+			ilog.WithError(err).WithField("file", entry.name).Warn("Cannot read spooled batch, discarding it.")
+			s.removeOldest()
+			continue
+		}
+
+		var events []spooledEvent
+		if err = json.Unmarshal(content, &events); err != nil {
+			ilog.WithError(err).WithField("file", entry.name).Warn("Corrupted spooled batch, discarding it.")
+			s.removeOldest()
+			continue
+		}
+
+		batch = make(eventBatch, 0, len(events))
+		for _, e := range events {
+			batch = append(batch, eventData{
+				entityKey: e.EntityKey,
+				entityID:  e.EntityID,
+				agentKey:  e.AgentKey,
+				data:      e.Data,
+			})
+		}
+		return entry.name, batch
+	}
+
+	return "", nil
+}
+
+// Remove deletes a batch previously returned by Oldest.
+func (s *eventSpool) Remove(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, entry := range s.entries {
+		if entry.name != name {
+			continue
+		}
+		s.deleteFile(entry)
+		s.entries = append(s.entries[:i], s.entries[i+1:]...)
+		return
+	}
+}
+
+func (s *eventSpool) removeOldest() {
+	s.deleteFile(s.entries[0])
+	s.entries = s.entries[1:]
+}
+
+func (s *eventSpool) deleteFile(entry spoolEntry) {
+	s.totalSize -= entry.size
+	if err := os.Remove(filepath.Join(s.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+		ilog.WithError(err).WithField("file", entry.name).Warn("Cannot remove spooled batch.")
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+func testBatch(values ...string) eventBatch {
+	var batch eventBatch
+	for _, v := range values {
+		batch = append(batch, eventData{
+			entityKey: "entity",
+			agentKey:  "agent",
+			data:      json.RawMessage(`{"value":"` + v + `"}`),
+		})
+	}
+	return batch
+}
+
+func TestEventSpool_ReadsInOrder(t *testing.T) {
+	s, err := newEventSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(testBatch("1")))
+	require.NoError(t, s.Write(testBatch("2", "3")))
+	assert.Equal(t, 2, s.Len())
+
+	name, batch := s.Oldest()
+	assert.Equal(t, testBatch("1"), batch)
+	s.Remove(name)
+
+	name, batch = s.Oldest()
+	assert.Equal(t, testBatch("2", "3"), batch)
+	s.Remove(name)
+
+	name, batch = s.Oldest()
+	assert.Empty(t, name)
+	assert.Nil(t, batch)
+	assert.Equal(t, int64(0), s.Size())
+}
+
+func TestEventSpool_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newEventSpool(dir, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Write(testBatch("1")))
+	require.NoError(t, s.Write(testBatch("2")))
+
+	s, err = newEventSpool(dir, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Write(testBatch("3")))
+	assert.Equal(t, 3, s.Len())
+
+	for _, expected := range []string{"1", "2", "3"} {
+		name, batch := s.Oldest()
+		assert.Equal(t, testBatch(expected), batch)
+		s.Remove(name)
+	}
+}
+
+func TestEventSpool_DiscardsOldestWhenFull(t *testing.T) {
+	s, err := newEventSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Write(testBatch("1")))
+	batchSize := s.Size()
+
+	s.maxSize = 2 * batchSize
+	require.NoError(t, s.Write(testBatch("2")))
+	require.NoError(t, s.Write(testBatch("3")))
+
+	assert.Equal(t, 2, s.Len())
+	assert.Equal(t, 2*batchSize, s.Size())
+	_, batch := s.Oldest()
+	assert.Equal(t, testBatch("2"), batch)
+}
+
+func TestEventSpool_RejectsBatchLargerThanSpool(t *testing.T) {
+	s, err := newEventSpool(t.TempDir(), 10, 0)
+	require.NoError(t, err)
+
+	assert.Error(t, s.Write(testBatch("1")))
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestEventSpool_DiscardsExpired(t *testing.T) {
+	s, err := newEventSpool(t.TempDir(), 0, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.now = func() time.Time { return now.Add(-2 * time.Hour) }
+	require.NoError(t, s.Write(testBatch("1")))
+	s.now = func() time.Time { return now }
+	require.NoError(t, s.Write(testBatch("2")))
+
+	_, batch := s.Oldest()
+	assert.Equal(t, testBatch("2"), batch)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestEventSender_ReplaysSpooledBatchesAfterFailure(t *testing.T) {
+	var lock sync.Mutex
+	var failures = 1
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+	}))
+	defer ts.Close()
+
+	ctx := newTestContext("testAgent", &config.Config{
+		PayloadCompressionLevel:  gzip.NoCompression,
+		CollectorURL:             ts.URL,
+		AgentDir:                 t.TempDir(),
+		MetricsSpoolEnabled:      true,
+		MetricsSpoolMaxSizeBytes: config.DefaultMetricsSpoolMaxSizeBytes,
+		MetricsSpoolMaxAge:       config.DefaultMetricsSpoolMaxAge,
+	})
+	sender := newMetricsIngestSender(ctx, "license", "userAgent", http.DefaultClient.Do, false)
+	require.NotNil(t, sender.spool)
+	sender.getBackoffTimer = func(time.Duration) *time.Timer {
+		return time.NewTimer(0)
+	}
+
+	require.NoError(t, sender.Start())
+	defer sender.Stop()
+
+	require.NoError(t, sender.QueueEvent(mapEvent{"eventType": "TestEvent", "value": "1"}, ""))
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(bodies) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, `[{"ExternalKeys":["testAgent"],"IsAgent":true,"Events":[{"entityKey":"testAgent","eventType":"TestEvent","value":"1"}]}]`, bodies[0])
+	assert.Equal(t, 0, sender.spool.Len())
+}
+
+func TestEventSender_DiscardsRejectedSpooledBatches(t *testing.T) {
+	var lock sync.Mutex
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		if strings.Contains(string(body), `"value":"rejected"`) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		bodies = append(bodies, string(body))
+	}))
+	defer ts.Close()
+
+	ctx := newTestContext("testAgent", &config.Config{
+		PayloadCompressionLevel:  gzip.NoCompression,
+		CollectorURL:             ts.URL,
+		AgentDir:                 t.TempDir(),
+		MetricsSpoolEnabled:      true,
+		MetricsSpoolMaxSizeBytes: config.DefaultMetricsSpoolMaxSizeBytes,
+		MetricsSpoolMaxAge:       config.DefaultMetricsSpoolMaxAge,
+	})
+	sender := newMetricsIngestSender(ctx, "license", "userAgent", http.DefaultClient.Do, false)
+	require.NotNil(t, sender.spool)
+	sender.getBackoffTimer = func(time.Duration) *time.Timer {
+		return time.NewTimer(0)
+	}
+
+	// a batch left in the spool by a previous run is rejected by the backend
+	rejected := testBatch("rejected")
+	rejected[0].entityKey = "testAgent"
+	rejected[0].agentKey = "testAgent"
+	require.NoError(t, sender.spool.Write(rejected))
+
+	require.NoError(t, sender.Start())
+	defer sender.Stop()
+
+	require.NoError(t, sender.QueueEvent(mapEvent{"eventType": "TestEvent", "value": "1"}, ""))
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(bodies) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Contains(t, bodies[0], `"value":"1"`)
+	assert.Equal(t, 0, sender.spool.Len())
+}
+
+func TestBatchRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		rejected bool
+	}{
+		{"bad request", newErrRetry("", http.StatusBadRequest, "", "", backendhttp.RetryPolicy{}), true},
+		{"forbidden", newErrRetry("", http.StatusForbidden, "", "", backendhttp.RetryPolicy{}), true},
+		{"too large", newErrRetry("", http.StatusRequestEntityTooLarge, "", "", backendhttp.RetryPolicy{}), true},
+		{"timeout", newErrRetry("", http.StatusRequestTimeout, "", "", backendhttp.RetryPolicy{}), false},
+		{"throttled", newErrRetry("", http.StatusTooManyRequests, "", "", backendhttp.RetryPolicy{}), false},
+		{"server error", newErrRetry("", http.StatusServiceUnavailable, "", "", backendhttp.RetryPolicy{}), false},
+		{"network error", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.rejected, batchRejected(tt.err))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package instrumentation
+
+import (
+	"context"
+	"sync"
+)
+
+// GaugesRecorder decorates an AgentInstrumentation keeping the last value recorded for every gauge, so they
+// can be exposed locally.
+type GaugesRecorder struct {
+	AgentInstrumentation
+	lock   sync.RWMutex
+	gauges map[string]float64
+}
+
+// NewGaugesRecorder creates a GaugesRecorder forwarding every call to the wrapped instrumentation.
+func NewGaugesRecorder(wrapped AgentInstrumentation) *GaugesRecorder {
+	return &GaugesRecorder{
+		AgentInstrumentation: wrapped,
+		gauges:               make(map[string]float64),
+	}
+}
+
+func (g *GaugesRecorder) RecordMetric(ctx context.Context, metric metric) {
+	if metric.Type == Gauge {
+		g.lock.Lock()
+		g.gauges[metric.Name] = metric.Value
+		g.lock.Unlock()
+	}
+	g.AgentInstrumentation.RecordMetric(ctx, metric)
+}
+
+// Gauges returns a copy of the last values recorded for each gauge.
+func (g *GaugesRecorder) Gauges() map[string]float64 {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	gauges := make(map[string]float64, len(g.gauges))
+	for name, value := range g.gauges {
+		gauges[name] = value
+	}
+	return gauges
+}
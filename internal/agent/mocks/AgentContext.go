@@ -166,6 +166,20 @@ func (_m *AgentContext) SendData(_a0 agent.PluginOutput) {
 	_m.Called(_a0)
 }
 
+// IncludeEvent provides a mock function with given fields: event
+func (_m *AgentContext) IncludeEvent(event interface{}) bool {
+	ret := _m.Called(event)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(interface{}) bool); ok {
+		r0 = rf(event)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // SendEvent provides a mock function with given fields: event, entityKey
 func (_m *AgentContext) SendEvent(event sample.Event, entityKey entity.Key) {
 	_m.Called(event, entityKey)
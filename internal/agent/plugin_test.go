@@ -90,6 +90,10 @@ func (c *fakeContext) SendEvent(event sample.Event, entityKey entity.Key) {
 	c.ev <- event
 }
 
+func (c *fakeContext) IncludeEvent(event interface{}) bool {
+	return true
+}
+
 func (c *fakeContext) Unregister(id ids.PluginID) {}
 
 func (c *fakeContext) Version() string {
@@ -18,7 +18,10 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/newrelic/infrastructure-agent/internal/agent/status"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
 
@@ -29,6 +32,8 @@ const (
 	statusOnlyErrorsAPIPath    = "/v1/status/errors"
 	statusEntityAPIPath        = "/v1/status/entity"
 	statusAPIPathReady         = "/v1/status/ready"
+	statusIntegrationsAPIPath  = "/v1/status/integrations"
+	metricsAPIPath             = "/metrics"
 	ingestAPIPath              = "/v1/data"
 	ingestAPIPathReady         = "/v1/data/ready"
 	readinessProbeRetryBackoff = 100 * time.Millisecond
@@ -49,6 +54,8 @@ type Server struct {
 	definition integration.Definition
 	emitter    emitter.Emitter
 	readyCh    chan struct{}
+	metrics    prometheus.Gatherer
+	monitor    *monitor.Monitor
 }
 
 // ComponentConfig stores configuration for a server component.
@@ -86,6 +93,16 @@ func (sc *ComponentConfig) VerifyTLSClient(caCertPath string) {
 	sc.tls.caPath = caCertPath
 }
 
+// ExposeMetrics serves the metrics provided by the gatherer in Prometheus text format through the status API.
+func (s *Server) ExposeMetrics(g prometheus.Gatherer) {
+	s.metrics = g
+}
+
+// ExposeIntegrations serves the execution status of the integrations kept by the monitor through the status API.
+func (s *Server) ExposeIntegrations(m *monitor.Monitor) {
+	s.monitor = m
+}
+
 // NewServer creates a new API server.
 // Nice2Have: decouple services into path handlers.
 // Separate HTTP API configs should be deprecated if we want to unify under a single server & port.
@@ -119,6 +136,12 @@ func (s *Server) Serve(ctx context.Context) {
 			router.GET(statusEntityAPIPath, s.handleEntity)
 			router.GET(statusAPIPath, s.handle(false))
 			router.GET(statusOnlyErrorsAPIPath, s.handle(true))
+			if s.monitor != nil {
+				router.GET(statusIntegrationsAPIPath, s.handleIntegrations)
+			}
+			if s.metrics != nil {
+				router.Handler(http.MethodGet, metricsAPIPath, promhttp.HandlerFor(s.metrics, promhttp.HandlerOpts{}))
+			}
 			// local only API
 			err := http.ListenAndServe(s.Status.address, router)
 			if err != nil {
@@ -316,6 +339,30 @@ func (s *Server) handleEntity(w http.ResponseWriter, r *http.Request, ps httprou
 	w.WriteHeader(http.StatusOK)
 }
 
+// integrationsReport is the response of the integrations status API.
+type integrationsReport struct {
+	Executions   monitor.Executions          `json:"executions"`
+	Integrations []monitor.IntegrationStatus `json:"integrations"`
+}
+
+func (s *Server) handleIntegrations(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	b, err := json.Marshal(integrationsReport{
+		Executions:   s.monitor.Executions(),
+		Integrations: s.monitor.Status(),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		s.logger.WithError(err).Warn("couldn't encode integrations report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_, err = w.Write(b)
+	if err != nil {
+		s.logger.Warn("cannot write integrations response, error: " + err.Error())
+	}
+}
+
 func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	rawBody, err := ioutil.ReadAll(r.Body)
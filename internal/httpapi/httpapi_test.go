@@ -20,6 +20,8 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/agent/status"
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	network_helpers "github.com/newrelic/infrastructure-agent/pkg/helpers/network"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/executor"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/testhelp/testemit"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 	"github.com/stretchr/testify/assert"
@@ -375,3 +377,50 @@ func (r *noopReporter) ReportErrors() (status.Report, error) {
 func (r *noopReporter) ReportEntity() (re status.ReportEntity, err error) {
 	return status.ReportEntity{}, nil
 }
+
+func TestServe_Integrations(t *testing.T) {
+	t.Parallel()
+
+	port, err := network_helpers.TCPPort()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	emptyIDProvide := func() entity.Identity {
+		return entity.EmptyIdentity
+	}
+	r := status.NewReporter(ctx, log.WithComponent(t.Name()), []string{}, time.Second, &http.Transport{}, emptyIDProvide, "user-agent", "agent-key")
+
+	// Given a monitor with the status of an OOM killed integration
+	m := monitor.New(nil)
+	mysql := monitor.Integration{Name: "nri-mysql", ConfigPath: "/etc/newrelic-infra/integrations.d/mysql.yml", Hash: "1a2b"}
+	m.ReportResourceUsage(mysql, executor.ResourceUsage{OOMKills: 1})
+	// And a running integration
+	m.RunQueued(mysql)
+	m.RunStarted(mysql)
+
+	s, err := NewServer(r, &testemit.RecordEmitter{})
+	require.NoError(t, err)
+	s.Status.Enable("localhost", port)
+	s.ExposeIntegrations(m)
+
+	go s.Serve(ctx)
+	s.WaitUntilReady()
+
+	// When the integrations status is requested
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d%s", port, statusIntegrationsAPIPath))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	// Then the response contains the integration status
+	var report integrationsReport
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+	assert.Equal(t, monitor.Executions{Running: 1}, report.Executions)
+	require.Len(t, report.Integrations, 1)
+	assert.Equal(t, "nri-mysql", report.Integrations[0].Name)
+	assert.Equal(t, "/etc/newrelic-infra/integrations.d/mysql.yml", report.Integrations[0].ConfigPath)
+	assert.NotNil(t, report.Integrations[0].LastStart)
+	require.NotNil(t, report.Integrations[0].Resources)
+	assert.Equal(t, uint64(1), report.Integrations[0].Resources.OOMKills)
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const metricsNamespace = "newrelic_infra"
+
+// ExportedSamplesTTL is the time after which a sample not updated, e.g. from a finished process, is no longer exposed.
+const ExportedSamplesTTL = 2 * time.Minute
+
+// ExportedSamples maps the sample event types exposed through the metrics endpoint to the attributes identifying
+// each of their sources, which are exposed as labels.
+var ExportedSamples = map[string][]string{
+	"SystemSample":  {},
+	"StorageSample": {"mountPoint", "device", "filesystemType"},
+	"NetworkSample": {"interfaceName"},
+	"ProcessSample": {"processId", "processDisplayName", "commandName", "userName", "containerId"},
+}
+
+var (
+	camelCaseBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	invalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+)
+
+// samplesCollector is a prometheus.Collector exposing the latest agent samples and the agent self-instrumentation
+// gauges.
+type samplesCollector struct {
+	store       *sample.Store
+	agentGauges func() map[string]float64
+}
+
+// NewSamplesGatherer returns a prometheus.Gatherer exposing the latest samples kept in the store, and the gauges
+// returned by agentGauges, if provided.
+func NewSamplesGatherer(store *sample.Store, agentGauges func() map[string]float64) prometheus.Gatherer {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&samplesCollector{
+		store:       store,
+		agentGauges: agentGauges,
+	})
+	return registry
+}
+
+// Describe sends no descriptors, making this an unchecked collector, as the metrics depend on the samples.
+func (c *samplesCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *samplesCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.store != nil {
+		for _, snapshot := range c.store.Snapshots() {
+			c.collectSnapshot(snapshot, ch)
+		}
+	}
+
+	if c.agentGauges != nil {
+		for name, value := range c.agentGauges() {
+			desc := prometheus.NewDesc(metricName(name), "Agent self-instrumentation gauge "+name+".", nil, nil)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+		}
+	}
+}
+
+func (c *samplesCollector) collectSnapshot(snapshot sample.Snapshot, ch chan<- prometheus.Metric) {
+	identity := c.store.Identity(snapshot.EventType)
+
+	labelNames := []string{"entity_key"}
+	labelValues := []string{stringAttribute(snapshot.Attributes["entityKey"])}
+	for _, attr := range identity {
+		labelNames = append(labelNames, snakeCase(attr))
+		labelValues = append(labelValues, stringAttribute(snapshot.Attributes[attr]))
+	}
+
+	prefix := snakeCase(strings.TrimSuffix(snapshot.EventType, "Sample"))
+
+	attrs := make([]string, 0, len(snapshot.Attributes))
+	for attr := range snapshot.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+
+	for _, attr := range attrs {
+		value, ok := snapshot.Attributes[attr].(float64)
+		if !ok || attr == "timestamp" {
+			continue
+		}
+		name := metricName(prefix + "_" + snakeCase(attr))
+		desc := prometheus.NewDesc(name, snapshot.EventType+" "+attr+" attribute.", labelNames, nil)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...)
+	}
+}
+
+// metricName builds a valid Prometheus metric name within the agent namespace.
+func metricName(name string) string {
+	name = invalidNameChars.ReplaceAllString(snakeCase(name), "_")
+	return metricsNamespace + "_" + strings.Trim(name, "_")
+}
+
+func snakeCase(name string) string {
+	return strings.ToLower(camelCaseBoundary.ReplaceAllString(name, "${1}_${2}"))
+}
+
+func stringAttribute(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/status"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	network_helpers "github.com/newrelic/infrastructure-agent/pkg/helpers/network"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/testhelp/testemit"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+type testStorageSample struct {
+	sample.BaseEvent
+	MountPoint  string  `json:"mountPoint"`
+	Device      string  `json:"device"`
+	UsedPercent float64 `json:"diskUsedPercent"`
+}
+
+type testProcessSample struct {
+	sample.BaseEvent
+	ProcessID   int32   `json:"processId"`
+	CommandName string  `json:"commandName"`
+	CPUPercent  float64 `json:"cpuPercent"`
+}
+
+func newTestStore() *sample.Store {
+	store := sample.NewStore(time.Minute, ExportedSamples)
+
+	storage := &testStorageSample{MountPoint: "/", Device: "/dev/sda1", UsedPercent: 42.5}
+	storage.Type("StorageSample")
+	storage.Entity("my-host")
+	storage.Timestamp(1234)
+	store.Record(storage)
+
+	process := &testProcessSample{ProcessID: 1234, CommandName: "java", CPUPercent: 3}
+	process.Type("ProcessSample")
+	process.Entity("my-host")
+	store.Record(process)
+
+	return store
+}
+
+func TestServe_Metrics(t *testing.T) {
+	t.Parallel()
+
+	port, err := network_helpers.TCPPort()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	emptyIDProvide := func() entity.Identity {
+		return entity.EmptyIdentity
+	}
+	r := status.NewReporter(ctx, log.WithComponent(t.Name()), []string{}, time.Second, &http.Transport{}, emptyIDProvide, "user-agent", "agent-key")
+
+	s, err := NewServer(r, &testemit.RecordEmitter{})
+	require.NoError(t, err)
+	s.Status.Enable("localhost", port)
+	s.ExposeMetrics(NewSamplesGatherer(newTestStore(), func() map[string]float64 {
+		return map[string]float64{"agent.eventQueueSize": 7}
+	}))
+
+	go s.Serve(ctx)
+	s.WaitUntilReady()
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d%s", port, metricsAPIPath))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	metrics := string(body)
+
+	assert.Contains(t, metrics, `newrelic_infra_storage_disk_used_percent{device="/dev/sda1",entity_key="my-host",filesystem_type="",mount_point="/"} 42.5`)
+	assert.Contains(t, metrics, `newrelic_infra_process_cpu_percent{command_name="java",container_id="",entity_key="my-host",process_display_name="",process_id="1234",user_name=""} 3`)
+	assert.Contains(t, metrics, `newrelic_infra_process_process_id{`)
+	assert.Contains(t, metrics, `newrelic_infra_agent_event_queue_size 7`)
+	assert.NotContains(t, metrics, `timestamp`)
+}
+
+func TestServe_MetricsDisabled(t *testing.T) {
+	t.Parallel()
+
+	port, err := network_helpers.TCPPort()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	emptyIDProvide := func() entity.Identity {
+		return entity.EmptyIdentity
+	}
+	r := status.NewReporter(ctx, log.WithComponent(t.Name()), []string{}, time.Second, &http.Transport{}, emptyIDProvide, "user-agent", "agent-key")
+
+	s, err := NewServer(r, &testemit.RecordEmitter{})
+	require.NoError(t, err)
+	s.Status.Enable("localhost", port)
+
+	go s.Serve(ctx)
+	s.WaitUntilReady()
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d%s", port, metricsAPIPath))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
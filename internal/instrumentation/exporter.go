@@ -15,6 +15,7 @@ import (
 )
 
 type instrumentation struct {
+	registry *prometheus.Registry
 	handler  *oprometheus.Exporter
 	meter    *metric.Meter
 	counters map[MetricName]metric.Int64Counter
@@ -24,6 +25,10 @@ func (i instrumentation) GetHandler() http.Handler {
 	return i.handler
 }
 
+func (i instrumentation) GetGatherer() prometheus.Gatherer {
+	return i.registry
+}
+
 func (i instrumentation) Measure(metricType MetricType, name MetricName, val int64) {
 	i.meter.RecordBatch(
 		context.Background(),
@@ -59,6 +64,7 @@ func New() (Instrumenter, error) {
 	}
 
 	return &instrumentation{
+		registry: registry,
 		handler:  prometheusExporter,
 		counters: counters,
 		meter:    &meter,
@@ -4,6 +4,8 @@ package instrumentation
 
 import (
 	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type MetricType int
@@ -41,4 +43,5 @@ type Instrumenter interface {
 	GetHandler() http.Handler
 	Measure(metricType MetricType, name MetricName, val int64)
 	GetHttpTransport(base http.RoundTripper) http.RoundTripper
+	GetGatherer() prometheus.Gatherer
 }
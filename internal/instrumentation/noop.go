@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
@@ -37,3 +38,7 @@ func (n noop) GetHandler() http.Handler {
 func (n noop) GetHttpTransport(base http.RoundTripper) http.RoundTripper {
 	return base
 }
+
+func (n noop) GetGatherer() prometheus.Gatherer {
+	return prometheus.Gatherers{}
+}
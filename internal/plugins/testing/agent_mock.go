@@ -82,6 +82,10 @@ func (self *MockAgent) SendEvent(event sample.Event, entityKey entity.Key) {
 	// Not implemented yet
 }
 
+func (self *MockAgent) IncludeEvent(event interface{}) bool {
+	return true
+}
+
 func (self *MockAgent) Unregister(id ids.PluginID) {
 	self.registered = false
 	self.ch <- agent.NewNotApplicableOutput(id)
@@ -0,0 +1,292 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+// dryRunEntityID is the entity ID the simulated identity connect responses assign to the agent.
+const dryRunEntityID = 1
+
+// dryRunMaxRotatedFiles is the number of rotated dry run files that are kept.
+const dryRunMaxRotatedFiles = 5
+
+var (
+	dryRunOnce      sync.Once
+	dryRunTransport *DryRunTransport
+)
+
+// DryRunPayload is the record written for each request submitted in dry run mode.
+type DryRunPayload struct {
+	Timestamp int64           `json:"timestamp"`
+	Method    string          `json:"method"`
+	URL       string          `json:"url"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// DryRunTransport is an http.RoundTripper that, instead of submitting the requests to New Relic, writes their
+// payloads as JSON lines and replies with the minimal successful responses the agent clients expect, so all the
+// senders behave as if the data had been accepted.
+type DryRunTransport struct {
+	lock         sync.Mutex
+	out          io.Writer
+	lastEntityID int64
+}
+
+// NewDryRunTransport returns a DryRunTransport writing the request payloads to out.
+func NewDryRunTransport(out io.Writer) *DryRunTransport {
+	return &DryRunTransport{
+		out:          out,
+		lastEntityID: dryRunEntityID,
+	}
+}
+
+// buildDryRunTransport returns the transport shared by all the clients of the agent in dry run mode, so all the
+// payloads are written to the same output.
+func buildDryRunTransport(cfg *config.Config) http.RoundTripper {
+	dryRunOnce.Do(func() {
+		// stderr is only used if the file can't be written, as the agent logs go to stdout
+		var out io.Writer = os.Stderr
+		if cfg.DryRunFile != "" {
+			file, err := newRotatingFile(cfg.DryRunFile, int64(cfg.DryRunFileMaxSizeMb)*1024*1024, dryRunMaxRotatedFiles)
+			if err != nil {
+				plog.WithError(err).WithField("file", cfg.DryRunFile).Error("Cannot open dry run file, writing payloads to stderr.")
+			} else {
+				out = file
+			}
+		}
+		dryRunTransport = NewDryRunTransport(out)
+	})
+	return dryRunTransport
+}
+
+func (t *DryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	payload, err := requestPayload(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read dry run request payload: %v", err)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(payload) > 0 {
+		if err := t.write(req, payload); err != nil {
+			return nil, err
+		}
+	}
+
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	switch {
+	case strings.HasSuffix(path, "/connect"):
+		return dryRunResponse(http.StatusOK, fmt.Sprintf(`{"identity":{"entityId":%d,"GUID":"dry-run"}}`, dryRunEntityID))
+	case strings.HasSuffix(path, "/register/batch"):
+		return t.registerBatchResponse(payload)
+	case strings.HasSuffix(path, "/deltas/bulk"):
+		return deltasBulkResponse(payload)
+	case strings.HasSuffix(path, "/deltas"):
+		return deltasResponse(payload)
+	case req.Method == http.MethodGet || req.Method == http.MethodHead:
+		return dryRunResponse(http.StatusOK, `{}`)
+	default:
+		return dryRunResponse(http.StatusAccepted, `{}`)
+	}
+}
+
+// write outputs the payload as a JSON line. Payloads that aren't valid JSON are written as strings.
+func (t *DryRunTransport) write(req *http.Request, payload []byte) error {
+	if !json.Valid(payload) {
+		payload, _ = json.Marshal(string(payload))
+	}
+	line, err := json.Marshal(DryRunPayload{
+		Timestamp: time.Now().Unix(),
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Payload:   json.RawMessage(bytes.TrimSpace(payload)),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = t.out.Write(append(line, '\n'))
+	return err
+}
+
+// requestPayload returns the uncompressed body of the request.
+func requestPayload(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+
+	body := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+	return ioutil.ReadAll(body)
+}
+
+// registerBatchResponse assigns a new entity ID to each of the registered entities.
+func (t *DryRunTransport) registerBatchResponse(payload []byte) (*http.Response, error) {
+	var entities []struct {
+		EntityName string `json:"entityName"`
+	}
+	if err := json.Unmarshal(payload, &entities); err != nil {
+		return dryRunResponse(http.StatusBadRequest, fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+
+	type registered struct {
+		EntityID   int64  `json:"entityId"`
+		EntityName string `json:"entityName"`
+	}
+	response := make([]registered, len(entities))
+	for i, e := range entities {
+		t.lastEntityID++
+		response[i] = registered{EntityID: t.lastEntityID, EntityName: e.EntityName}
+	}
+	return dryRunJSONResponse(http.StatusOK, response)
+}
+
+type dryRunDeltas struct {
+	EntityKeys []string `json:"entityKeys"`
+	Deltas     []struct {
+		Source string `json:"source"`
+		ID     int64  `json:"id"`
+	} `json:"deltas"`
+}
+
+type dryRunDeltaState struct {
+	LastStoredID int64 `json:"last_stored_id"`
+	SendNextID   int64 `json:"send_next_id"`
+}
+
+type dryRunDeltasResponse struct {
+	Version    int64                       `json:"version"`
+	StateMap   map[string]dryRunDeltaState `json:"state_map"`
+	EntityKeys []string                    `json:"entityKeys,omitempty"`
+}
+
+// stored acknowledges all the submitted deltas, so they aren't submitted again.
+func (d dryRunDeltas) stored() dryRunDeltasResponse {
+	stateMap := map[string]dryRunDeltaState{}
+	for _, delta := range d.Deltas {
+		if delta.ID >= stateMap[delta.Source].LastStoredID {
+			stateMap[delta.Source] = dryRunDeltaState{LastStoredID: delta.ID, SendNextID: delta.ID + 1}
+		}
+	}
+	return dryRunDeltasResponse{Version: 1, StateMap: stateMap, EntityKeys: d.EntityKeys}
+}
+
+func deltasResponse(payload []byte) (*http.Response, error) {
+	var deltas dryRunDeltas
+	if err := json.Unmarshal(payload, &deltas); err != nil {
+		return dryRunResponse(http.StatusBadRequest, fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	deltas.EntityKeys = nil
+	return dryRunJSONResponse(http.StatusAccepted, StandardResponse{Payload: deltas.stored()})
+}
+
+func deltasBulkResponse(payload []byte) (*http.Response, error) {
+	var bulk []dryRunDeltas
+	if err := json.Unmarshal(payload, &bulk); err != nil {
+		return dryRunResponse(http.StatusBadRequest, fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	response := make([]dryRunDeltasResponse, len(bulk))
+	for i, deltas := range bulk {
+		response[i] = deltas.stored()
+	}
+	return dryRunJSONResponse(http.StatusAccepted, StandardResponse{Payload: response})
+}
+
+func dryRunJSONResponse(statusCode int, body interface{}) (*http.Response, error) {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return dryRunResponse(statusCode, string(buf))
+}
+
+func dryRunResponse(statusCode int, body string) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Proto:      "HTTP/1.0",
+		ProtoMajor: 1,
+		ProtoMinor: 0,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+// rotatingFile is a file writer that rotates the file once it reaches maxSize bytes, keeping the last maxFiles
+// rotated files as <path>.1 (the most recent) to <path>.<maxFiles>.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func newRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	for i := r.maxFiles - 1; i > 0; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return err
+	}
+	return r.open()
+}
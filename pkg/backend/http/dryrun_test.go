@@ -0,0 +1,155 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipped(t *testing.T, content string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func responseBody(t *testing.T, resp *http.Response) string {
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestDryRunTransport_WritesUncompressedPayloads(t *testing.T) {
+	var out bytes.Buffer
+	transport := NewDryRunTransport(&out)
+
+	req, err := http.NewRequest("POST", "https://infra-api.newrelic.com/metrics/events/bulk", gzipped(t, `[{"eventType":"SystemSample"}]`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set(LicenseHeader, "secret")
+
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	req, err = http.NewRequest("POST", "https://metric-api.newrelic.com/metric/v1/infra", strings.NewReader("not json"))
+	require.NoError(t, err)
+	_, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+
+	// requests without payload aren't written
+	req, err = http.NewRequest("GET", "https://infrastructure-command-api.newrelic.com/agent_commands/v1/commands", nil)
+	require.NoError(t, err)
+	resp, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	scanner := bufio.NewScanner(&out)
+	var payloads []DryRunPayload
+	for scanner.Scan() {
+		var p DryRunPayload
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &p))
+		payloads = append(payloads, p)
+	}
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "POST", payloads[0].Method)
+	assert.Equal(t, "https://infra-api.newrelic.com/metrics/events/bulk", payloads[0].URL)
+	assert.JSONEq(t, `[{"eventType":"SystemSample"}]`, string(payloads[0].Payload))
+	assert.JSONEq(t, `"not json"`, string(payloads[1].Payload))
+	assert.NotContains(t, out.String(), "secret")
+}
+
+func TestDryRunTransport_Responses(t *testing.T) {
+	transport := NewDryRunTransport(ioutil.Discard)
+
+	tests := []struct {
+		name     string
+		url      string
+		body     string
+		status   int
+		expected string
+	}{
+		{
+			name:     "connect",
+			url:      "https://infra-api.newrelic.com/identity/v1/connect",
+			body:     `{"fingerprint":{}}`,
+			status:   http.StatusOK,
+			expected: `{"identity":{"entityId":1,"GUID":"dry-run"}}`,
+		},
+		{
+			name:     "register",
+			url:      "https://infra-api.newrelic.com/identity/v1/register/batch",
+			body:     `[{"entityName":"redis:6379"},{"entityName":"nginx"}]`,
+			status:   http.StatusOK,
+			expected: `[{"entityId":2,"entityName":"redis:6379"},{"entityId":3,"entityName":"nginx"}]`,
+		},
+		{
+			name:     "deltas",
+			url:      "https://infra-api.newrelic.com/inventory/deltas",
+			body:     `{"entityKeys":["host"],"deltas":[{"source":"metadata/system","id":1},{"source":"metadata/system","id":2},{"source":"packages/rpm","id":5}]}`,
+			status:   http.StatusAccepted,
+			expected: `{"payload":{"version":1,"state_map":{"metadata/system":{"last_stored_id":2,"send_next_id":3},"packages/rpm":{"last_stored_id":5,"send_next_id":6}}}}`,
+		},
+		{
+			name:     "deltas bulk",
+			url:      "https://infra-api.newrelic.com/inventory/deltas/bulk",
+			body:     `[{"entityKeys":["redis:6379"],"deltas":[{"source":"config/redis","id":1}]}]`,
+			status:   http.StatusAccepted,
+			expected: `{"payload":[{"version":1,"state_map":{"config/redis":{"last_stored_id":1,"send_next_id":2}},"entityKeys":["redis:6379"]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+			require.NoError(t, err)
+
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.JSONEq(t, tt.expected, responseBody(t, resp))
+		})
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dryrun")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "payloads.json")
+
+	file, err := newRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	for name, expected := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		content, err := ioutil.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
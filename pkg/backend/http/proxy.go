@@ -125,7 +125,12 @@ func proxyByPriority(cfg *config.Config) proxyConfig {
 // If the configuration option ignore_system_proxy is set, it ignores the HTTPS_PROXY and HTTP_PROXY configuration
 // If the configuration option proxy_validate_certificates is set, it will force the HTTPS proxy options to verify the
 // certificates
+// In dry run mode, the requests aren't submitted but written by a DryRunTransport.
 func BuildTransport(cfg *config.Config, timeout time.Duration) http.RoundTripper {
+	if cfg.DryRun {
+		return buildDryRunTransport(cfg)
+	}
+
 	proxyConfig := proxyByPriority(cfg)
 
 	if proxyConfig.isEmpty() {
@@ -48,7 +48,7 @@ type CustomAttributeMap map[string]interface{}
 
 var clog = log.WithComponent("Configuration")
 
-// Configuration type to Map include_matching_metrics setting env var
+// Configuration type to Map include_matching_metrics and exclude_matching_metrics settings env vars
 type IncludeMetricsMap map[string][]string
 
 //
@@ -77,6 +77,23 @@ type Config struct {
 	// Public: No
 	Staging bool `yaml:"staging" envconfig:"staging" public:"false"`
 
+	// DryRun makes the agent write the payloads it would submit to New Relic into the dry_run_file, instead of
+	// sending them. Backend responses are simulated, so no license key
+	// is required. It's intended to validate integrations and metric filtering rules locally.
+	// Default: False
+	// Public: Yes
+	DryRun bool `yaml:"dry_run" envconfig:"dry_run"`
+
+	// DryRunFile is the file the payloads are written to in dry run mode.
+	// Default: <agent_dir>/dry-run-payloads.json
+	// Public: Yes
+	DryRunFile string `yaml:"dry_run_file" envconfig:"dry_run_file"`
+
+	// DryRunFileMaxSizeMb is the size in megabytes the dry_run_file is rotated at. The last 5 rotated files are kept.
+	// Default: 100
+	// Public: Yes
+	DryRunFileMaxSizeMb int `yaml:"dry_run_file_max_size_mb" envconfig:"dry_run_file_max_size_mb"`
+
 	// CollectorURL is the base URL for the metrics and inventory ingest endpoints. See metrics and inventory
 	// ingest endpoint configuration option.
 	// Default: https://infra-api.newrelic.com
@@ -272,6 +289,13 @@ type Config struct {
 	// Public: No
 	IsContainerized bool `yaml:"is_containerized" envconfig:"is_containerized" public:"false"`
 
+	// CgroupPath cgroup v2 path, relative to the host cgroup v2 mount point, whose cpu.stat and memory.stat
+	// statistics are reported in the SystemSample when the agent is containerized (e.g. /kubepods.slice). The sample
+	// is labeled with the path through the cgroupPath attribute. Statistics aren't reported per container.
+	// Default: / (root cgroup, i.e. the whole host)
+	// Public: Yes
+	CgroupPath string `yaml:"cgroup_path" envconfig:"cgroup_path" os:"linux"`
+
 	// IsForwardOnly enables the forwarding mode, in this mode the agent doesn't activate any of its plugins or
 	// samplers, and just forwards data from the integrations.
 	// Default: False
@@ -365,6 +389,15 @@ type Config struct {
 	// Public: Yes
 	MetricsProcessSampleRate int `yaml:"metrics_process_sample_rate" envconfig:"metrics_process_sample_rate"`
 
+	// MetricsProcessAggregateBy Process attribute the process samples are aggregated by. When set, the process
+	// sampler rolls up the samples of the processes sharing the same value for the attribute into a single
+	// ProcessGroupSample with the summed CPU, memory and IO usage and the number of processes, instead of
+	// reporting a ProcessSample per process. Every process is aggregated, whether or not enable_process_metrics and
+	// include_matching_metrics would report it. Accepted values are commandName, containerId and userName.
+	// Default: empty
+	// Public: Yes
+	MetricsProcessAggregateBy string `yaml:"metrics_process_aggregate_by" envconfig:"metrics_process_aggregate_by"`
+
 	// HeartBeatSampleRate Interval in seconds for sending the HeartBeatSample.
 	// Default: False
 	// Public: No
@@ -392,6 +425,12 @@ type Config struct {
 	// Public: Yes
 	FileDevicesIgnored []string `yaml:"file_devices_ignored" envconfig:"file_devices_ignored"`
 
+	// EnableRawBlockDevicesMetrics When true, StorageSamples are also reported for the block devices without a mounted
+	// file system, like raw LVM or Ceph volumes. These samples only contain IO metrics and have an empty mount point.
+	// Default: False
+	// Public: Yes
+	EnableRawBlockDevicesMetrics bool `yaml:"enable_raw_block_devices_metrics" envconfig:"enable_raw_block_devices_metrics" os:"linux"`
+
 	// NetworkInterfaceFilters You can use the network interface filters configuration to hide unused or uninteresting
 	// network interfaces from the Infrastructure agent. This helps reduce resource usage, work, and noise in your data.
 	// Default: Empty
@@ -617,6 +656,26 @@ type Config struct {
 	// Public: No
 	BatchQueueDepth int `yaml:"batch_queue_depth" envconfig:"batch_queue_depth" public:"false"` // See event_sender.go
 
+	// MetricsSpoolEnabled enables an on-disk spool for the metrics sender. Event batches that cannot be queued because
+	// the queues are full, or that could not be submitted because the backend is unreachable, are stored under
+	// app_data_dir (or agent_dir when not set) and replayed in order once submissions succeed again. Batches rejected
+	// by the backend with a client error (4xx other than 408 and 429) are discarded instead of being retried.
+	// Default: False
+	// Public: Yes
+	MetricsSpoolEnabled bool `yaml:"metrics_spool_enabled" envconfig:"metrics_spool_enabled"` // See event_spool.go
+
+	// MetricsSpoolMaxSizeBytes Size in bytes the metrics spool can grow up to. Once reached, the oldest spooled
+	// batches are discarded to make room for newer ones.
+	// Default: 104857600 (100 MB)
+	// Public: Yes
+	MetricsSpoolMaxSizeBytes int64 `yaml:"metrics_spool_max_size_bytes" envconfig:"metrics_spool_max_size_bytes"`
+
+	// MetricsSpoolMaxAge Time duration spooled batches are kept on disk. Older batches are discarded instead of being
+	// replayed. Valid time units are: "s" (seconds), "m" (minutes), "h" (hour).
+	// Default: 24h
+	// Public: Yes
+	MetricsSpoolMaxAge string `yaml:"metrics_spool_max_age" envconfig:"metrics_spool_max_age"`
+
 	// InventoryQueueLen sets the inventory processing queue size. Zero value makes inventory processing synchronous (blocking call).
 	// Default: 0
 	// Public: Yes
@@ -729,6 +788,42 @@ type Config struct {
 	// Public: Yes
 	PassthroughEnvironment []string `yaml:"passthrough_environment" envconfig:"passthrough_environment"`
 
+	// MaxConcurrentIntegrations limits how many runs of periodic integrations are executed at the same time. Runs
+	// exceeding the limit wait until a previous run finishes. Integrations with interval 0, including daemons, aren't
+	// limited. Zero disables the limit.
+	// Default: 0
+	// Public: Yes
+	MaxConcurrentIntegrations int `yaml:"max_concurrent_integrations" envconfig:"max_concurrent_integrations"`
+
+	// IntegrationsStartJitter delays the first execution of each periodic integration by a random time within its
+	// interval (up to one minute), so integrations sharing the same interval don't run all at the same time.
+	// Default: True
+	// Public: Yes
+	IntegrationsStartJitter bool `yaml:"integrations_start_jitter" envconfig:"integrations_start_jitter"`
+
+	// IntegrationsPayloadValidation validates the protocol v4 payloads of the integrations against the protocol
+	// types. Rejected items are counted and reported in the integrations status API and as IntegrationPayloadError
+	// events. Meant for integration development, as payloads are parsed twice.
+	// Default: False
+	// Public: Yes
+	IntegrationsPayloadValidation bool `yaml:"integrations_payload_validation" envconfig:"integrations_payload_validation"`
+
+	// IntegrationsAllowList is the path of a signed allow-list with the sha256 digests of the integration executables,
+	// configuration files and config_template_path files the agent is allowed to run. Configuration files are
+	// verified when they are loaded, and the files are hashed again before running the discovery, the "when"
+	// conditions and every execution. Integrations not matching the list are refused and reported as
+	// IntegrationVerificationFailure events. The list is signed with ed25519, and its base64-encoded signature is
+	// read from the same path plus the ".sig" extension. Empty disables the verification.
+	// Default: Empty
+	// Public: Yes
+	IntegrationsAllowList string `yaml:"integrations_allow_list" envconfig:"integrations_allow_list"`
+
+	// IntegrationsAllowListPublicKey is the base64-encoded ed25519 public key verifying the signature of the
+	// integrations allow-list. Required when integrations_allow_list is set.
+	// Default: Empty
+	// Public: Yes
+	IntegrationsAllowListPublicKey string `yaml:"integrations_allow_list_public_key" envconfig:"integrations_allow_list_public_key"`
+
 	// PluginConfigFiles This configuration parameter specify the agent to look for newrelic-infra-plugins.yml
 	// Default: Empty
 	// Public: No
@@ -824,6 +919,13 @@ type Config struct {
 	// Public: Yes
 	StatusEndpoints []string `yaml:"status_endpoints" envconfig:"status_endpoints"`
 
+	// StatusServerMetricsEnabled exposes the latest SystemSample, StorageSample, NetworkSample and ProcessSample
+	// values, along with the agent self-instrumentation metrics, in Prometheus text format through the /metrics
+	// path of the status server. Requires status_server_enabled.
+	// Default: False
+	// Public: Yes
+	StatusServerMetricsEnabled bool `yaml:"status_server_metrics_enabled" envconfig:"status_server_metrics_enabled"`
+
 	// AppDataDir This option is only for Windows. It defines the path to store data in a different path than the
 	// program files directory.
 	// - %AppDir%/data: used for storing the delta data.
@@ -1053,16 +1155,38 @@ type Config struct {
 	// Public: Yes
 	EnableProcessMetrics *bool `yaml:"enable_process_metrics" envconfig:"enable_process_metrics"`
 
+	// EnableProcessNetworkMetrics enables the per process network metrics on Linux: listening ports, TCP connection
+	// counts per state and UDP socket counts. Sockets are mapped to their processes by reading the file descriptors of
+	// every process, which increases the cost of the process sampling, so this requires root or privileged mode.
+	// Default: False
+	// Public: Yes
+	EnableProcessNetworkMetrics bool `yaml:"enable_process_network_metrics" envconfig:"enable_process_network_metrics" os:"linux"`
+
 	// IncludeMetricsMatchers Configuration of the metrics matchers that determine which metric data should the agent
 	// send to the New Relic backend.
 	// If no configuration is defined, the previous behaviour is maintained, i.e., every metric data captured is sent.
 	// If a configuration is defined, then only metric data matching the configuration is sent.
 	// Note that ALL DATA NOT MATCHED WILL BE DROPPED.
-	// Also note that at present it ONLY APPLIES to metric data related to processes. All other metric data is still being sent as usual.
+	// Also note that attribute rules (process.name, process.executable) ONLY APPLY to metric data related to
+	// processes. All other metric data is still being sent as usual.
+	// Rules under the "expression" key are boolean expressions (e.g. `cpuPercent > 5 and userName != "nobody"`)
+	// evaluated against EVERY sample type, see sampler.ExpressionKey for the supported syntax. When expressions are
+	// defined, samples other than process samples are only sent if they match one of them, and process samples are
+	// sent if they match either an attribute rule or an expression. Invalid expressions prevent the agent from starting.
 	// Default: none
 	// Public: Yes
 	IncludeMetricsMatchers IncludeMetricsMap `yaml:"include_matching_metrics" envconfig:"include_matching_metrics"`
 
+	// ExcludeMetricsMatchers Configuration of the metrics matchers that determine which metric data should NOT be
+	// sent to the New Relic backend. It supports the same rules as include_matching_metrics.
+	// Exclusion rules take precedence over include rules and over enable_process_metrics: metric data matching any
+	// exclusion rule is dropped, even when it also matches an include rule.
+	// As with include rules, attribute rules (process.name, process.executable) ONLY APPLY to metric data related to
+	// processes, while "expression" rules are evaluated against EVERY sample type.
+	// Default: none
+	// Public: Yes
+	ExcludeMetricsMatchers IncludeMetricsMap `yaml:"exclude_matching_metrics" envconfig:"exclude_matching_metrics"`
+
 	// AgentMetricsEndpoint Set the endpoint (host:port) for the HTTP server the agent will use to server OpenMetrics
 	// if empty the server will be not spawned
 	// Default: empty
@@ -1149,6 +1273,19 @@ func (c *Config) IsTroubleshootMode() bool {
 	return c.Verbose == TroubleshootLogging
 }
 
+// IntegrationsSourceDirs returns the folders where the agent looks for the integration executables and their
+// legacy definition files.
+func (c *Config) IntegrationsSourceDirs() []string {
+	dirs := []string{
+		c.CustomPluginInstallationDir,
+		filepath.Join(c.AgentDir, "custom-integrations"),
+		filepath.Join(c.AgentDir, DefaultIntegrationsDir),
+		filepath.Join(c.AgentDir, "bundled-plugins"),
+		filepath.Join(c.AgentDir, "plugins"),
+	}
+	return helpers.RemoveEmptyAndDuplicateEntries(dirs)
+}
+
 // GetDefaultLogFile sets log file to defined app data dir or default.
 func (c *Config) GetDefaultLogFile() string {
 	if c.AppDataDir == "" {
@@ -1374,6 +1511,8 @@ func NewConfig() *Config {
 		DMSubmissionPeriod:            DefaultDMPeriodSecs,
 		ProxyConfigPlugin:             defaultProxyConfigPlugin,
 		ProxyValidateCerts:            defaultProxyValidateCerts,
+		DryRunFileMaxSizeMb:           defaultDryRunFileMaxSizeMb,
+		IntegrationsStartJitter:       defaultIntegrationsStartJitter,
 		CloudRetryBackOffSec:          defaultCloudRetryBackOffSec,
 		CloudMaxRetryCount:            defaultCloudMaxRetryCount,
 		CloudMetadataDisableKeepAlive: defaultCloudMetadataDisableKeepAlive,
@@ -1407,7 +1546,10 @@ func NewConfig() *Config {
 		SmartVerboseModeEntryLimit:  DefaultSmartVerboseModeEntryLimit,
 		DefaultIntegrationsTempDir:  defaultIntegrationsTempDir,
 		IncludeMetricsMatchers:      defaultMetricsMatcherConfig,
+		ExcludeMetricsMatchers:      defaultMetricsExcludeMatcherConfig,
 		InventoryQueueLen:           DefaultInventoryQueue,
+		MetricsSpoolMaxSizeBytes:    DefaultMetricsSpoolMaxSizeBytes,
+		MetricsSpoolMaxAge:          DefaultMetricsSpoolMaxAge,
 	}
 }
 
@@ -1611,6 +1753,15 @@ func NormalizeConfig(cfg *Config, cfgMetadata config_loader.YAMLMetadata) (err e
 	}
 
 	// Setting default values
+	if cfg.License == "" && cfg.DryRun {
+		cfg.License = dryRunLicense
+	}
+
+	// payloads aren't written to the standard output, where they would be mixed with the agent logs
+	if cfg.DryRun && cfg.DryRunFile == "" {
+		cfg.DryRunFile = filepath.Join(cfg.AgentDir, defaultDryRunFile)
+	}
+
 	if cfg.License == "" {
 		err = fmt.Errorf("no license key, please add it to agent's config file or NRIA_LICENSE_KEY environment variable")
 		return
@@ -1788,6 +1939,13 @@ func NormalizeConfig(cfg *Config, cfgMetadata config_loader.YAMLMetadata) (err e
 	}
 	nlog.WithField("MetricsProcessSampleRate", cfg.MetricsProcessSampleRate).Debug("Metrics Process Sample Rate.")
 
+	switch cfg.MetricsProcessAggregateBy {
+	case "", ProcessAggregateByCommandName, ProcessAggregateByContainerID, ProcessAggregateByUserName:
+	default:
+		nlog.WithField("MetricsProcessAggregateBy", cfg.MetricsProcessAggregateBy).Warn("Invalid process aggregation attribute, process samples won't be aggregated.")
+		cfg.MetricsProcessAggregateBy = ""
+	}
+
 	nlog.WithField("FilesConfigOn", cfg.FilesConfigOn).Debug("Configuration file monitoring.")
 
 	if cfg.NetworkInterfaceFilters == nil || len(cfg.NetworkInterfaceFilters) == 0 {
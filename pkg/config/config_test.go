@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"testing"
@@ -108,6 +109,21 @@ license_key: `
 
 }
 
+func (s *ConfigSuite) TestParseConfigDryRunWithoutLicense(c *C) {
+	f, err := ioutil.TempFile("", "opsmatic_config_test")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+	f.WriteString("dry_run: true\n")
+	f.Close()
+
+	cfg, err := LoadConfig(f.Name())
+	c.Assert(err, IsNil)
+	c.Assert(cfg.DryRun, Equals, true)
+	c.Assert(cfg.License, Equals, dryRunLicense)
+	c.Assert(cfg.DryRunFileMaxSizeMb, Equals, defaultDryRunFileMaxSizeMb)
+	c.Assert(cfg.DryRunFile, Equals, filepath.Join(cfg.AgentDir, defaultDryRunFile))
+}
+
 func (s *ConfigSuite) TestValidateConfigFrequencySettings(c *C) {
 
 	var testCases = []struct {
@@ -52,6 +52,8 @@ var (
 	DefaultSmartVerboseModeEntryLimit  = 1000
 	DefaultIntegrationsDir             = "newrelic-integrations"
 	DefaultInventoryQueue              = 0
+	DefaultMetricsSpoolMaxSizeBytes    = int64(100 * 1024 * 1024)
+	DefaultMetricsSpoolMaxAge          = "24h"
 
 	// private
 	defaultAppDataDir                    = ""
@@ -103,7 +105,13 @@ var (
 	defaultWinRemovableDrives            = true
 	defaultTraces                        = []trace.Feature{trace.CONN}
 	defaultMetricsMatcherConfig          = IncludeMetricsMap{}
+	defaultMetricsExcludeMatcherConfig   = IncludeMetricsMap{}
 	defaultRegisterMaxRetryBoSecs        = 60
+	defaultDryRunFileMaxSizeMb           = 100
+	defaultDryRunFile                    = "dry-run-payloads.json"
+	defaultIntegrationsStartJitter       = true
+	// dryRunLicense is a placeholder for the license key, which isn't required in dry run mode.
+	dryRunLicense = "0000000000000000000000000000000000000000"
 )
 
 // Default internal values
@@ -24,3 +24,10 @@ const (
 	// DefaultWMINamespace is the Namespace where the WMI queries will be executed
 	DefaultWMINamespace = "root/cimv2"
 )
+
+// Attributes process samples can be aggregated by, see MetricsProcessAggregateBy.
+const (
+	ProcessAggregateByCommandName = "commandName"
+	ProcessAggregateByContainerID = "containerId"
+	ProcessAggregateByUserName    = "userName"
+)
@@ -41,6 +41,7 @@ type Command struct {
 // when the integration is run).
 type DefinitionContext struct {
 	Dir        string
+	File       string // path of the definition file
 	Definition Definition
 }
 
@@ -80,7 +81,8 @@ func (dr *DefinitionsRepo) loadDefinitions(folder string) {
 	}
 	for _, file := range yamlFiles {
 		fflog := flog.WithField("file", file.Name())
-		contents, err := ioutil.ReadFile(filepath.Join(folder, file.Name()))
+		path := filepath.Join(folder, file.Name())
+		contents, err := ioutil.ReadFile(path)
 		if err != nil {
 			fflog.WithError(err).Warn("can't read file. Ignoring")
 			continue
@@ -98,6 +100,7 @@ func (dr *DefinitionsRepo) loadDefinitions(folder string) {
 		// find into the definitions repo
 		dr.Definitions[def.Name] = DefinitionContext{
 			Dir:        folder,
+			File:       path,
 			Definition: def,
 		}
 	}
@@ -128,6 +131,7 @@ func (dcb *DefinitionsRepo) NewDefinitionCommand(dcc integration.DefinitionComma
 
 	// We need to set the Working Directory to the folder where the definition file is placed
 	dcc.Common.ExecutorConfig.Directory = definition.Dir
+	dcc.Common.DefinitionPath = definition.File
 
 	if command.Prefix == "" {
 		dcc.Common.InventorySource = dcc.DefaultPrefix
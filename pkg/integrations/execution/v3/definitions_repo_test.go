@@ -3,6 +3,7 @@
 package v3
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,6 +37,7 @@ func TestNewDefinitionsRepo(t *testing.T) {
 	assert.Equal(t, cassandraMysqlFolder, dr.Definitions[cassandra].Dir)
 	assert.Equal(t, cassandraMysqlFolder, dr.Definitions[mysql].Dir)
 	assert.Equal(t, nginxApacheFolder, dr.Definitions[nginx].Dir)
+	assert.Equal(t, filepath.Join(nginxApacheFolder, "apache-definition.yml"), dr.Definitions[apache].File)
 
 	// e.g. apache has loaded the "metrics" command
 	require.Contains(t, dr.Definitions[apache].Definition.Commands, "metrics")
@@ -0,0 +1,132 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package allowlist verifies the integration executables and configuration files against a signed list of allowed
+// sha256 digests.
+//
+// The allow-list has the format of the sha256sum command output: one "<hex digest>  <file>" line per allowed file.
+// Files are allowed by their content, so the file names in the list are informative. The list is signed with
+// ed25519, and its base64-encoded signature is stored in a file with the same path plus the ".sig" extension.
+package allowlist
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// SignatureExtension is appended to the allow-list path to get the path of its signature.
+const SignatureExtension = ".sig"
+
+// Verifier checks that files are included in the allow-list. A nil Verifier allows any file.
+// Files are hashed on every verification, as their size and modification time can be preserved while replacing their
+// contents.
+type Verifier struct {
+	digests map[string]struct{}
+}
+
+// ParsePublicKey decodes a base64-encoded ed25519 public key.
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid allow-list public key: %s", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid allow-list public key: expected %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Load reads the allow-list in the given path, verifying its signature with the public key.
+func Load(path string, publicKey ed25519.PublicKey) (*Verifier, error) {
+	list, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read allow-list: %s", err)
+	}
+	encodedSignature, err := ioutil.ReadFile(path + SignatureExtension)
+	if err != nil {
+		return nil, fmt.Errorf("can't read allow-list signature: %s", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSignature)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid allow-list signature: %s", err)
+	}
+	if !ed25519.Verify(publicKey, list, signature) {
+		return nil, fmt.Errorf("allow-list signature doesn't match the allow-list contents")
+	}
+
+	digests, err := parse(list)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{digests: digests}, nil
+}
+
+// parse reads the digests of the allow-list, ignoring empty lines and comments.
+func parse(list []byte) (map[string]struct{}, error) {
+	digests := map[string]struct{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(list))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		digest := strings.ToLower(strings.Fields(line)[0])
+		if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid sha256 digest in allow-list line %d: %q", lineNum, digest)
+		}
+		digests[digest] = struct{}{}
+	}
+	return digests, scanner.Err()
+}
+
+// Verify returns an error if the contents of the file aren't included in the allow-list.
+func (v *Verifier) Verify(path string) error {
+	if v == nil {
+		return nil
+	}
+	digest, err := fileDigest(path)
+	if err != nil {
+		return fmt.Errorf("can't verify %s: %s", path, err)
+	}
+	return v.verifyDigest(path, digest)
+}
+
+// VerifyContent returns an error if the contents, already read from the named file, aren't included in the
+// allow-list. It allows verifying the files whose contents are loaded once, such as the configuration templates.
+func (v *Verifier) VerifyContent(name string, content []byte) error {
+	if v == nil {
+		return nil
+	}
+	sum := sha256.Sum256(content)
+	return v.verifyDigest(name, hex.EncodeToString(sum[:]))
+}
+
+func (v *Verifier) verifyDigest(name, digest string) error {
+	if _, ok := v.digests[digest]; !ok {
+		return fmt.Errorf("%s isn't in the integrations allow-list (sha256 %s)", name, digest)
+	}
+	return nil
+}
+
+// fileDigest returns the hex-encoded sha256 digest of the file.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package allowlist
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func digestOf(contents string) string {
+	sum := sha256.Sum256([]byte(contents))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeList writes an allow-list signed with the private key, returning its path.
+func writeList(t *testing.T, dir string, list string, key ed25519.PrivateKey) string {
+	path := filepath.Join(dir, "allow-list.sha256")
+	require.NoError(t, ioutil.WriteFile(path, []byte(list), 0644))
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(list)))
+	require.NoError(t, ioutil.WriteFile(path+SignatureExtension, []byte(signature+"\n"), 0644))
+	return path
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allowlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	// GIVEN an allowed and a not allowed file
+	allowed := filepath.Join(dir, "nri-allowed")
+	require.NoError(t, ioutil.WriteFile(allowed, []byte("allowed"), 0755))
+	notAllowed := filepath.Join(dir, "nri-not-allowed")
+	require.NoError(t, ioutil.WriteFile(notAllowed, []byte("not allowed"), 0755))
+
+	// AND a signed allow-list only containing the first one
+	listPath := writeList(t, dir, fmt.Sprintf("# integrations\n%s  /var/db/newrelic-infra/nri-allowed\n\n", digestOf("allowed")), privateKey)
+	v, err := Load(listPath, publicKey)
+	require.NoError(t, err)
+
+	// THEN only the listed file is allowed
+	assert.NoError(t, v.Verify(allowed))
+	err = v.Verify(notAllowed)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), digestOf("not allowed"))
+	assert.Error(t, v.Verify(filepath.Join(dir, "missing")))
+
+	// AND the modified files are refused, even if they keep their size and modification time
+	info, err := os.Stat(allowed)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(allowed, []byte("tampere"), 0755))
+	require.NoError(t, os.Chtimes(allowed, info.ModTime(), info.ModTime()))
+	assert.Error(t, v.Verify(allowed))
+}
+
+func TestVerifier_VerifyContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allowlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	// GIVEN a signed allow-list containing a configuration template
+	listPath := writeList(t, dir, digestOf("template")+"  /etc/newrelic-infra/integrations.d/template.yml\n", privateKey)
+	v, err := Load(listPath, publicKey)
+	require.NoError(t, err)
+
+	// THEN only the listed contents are allowed
+	assert.NoError(t, v.VerifyContent("template.yml", []byte("template")))
+	err = v.VerifyContent("template.yml", []byte("tampered"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "template.yml")
+}
+
+func TestVerifier_Nil(t *testing.T) {
+	var v *Verifier
+	assert.NoError(t, v.Verify("/any/file"))
+}
+
+func TestLoad_InvalidSignature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allowlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	// GIVEN an allow-list signed with another key
+	listPath := writeList(t, dir, digestOf("allowed")+"  nri-allowed\n", otherKey)
+
+	// THEN it can't be loaded
+	_, err = Load(listPath, publicKey)
+	assert.Error(t, err)
+
+	// AND neither can a list without signature
+	require.NoError(t, os.Remove(listPath+SignatureExtension))
+	_, err = Load(listPath, publicKey)
+	assert.Error(t, err)
+}
+
+func TestLoad_InvalidDigest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allowlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	listPath := writeList(t, dir, "abcdef  nri-allowed\n", privateKey)
+
+	_, err = Load(listPath, publicKey)
+	assert.EqualError(t, err, `invalid sha256 digest in allow-list line 1: "abcdef"`)
+}
+
+func TestParsePublicKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	parsed, err := ParsePublicKey(base64.StdEncoding.EncodeToString(publicKey) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, parsed)
+
+	_, err = ParsePublicKey("not base64")
+	assert.Error(t, err)
+	_, err = ParsePublicKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+}
@@ -11,6 +11,24 @@ import (
 	"github.com/google/shlex"
 )
 
+// Integration execution modes.
+const (
+	// ModePeriodic executes the integration every interval, waiting for each run to finish.
+	ModePeriodic = "periodic"
+	// ModeDaemon keeps the integration running, consuming its stream of payloads, and restarts it if it exits or
+	// stops sending heartbeats.
+	ModeDaemon = "daemon"
+)
+
+// Transports of the integration payloads.
+const (
+	// TransportStdout receives the payloads line by line through the integration standard output.
+	TransportStdout = "stdout"
+	// TransportSocket receives length-prefixed payloads through a socket created by the agent for each run, whose
+	// path is passed in the NRI_OUTPUT_SOCKET environment variable. The standard output is handled as log output.
+	TransportSocket = "socket"
+)
+
 // ConfigEntry holds an integrations YAML configuration entry. It may define multiple types of tasks
 type ConfigEntry struct {
 	InstanceName string            `yaml:"name" json:"name"`                   // integration instance name
@@ -19,10 +37,14 @@ type ConfigEntry struct {
 	Env          map[string]string `yaml:"env,omitempty" json:"env"`           // User-defined environment variables
 	Interval     string            `yaml:"interval,omitempty" json:"interval"` // User-defined interval string (duration notation)
 	Timeout      *time.Duration    `yaml:"timeout,omitempty" json:"timeout"`
+	Mode         string            `yaml:"mode,omitempty" json:"mode"`           // periodic (default) or daemon
+	Transport    string            `yaml:"transport,omitempty" json:"transport"` // stdout (default) or socket
 	User         string            `yaml:"integration_user,omitempty" json:"integration_user"`
 	WorkDir      string            `yaml:"working_dir,omitempty" json:"working_dir"`
 	Labels       map[string]string `yaml:"labels,omitempty" json:"labels"`
 	When         EnableConditions  `yaml:"when,omitempty" json:"when"`
+	Resources    Resources         `yaml:"resources,omitempty" json:"resources"`
+	Retry        Retry             `yaml:"retry,omitempty" json:"retry"`
 
 	// Legacy definition commands
 	Command         string            `yaml:"command,omitempty" json:"command"`
@@ -37,6 +59,8 @@ type ConfigEntry struct {
 }
 
 // EnableConditions condition the execution of an integration to the trueness of ALL the conditions
+// Except the feature, they are evaluated before every execution, and periodically while integrations run, to stop
+// them when they are no longer true. Integrations with interval 0 wait until the conditions are true.
 type EnableConditions struct {
 	// Feature allows enabling/disabling the OHI via agent cfg "feature" or cmd-channel Feature Flag
 	Feature string `yaml:"feature"`
@@ -45,6 +69,49 @@ type EnableConditions struct {
 	// EnvExists conditions the execution of the OHI only if the given
 	// environment variables exists and match the value.
 	EnvExists map[string]string `yaml:"env_exists"`
+	// FileContains conditions the execution of the OHI only if the given file content matches a regular expression.
+	FileContains FileContains `yaml:"file_contains"`
+	// ProcessRunning conditions the execution of the OHI only if the name or command line of a running process
+	// matches the given regular expression.
+	ProcessRunning string `yaml:"process_running"`
+	// PortListening conditions the execution of the OHI only if a TCP socket is listening on the given port.
+	PortListening int `yaml:"port_listening"`
+	// CommandSucceeds conditions the execution of the OHI only if the given command and arguments exit with a
+	// zero status.
+	CommandSucceeds []string `yaml:"command_succeeds"`
+}
+
+// FileContains is the file_contains execution condition.
+type FileContains struct {
+	Path  string `yaml:"path"`
+	Regex string `yaml:"regex"`
+}
+
+// Resources limit the host resources an integration can use. They are enforced by placing the integration process
+// in its own cgroup v2, so they are only supported on Linux hosts with a unified cgroup hierarchy. Empty values aren't
+// limited.
+type Resources struct {
+	// CPUMax is the maximum number of CPUs the integration can use, e.g. "0.5", or a raw cgroup cpu.max
+	// "<quota> <period>" value in microseconds.
+	CPUMax string `yaml:"cpu_max,omitempty" json:"cpu_max"`
+	// MemoryMax is the maximum memory the integration can use, in bytes or with a K, M, G or T suffix, e.g. "512M".
+	MemoryMax string `yaml:"memory_max,omitempty" json:"memory_max"`
+	// PidsMax is the maximum number of processes and threads the integration can create.
+	PidsMax int `yaml:"pids_max,omitempty" json:"pids_max"`
+	// IOWeight is the relative block IO weight of the integration, from 1 to 10000 (100 by default).
+	IOWeight int `yaml:"io_weight,omitempty" json:"io_weight"`
+}
+
+// Retry re-executes the failed integration runs with exponential backoff, instead of waiting for the next interval.
+// For daemon integrations it defines how their process is restarted whenever it exits.
+type Retry struct {
+	// MaxAttempts is the maximum number of re-executions after a failed run. Zero disables the retries, or restarts
+	// daemon integrations forever.
+	MaxAttempts int `yaml:"max_attempts,omitempty" json:"max_attempts"`
+	// InitialBackoff is the time to wait before the first re-execution.
+	InitialBackoff time.Duration `yaml:"initial_backoff,omitempty" json:"initial_backoff"`
+	// MaxBackoff is the maximum time to wait between re-executions. It's also bounded by the integration interval.
+	MaxBackoff time.Duration `yaml:"max_backoff,omitempty" json:"max_backoff"`
 }
 
 // ShlexOpt is a wrapper around []string so we can use go-shlex for shell tokenizing
@@ -100,6 +167,14 @@ func (cf *ConfigEntry) Sanitize() error {
 		return errors.New("use either 'exec' or 'cli_args' but not both")
 	}
 
+	if cf.Mode != "" && cf.Mode != ModePeriodic && cf.Mode != ModeDaemon {
+		return fmt.Errorf("'mode' must be either %q or %q. Found: %q", ModePeriodic, ModeDaemon, cf.Mode)
+	}
+
+	if cf.Transport != "" && cf.Transport != TransportStdout && cf.Transport != TransportSocket {
+		return fmt.Errorf("'transport' must be either %q or %q. Found: %q", TransportStdout, TransportSocket, cf.Transport)
+	}
+
 	// Checking if there is any configuration file or path to be passed externally to the integration
 	if cf.Config != nil && cf.TemplatePath != "" {
 		return fmt.Errorf("only 'config' or 'config_template_path' is allowed, not both at the same time")
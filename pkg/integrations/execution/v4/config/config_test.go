@@ -32,3 +32,27 @@ func TestConfigEntry_UppercaseEnvVars(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigEntry_Sanitize(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   ConfigEntry
+		wantErr bool
+	}{
+		{name: "defaults", input: ConfigEntry{InstanceName: "foo"}},
+		{name: "daemon over socket", input: ConfigEntry{InstanceName: "foo", Mode: ModeDaemon, Transport: TransportSocket}},
+		{name: "periodic over stdout", input: ConfigEntry{InstanceName: "foo", Mode: ModePeriodic, Transport: TransportStdout}},
+		{name: "unknown mode", input: ConfigEntry{InstanceName: "foo", Mode: "cron"}, wantErr: true},
+		{name: "unknown transport", input: ConfigEntry{InstanceName: "foo", Transport: "pipe"}, wantErr: true},
+		{name: "missing name", input: ConfigEntry{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Sanitize()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
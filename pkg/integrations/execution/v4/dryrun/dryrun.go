@@ -0,0 +1,305 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dryrun runs the integrations of a v4 configuration file once, printing the data the agent would send
+// for them instead of sending it. It's meant to troubleshoot integration configs without a running agent.
+package dryrun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel/fflag"
+	"github.com/newrelic/infrastructure-agent/internal/feature_flags"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/fwrequest"
+	cmdprotocol "github.com/newrelic/infrastructure-agent/pkg/integrations/cmdrequest/protocol"
+	cfgprotocol "github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest/protocol"
+	config_v32 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v3/config"
+	v4 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	v3 "github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v3"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/dm"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/protocol"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+// daemonTimeout bounds how long daemons without timeout are waited for their first payload or heartbeat.
+const daemonTimeout = 2 * time.Minute
+
+var (
+	heartBeatJSON = []byte("{}")
+	ffRetriever   = feature_flags.NewManager(map[string]bool{fflag.FlagProtocolV4: true})
+	dlog          = log.WithComponent("integrations.DryRun")
+)
+
+// Tester runs the integrations of a configuration file and prints their output.
+type Tester struct {
+	// Lookup resolves the integrations that are referenced by name or by a v3 definition.
+	Lookup integration.InstancesLookup
+	// PassthroughEnv holds the agent environment variables passed to the integrations.
+	PassthroughEnv []string
+	// PluginRegistry resolves the v3 integration definitions of legacy configs. Optional.
+	PluginRegistry *config_v32.PluginRegistry
+	// Out receives the report of the run.
+	Out io.Writer
+}
+
+// Test loads the integrations configuration file, resolving its discovery and variables as the agent does, runs
+// each integration once and prints the parsed payloads, the resulting data and any problem found. It returns an
+// error if any integration can't be loaded or run, or if it returns invalid payloads.
+func (t *Tester) Test(ctx context.Context, cfgPath string) error {
+	if _, err := os.Stat(cfgPath); err != nil {
+		return err
+	}
+	cfg, err := v4.LoadConfig(cfgPath, t.PluginRegistry)
+	if err != nil {
+		return fmt.Errorf("can't load integrations config: %s", err)
+	}
+	dSources, err := cfg.Databind.DataSources()
+	if err != nil {
+		return err
+	}
+	bindVals, err := databind.Fetch(dSources)
+	if err != nil {
+		return fmt.Errorf("can't fetch discovery and variables: %s", err)
+	}
+
+	var problems int
+	for _, entry := range cfg.Integrations {
+		t.printf("=== integration %q\n", entry.InstanceName)
+		template, err := integration.LoadConfigTemplate(entry.TemplatePath, entry.Config)
+		if err != nil {
+			t.printf("ERROR: can't load config template: %s\n", err)
+			problems++
+			continue
+		}
+		def, err := integration.NewDefinition(entry, t.Lookup, t.PassthroughEnv, template)
+		if err != nil {
+			t.printf("ERROR: invalid integration definition: %s\n", err)
+			problems++
+			continue
+		}
+		def.ConfigPath = cfgPath
+		problems += t.run(ctx, def, &bindVals)
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%d problem(s) found", problems)
+	}
+	return nil
+}
+
+// run executes all the discovered instances of an integration, returning the number of problems found.
+// Daemons never finish, so they are stopped once they report their first payload or heartbeat.
+func (t *Tester) run(ctx context.Context, def integration.Definition, bindVals *databind.Values) int {
+	timeout := def.Timeout
+	if def.Daemon && !def.TimeoutEnabled() {
+		timeout = daemonTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var daemon *daemonStopper
+	if def.Daemon {
+		daemon = &daemonStopper{}
+		ctx, daemon.stop = context.WithCancel(ctx)
+		defer daemon.stop()
+	}
+
+	outputs, err := def.Run(ctx, bindVals, nil, nil)
+	if err != nil {
+		t.printf("ERROR: can't run integration: %s\n", err)
+		return 1
+	}
+	if len(outputs) == 0 {
+		t.printf("WARNING: no instances to run, discovery didn't match any value\n")
+	}
+
+	var problems int
+	for i, out := range outputs {
+		t.printf("--- instance %d/%d\n", i+1, len(outputs))
+		problems += t.runInstance(ctx, def, out, timeout, daemon)
+	}
+	return problems
+}
+
+// daemonStopper stops all the instances of a daemon once any of them reports its first payload or heartbeat.
+type daemonStopper struct {
+	stop    context.CancelFunc
+	stopped int32
+}
+
+func (d *daemonStopper) firstOutput() {
+	if d != nil && atomic.CompareAndSwapInt32(&d.stopped, 0, 1) {
+		d.stop()
+	}
+}
+
+func (d *daemonStopper) isStopped() bool {
+	return d != nil && atomic.LoadInt32(&d.stopped) == 1
+}
+
+// runInstance reads the output of an integration instance until it finishes, returning the number of problems found.
+// Daemon instances are stopped once their first output line is read.
+func (t *Tester) runInstance(ctx context.Context, def integration.Definition, out integration.Output, timeout time.Duration, daemon *daemonStopper) int {
+	var stdout, stderr [][]byte
+	var errs []error
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for line := range out.Receive.Stdout {
+			stdout = append(stdout, line)
+			daemon.firstOutput()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for line := range out.Receive.Stderr {
+			stderr = append(stderr, line)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for err := range out.Receive.Errors {
+			// the daemons killed after their first output exit with an error
+			if !daemon.isStopped() {
+				errs = append(errs, err)
+			}
+		}
+	}()
+	// resource usage is reported by the agent status API, not needed here
+	go func() {
+		for range out.Receive.Resources {
+		}
+	}()
+	wg.Wait()
+	<-out.Receive.Done
+
+	var problems int
+	for _, line := range stderr {
+		t.printf("stderr: %s\n", line)
+	}
+	for _, line := range stdout {
+		problems += t.printPayload(def, out, line)
+	}
+	if daemon.isStopped() {
+		t.printf("daemon stopped after its first output\n")
+	} else if ctx.Err() == context.DeadlineExceeded {
+		t.printf("ERROR: integration timed out after %s\n", timeout)
+		problems++
+	}
+	for _, err := range errs {
+		t.printf("ERROR: %s\n", err)
+		problems++
+	}
+	return problems
+}
+
+// printPayload prints a line of the integration output and the data the agent would send for it, returning the
+// number of problems found.
+func (t *Tester) printPayload(def integration.Definition, out integration.Output, line []byte) int {
+	if bytes.Equal(bytes.Trim(line, " "), heartBeatJSON) {
+		t.printf("heartbeat\n")
+		return 0
+	}
+	if ok, _ := cmdprotocol.IsCommandRequest(line); ok {
+		t.printf("command request (not executed): %s\n", line)
+		return 0
+	}
+	if builder := cfgprotocol.GetConfigProtocolBuilder(line); builder != nil {
+		t.printf("config protocol request (not executed): %s\n", line)
+		return 0
+	}
+
+	version, err := protocol.VersionFromPayload(line, true)
+	if err != nil {
+		t.printf("ERROR: can't get protocol version: %s\npayload: %s\n", err, line)
+		return 1
+	}
+	if version != protocol.V4 {
+		data, err := protocol.ParsePayload(line, version)
+		if err != nil {
+			t.printf("ERROR: invalid payload: %s\npayload: %s\n", err, line)
+			return 1
+		}
+		t.printJSON(fmt.Sprintf("payload (protocol v%d)", version), data)
+		return 0
+	}
+
+	data, err := dm.ParsePayloadV4(line, ffRetriever)
+	if err != nil {
+		t.printf("ERROR: invalid payload: %s\npayload: %s\n", err, line)
+		return 1
+	}
+	t.printJSON("payload (protocol v4)", data)
+	t.printDataV4(def, out, data)
+
+	validationErrs := protocol.ValidatePayloadV4(line)
+	for _, err := range validationErrs {
+		t.printf("ERROR: rejected item: %s\n", err)
+	}
+	return len(validationErrs)
+}
+
+// printDataV4 prints the dimensional metrics, events and inventory the agent would send for a v4 payload.
+// Entities are not registered, so their IDs aren't decorated.
+func (t *Tester) printDataV4(def integration.Definition, out integration.Output, data protocol.DataV4) {
+	req := fwrequest.NewFwRequest(def, out.ExtraLabels, out.EntityRewrite, data)
+	labels, annotations := req.LabelsAndExtraAnnotations()
+	processor := dm.IntegrationProcessor{
+		IntegrationInterval:         def.Interval,
+		IntegrationLabels:           labels,
+		IntegrationExtraAnnotations: annotations,
+	}
+
+	for i, ds := range data.DataSets {
+		prefix := fmt.Sprintf("data[%d]", i)
+		if metrics := processor.ProcessMetrics(ds.Metrics, ds.Common, ds.Entity); len(metrics) > 0 {
+			t.printJSON(prefix+" metrics", metrics)
+		}
+
+		var events []protocol.EventData
+		for _, event := range ds.Events {
+			e, err := dm.BuildEvent(def, ds, event, labels, annotations, entity.EmptyID)
+			if err != nil {
+				dlog.WithError(err).WithField("event", event).Debug("Discarding event.")
+				continue
+			}
+			events = append(events, e)
+		}
+		if len(events) > 0 {
+			t.printJSON(prefix+" events", events)
+		}
+
+		if len(ds.Inventory) > 0 {
+			inventory := v3.BuildInventoryDataSet(
+				dlog, ds.Inventory, labels, def.ExecutorConfig.User, data.Integration.Name, ds.Entity.Name)
+			t.printJSON(prefix+" inventory", inventory)
+		}
+	}
+}
+
+func (t *Tester) printJSON(title string, v interface{}) {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.printf("%s: can't be printed: %s\n", title, err)
+		return
+	}
+	t.printf("%s:\n%s\n", title, content)
+}
+
+func (t *Tester) printf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(t.Out, format, args...)
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package dryrun
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/testhelp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	protocolV4GoFile    = testhelp.WrapScriptPath("..", "fixtures", "protocol_v4", "protocol_v4.go")
+	longRunningHBGoFile = testhelp.WrapScriptPath("..", "fixtures", "longrunning_hb", "longrunning_hb.go")
+	passthroughEnv      = []string{"GOCACHE", "GOPATH", "HOME", "PATH", "LOCALAPPDATA"}
+)
+
+func writeConfig(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "dryrun")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestTester_Test(t *testing.T) {
+	// GIVEN a config with an integration returning a protocol v4 payload
+	cfgPath := writeConfig(t, `
+integrations:
+  - name: nri-protocol-v4
+    labels:
+      env: production
+    exec: `+strings.Join(testhelp.GoRun(protocolV4GoFile), " "))
+
+	out := &bytes.Buffer{}
+	tester := Tester{Lookup: integration.ErrLookup, PassthroughEnv: passthroughEnv, Out: out}
+
+	// WHEN the integration is tested
+	err := tester.Test(context.Background(), cfgPath)
+
+	// THEN the payload and the data that would be sent are printed
+	require.NoError(t, err, out.String())
+	assert.Contains(t, out.String(), `=== integration "nri-protocol-v4"`)
+	assert.Contains(t, out.String(), "payload (protocol v4):")
+	assert.Contains(t, out.String(), "data[0] metrics:")
+	assert.Contains(t, out.String(), `"label.env": "production"`)
+	assert.NotContains(t, out.String(), "ERROR")
+}
+
+func TestTester_Test_Daemon(t *testing.T) {
+	// GIVEN a config with a daemon integration without timeout
+	cfgPath := writeConfig(t, `
+integrations:
+  - name: nri-daemon
+    mode: daemon
+    timeout: 0
+    exec: `+strings.Join(testhelp.GoRun(longRunningHBGoFile), " "))
+
+	out := &bytes.Buffer{}
+	tester := Tester{Lookup: integration.ErrLookup, PassthroughEnv: passthroughEnv, Out: out}
+
+	// WHEN the integration is tested
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := tester.Test(ctx, cfgPath)
+
+	// THEN the daemon is stopped after its first heartbeat
+	require.NoError(t, err, out.String())
+	require.NoError(t, ctx.Err())
+	assert.Contains(t, out.String(), "heartbeat")
+	assert.Contains(t, out.String(), "daemon stopped after its first output")
+	assert.NotContains(t, out.String(), "ERROR")
+}
+
+func TestTester_Test_Failures(t *testing.T) {
+	// GIVEN a config with an integration that fails and an integration that can't be found
+	cfgPath := writeConfig(t, `
+integrations:
+  - name: failing
+    exec: `+strings.Join(testhelp.GoRun(testhelp.Script("not_existing.go")), " ")+`
+  - name: nri-not-found
+`)
+
+	out := &bytes.Buffer{}
+	tester := Tester{Lookup: integration.ErrLookup, PassthroughEnv: passthroughEnv, Out: out}
+
+	// WHEN the integrations are tested
+	err := tester.Test(context.Background(), cfgPath)
+
+	// THEN the problems are reported
+	require.Error(t, err)
+	assert.Contains(t, out.String(), `=== integration "failing"`)
+	assert.Contains(t, out.String(), "ERROR: exit status")
+	assert.Contains(t, out.String(), "ERROR: invalid integration definition")
+}
+
+func TestTester_Test_MissingConfig(t *testing.T) {
+	tester := Tester{Lookup: integration.ErrLookup, Out: &bytes.Buffer{}}
+
+	assert.Error(t, tester.Test(context.Background(), "/not/existing/config.yml"))
+}
@@ -0,0 +1,254 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	agentCgroupName        = "agent"
+	integrationsCgroupName = "integrations"
+	// cgroupSetupAttempts bounds the retries to move the agent processes to its own cgroup
+	cgroupSetupAttempts = 5
+)
+
+// cgroupControllers are the cgroup v2 controllers enabled for the integrations, when available.
+var cgroupControllers = []string{"cpu", "memory", "pids", "io"}
+
+// integrationsTree is the cgroup tree where the integrations with resource limits are placed.
+var integrationsTree = &cgroupTree{
+	mountPoint:     "/sys/fs/cgroup",
+	selfCgroupFile: "/proc/self/cgroup",
+}
+
+// cgroupTree sets up the cgroup v2 sub-tree where the integrations are placed, under the cgroup of the agent.
+// As cgroup v2 doesn't allow processes in non-root cgroups that enable controllers for their children, the agent
+// processes are moved to a leaf "agent" cgroup, and each integration is placed in its own cgroup under an
+// "integrations" sibling:
+//
+//	<agent cgroup>/agent
+//	<agent cgroup>/integrations/<integration>-<pid>
+//
+// When the agent runs as a systemd service, the service requires "Delegate=yes" to manage its own cgroup.
+type cgroupTree struct {
+	mountPoint     string
+	selfCgroupFile string
+	once           sync.Once
+	path           string
+	err            error
+}
+
+// integrationsPath returns the path of the "integrations" cgroup, setting it up the first time.
+func (t *cgroupTree) integrationsPath() (string, error) {
+	t.once.Do(func() {
+		t.path, t.err = t.setup()
+	})
+	return t.path, t.err
+}
+
+func (t *cgroupTree) setup() (string, error) {
+	if _, err := os.Stat(filepath.Join(t.mountPoint, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("cgroup v2 unified hierarchy not found at %s: %v", t.mountPoint, err)
+	}
+
+	selfCgroup, err := readSelfCgroup(t.selfCgroupFile)
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Join(t.mountPoint, selfCgroup)
+
+	var controllers string
+	for attempt := 1; ; attempt++ {
+		// the root cgroup is allowed to have both processes and children with controllers
+		if selfCgroup != "/" {
+			if err := moveProcesses(base, filepath.Join(base, agentCgroupName)); err != nil {
+				return "", fmt.Errorf("can't move the agent to its own cgroup: %v", err)
+			}
+		}
+
+		controllers, err = enableControllers(base)
+		// processes spawned by the agent while the others were moved are left in its cgroup, which makes enabling
+		// the controllers fail, so they are moved again
+		if errors.Is(err, syscall.EBUSY) && selfCgroup != "/" && attempt < cgroupSetupAttempts {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		break
+	}
+
+	integrations := filepath.Join(base, integrationsCgroupName)
+	if err := os.Mkdir(integrations, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	if err := writeCgroupFile(integrations, "cgroup.subtree_control", controllers); err != nil {
+		return "", fmt.Errorf("can't enable controllers for the integrations cgroup: %v", err)
+	}
+	return integrations, nil
+}
+
+// readSelfCgroup returns the cgroup v2 path of the agent process, from the "0::<path>" line of /proc/self/cgroup.
+func readSelfCgroup(selfCgroupFile string) (string, error) {
+	content, err := ioutil.ReadFile(selfCgroupFile)
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if path := strings.TrimPrefix(scanner.Text(), "0::"); path != scanner.Text() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("can't find cgroup v2 entry in %s", selfCgroupFile)
+}
+
+// moveProcesses moves all the processes in the from cgroup to the to cgroup, creating it if it doesn't exist.
+func moveProcesses(from, to string) error {
+	if err := os.Mkdir(to, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	procs, err := ioutil.ReadFile(filepath.Join(from, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+	for _, pid := range strings.Fields(string(procs)) {
+		// the process may have finished since the list was read
+		if err := writeCgroupFile(to, "cgroup.procs", pid); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return err
+		}
+	}
+	return nil
+}
+
+// enableControllers enables for the children of the cgroup the available controllers from cgroupControllers,
+// returning them in cgroup.subtree_control format.
+func enableControllers(cgroupPath string) (string, error) {
+	available, err := ioutil.ReadFile(filepath.Join(cgroupPath, "cgroup.controllers"))
+	if err != nil {
+		return "", err
+	}
+	var enable []string
+	for _, available := range strings.Fields(string(available)) {
+		for _, controller := range cgroupControllers {
+			if available == controller {
+				enable = append(enable, "+"+controller)
+			}
+		}
+	}
+	if len(enable) == 0 {
+		return "", fmt.Errorf("none of the %v cgroup controllers are available in %s", cgroupControllers, cgroupPath)
+	}
+	controllers := strings.Join(enable, " ")
+	if err := writeCgroupFile(cgroupPath, "cgroup.subtree_control", controllers); err != nil {
+		return "", fmt.Errorf("can't enable cgroup controllers in %s: %w", cgroupPath, err)
+	}
+	return controllers, nil
+}
+
+// cgroup is the cgroup v2 where a single integration process is placed.
+type cgroup struct {
+	path string
+}
+
+// newCgroup creates a cgroup under the parent path and applies the resource limits to it.
+func newCgroup(parent, name string, limits ResourceLimits) (*cgroup, error) {
+	cg := &cgroup{path: filepath.Join(parent, name)}
+	if err := os.Mkdir(cg.path, 0755); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	files := map[string]string{}
+	if limits.CPUMax != "" {
+		files["cpu.max"] = limits.CPUMax
+	}
+	if limits.MemoryMax > 0 {
+		files["memory.max"] = strconv.FormatInt(limits.MemoryMax, 10)
+	}
+	if limits.PidsMax > 0 {
+		files["pids.max"] = strconv.FormatInt(limits.PidsMax, 10)
+	}
+	if limits.IOWeight > 0 {
+		files["io.weight"] = "default " + strconv.FormatInt(limits.IOWeight, 10)
+	}
+	for file, value := range files {
+		if err := writeCgroupFile(cg.path, file, value); err != nil {
+			_ = cg.remove()
+			return nil, fmt.Errorf("can't set %s: %v", file, err)
+		}
+	}
+	return cg, nil
+}
+
+// procsFile returns the path of the file where the processes are moved to the cgroup by writing their PID.
+func (c *cgroup) procsFile() string {
+	return filepath.Join(c.path, "cgroup.procs")
+}
+
+// usage reads the OOM kills from memory.events and the CPU throttling from cpu.stat. Files of disabled
+// controllers are ignored.
+func (c *cgroup) usage() (ResourceUsage, error) {
+	var usage ResourceUsage
+	events, err := readCgroupKeyValues(c.path, "memory.events")
+	if err != nil && !os.IsNotExist(err) {
+		return usage, err
+	}
+	usage.OOMKills = events["oom_kill"]
+
+	stats, err := readCgroupKeyValues(c.path, "cpu.stat")
+	if err != nil && !os.IsNotExist(err) {
+		return usage, err
+	}
+	usage.ThrottledPeriods = stats["nr_throttled"]
+	usage.ThrottledTime = time.Duration(stats["throttled_usec"]) * time.Microsecond
+	return usage, nil
+}
+
+// remove deletes the cgroup, which only succeeds when it doesn't contain any process.
+func (c *cgroup) remove() error {
+	return os.Remove(c.path)
+}
+
+func writeCgroupFile(cgroupPath, file, value string) error {
+	f, err := os.OpenFile(filepath.Join(cgroupPath, file), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(value)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readCgroupKeyValues parses cgroup files formatted as "<key> <value>" lines, such as memory.events or cpu.stat.
+func readCgroupKeyValues(cgroupPath, file string) (map[string]uint64, error) {
+	content, err := ioutil.ReadFile(filepath.Join(cgroupPath, file))
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]uint64{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values, nil
+}
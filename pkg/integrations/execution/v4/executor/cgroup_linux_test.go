@@ -0,0 +1,118 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cgroupFile(t *testing.T, path ...string) string {
+	content, err := ioutil.ReadFile(filepath.Join(path...))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestCgroupTree_IntegrationsPath(t *testing.T) {
+	mountPoint, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	defer os.RemoveAll(mountPoint)
+
+	// GIVEN the agent running in a systemd service cgroup
+	service := filepath.Join(mountPoint, "system.slice", "newrelic-infra.service")
+	require.NoError(t, os.MkdirAll(service, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountPoint, "cgroup.controllers"), []byte("cpuset cpu io memory pids\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(service, "cgroup.controllers"), []byte("cpu memory pids\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(service, "cgroup.procs"), []byte("1234\n"), 0644))
+	selfCgroup := filepath.Join(mountPoint, "self")
+	require.NoError(t, ioutil.WriteFile(selfCgroup, []byte("0::/system.slice/newrelic-infra.service\n"), 0644))
+
+	tree := &cgroupTree{mountPoint: mountPoint, selfCgroupFile: selfCgroup}
+
+	// WHEN the integrations cgroup is set up
+	path, err := tree.integrationsPath()
+	require.NoError(t, err)
+
+	// THEN the agent is moved to a leaf cgroup
+	assert.Equal(t, "1234", cgroupFile(t, service, agentCgroupName, "cgroup.procs"))
+	// AND the available controllers are enabled for the integrations
+	assert.Equal(t, filepath.Join(service, integrationsCgroupName), path)
+	assert.Equal(t, "+cpu +memory +pids", cgroupFile(t, service, "cgroup.subtree_control"))
+	assert.Equal(t, "+cpu +memory +pids", cgroupFile(t, path, "cgroup.subtree_control"))
+}
+
+func TestCgroupTree_NoCgroupV2(t *testing.T) {
+	mountPoint, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	defer os.RemoveAll(mountPoint)
+
+	tree := &cgroupTree{mountPoint: mountPoint, selfCgroupFile: filepath.Join(mountPoint, "self")}
+
+	_, err = tree.integrationsPath()
+	assert.Error(t, err)
+}
+
+func TestCgroup(t *testing.T) {
+	parent, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	defer os.RemoveAll(parent)
+
+	cg, err := newCgroup(parent, "nri-mysql-1234", ResourceLimits{
+		CPUMax:    "50000 100000",
+		MemoryMax: 512 << 20,
+		PidsMax:   64,
+		IOWeight:  50,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "50000 100000", cgroupFile(t, cg.path, "cpu.max"))
+	assert.Equal(t, "536870912", cgroupFile(t, cg.path, "memory.max"))
+	assert.Equal(t, "64", cgroupFile(t, cg.path, "pids.max"))
+	assert.Equal(t, "default 50", cgroupFile(t, cg.path, "io.weight"))
+
+	assert.Equal(t, filepath.Join(cg.path, "cgroup.procs"), cg.procsFile())
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(cg.path, "memory.events"), []byte("low 0\nhigh 0\nmax 12\noom 2\noom_kill 2\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(cg.path, "cpu.stat"), []byte("usage_usec 8000\nnr_periods 10\nnr_throttled 4\nthrottled_usec 3500\n"), 0644))
+
+	usage, err := cg.usage()
+	require.NoError(t, err)
+	assert.Equal(t, ResourceUsage{OOMKills: 2, ThrottledPeriods: 4, ThrottledTime: 3500 * time.Microsecond}, usage)
+}
+
+func TestExecInCgroup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	procsFile := filepath.Join(dir, "cgroup.procs")
+
+	cmd := exec.Command("/bin/sh", "-c", `echo "$@"; exit 3`, "sh", "hello", "world")
+	execInCgroup(cmd, procsFile)
+	output, err := cmd.Output()
+
+	// the command is executed with its arguments and exit code
+	require.Error(t, err)
+	assert.Equal(t, 3, cmd.ProcessState.ExitCode())
+	assert.Equal(t, "hello world\n", string(output))
+	// after being placed in the cgroup with the same PID
+	assert.Equal(t, strconv.Itoa(cmd.Process.Pid)+"\n", cgroupFile(t, procsFile))
+}
+
+func TestExecInCgroup_PlacementFails(t *testing.T) {
+	cmd := exec.Command("/bin/echo", "hello")
+	execInCgroup(cmd, filepath.Join("/non/existing/cgroup", "cgroup.procs"))
+	output, err := cmd.Output()
+
+	// the command is never run without the resource limits
+	require.Error(t, err)
+	assert.Equal(t, 126, cmd.ProcessState.ExitCode())
+	assert.Empty(t, output)
+}
@@ -8,7 +8,7 @@ import (
 	"strings"
 )
 
-// Config describes the context to execute a command: user, directory and environment variables.
+// Config describes the context to execute a command: user, directory, environment variables and resource limits.
 type Config struct {
 	User      string
 	Directory string
@@ -16,6 +16,10 @@ type Config struct {
 	Environment map[string]string
 	// Global variables that need to be retrieved before the integration runs
 	Passthrough []string
+	// Resources limits the host resources the command can use
+	Resources ResourceLimits
+	// OutputSocket receives the command payloads through a per-run socket instead of the standard output
+	OutputSocket bool
 }
 
 // for testing purposes
@@ -83,9 +87,11 @@ func (c *Config) deepClone() *Config {
 		copy(passthroughCopy, c.Passthrough)
 	}
 	return &Config{
-		User:        c.User,
-		Directory:   c.Directory,
-		Environment: envCopy,
-		Passthrough: passthroughCopy,
+		User:         c.User,
+		Directory:    c.Directory,
+		Environment:  envCopy,
+		Passthrough:  passthroughCopy,
+		Resources:    c.Resources,
+		OutputSocket: c.OutputSocket,
 	}
 }
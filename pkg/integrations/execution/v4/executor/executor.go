@@ -10,6 +10,7 @@ import (
 	"context"
 	"io"
 	"os/exec"
+	"path/filepath"
 	"sync"
 
 	"github.com/newrelic/infrastructure-agent/internal/gobackfill"
@@ -68,6 +69,21 @@ func (r *Executor) Execute(ctx context.Context, pidChan, exitCodeCh chan<- int)
 			return
 		}
 
+		// when the payloads are received through a socket, the standard output is handled as log output
+		stdoutFwd := out.Stdout
+		if r.Cfg.OutputSocket {
+			socket, err := newOutputSocket(r.Cfg.User != "")
+			if err != nil {
+				out.Errors <- err
+				return
+			}
+			// closing the socket waits for the received payloads to be forwarded
+			defer socket.close()
+			go socket.serve(out.Stdout, out.Errors)
+			cmd.Env = append(cmd.Env, OutputSocketEnv+"="+socket.path)
+			stdoutFwd = out.Stderr
+		}
+
 		// allows closing OutputSend only after the task is finished and all the data is read
 		allOutputForwarded := sync.WaitGroup{}
 		allOutputForwarded.Add(2)
@@ -75,7 +91,7 @@ func (r *Executor) Execute(ctx context.Context, pidChan, exitCodeCh chan<- int)
 		// scans standard output and error pipes and forwards individual lines to a channel
 		go func() {
 			defer allOutputForwarded.Done()
-			forwardCmdOutput(cmdOutput, out.Stdout, out.Errors)
+			forwardCmdOutput(cmdOutput, stdoutFwd, out.Errors)
 		}()
 		go func() {
 			defer allOutputForwarded.Done()
@@ -89,6 +105,17 @@ func (r *Executor) Execute(ctx context.Context, pidChan, exitCodeCh chan<- int)
 			cancelCommand()
 		}()
 
+		// when the process is run with resource limits, it's placed in its cgroup before the command is executed,
+		// so any process it spawns is limited too. Its usage is reported once it ends
+		var releaseResources func() (ResourceUsage, error)
+		if !r.Cfg.Resources.IsEmpty() {
+			releaseResources, err = limitResources(cmd, filepath.Base(r.Command), r.Cfg.Resources)
+			if err != nil {
+				illog.WithError(err).WithField("command", r.Command).
+					Warn("can't apply resource limits, running integration without them")
+			}
+		}
+
 		if err = startProcess(cmd); err != nil {
 			out.Errors <- err
 		}
@@ -113,6 +140,14 @@ func (r *Executor) Execute(ctx context.Context, pidChan, exitCodeCh chan<- int)
 			exitCodeCh <- 0
 		}
 
+		if releaseResources != nil {
+			usage, err := releaseResources()
+			if err != nil {
+				illog.WithError(err).WithField("command", r.Command).Debug("Can't release integration resources.")
+			}
+			out.Resources <- usage
+		}
+
 		allOutputForwarded.Wait() // waiting again to avoid closing output before the data is received during cancellation
 	}()
 	return receiver
@@ -16,3 +16,8 @@ func (r *Executor) userAwareCmd(ctx context.Context) *exec.Cmd {
 func startProcess(cmd *exec.Cmd) error {
 	return cmd.Start()
 }
+
+// limitResources is not supported on macOS, so resource limits aren't applied.
+func limitResources(cmd *exec.Cmd, name string, limits ResourceLimits) (func() (ResourceUsage, error), error) {
+	return nil, errResourceLimitsUnsupported
+}
@@ -4,7 +4,10 @@ package executor
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"os/exec"
+	"sync/atomic"
 )
 
 // userAwareCmd returns a cancellable Cmd struct to execute the given command with the provided
@@ -27,3 +30,41 @@ func (r *Executor) userAwareCmd(ctx context.Context) *exec.Cmd {
 func startProcess(cmd *exec.Cmd) error {
 	return cmd.Start()
 }
+
+// cgroupExecScript moves the shell to the cgroup whose cgroup.procs file is received as first argument, and then
+// replaces the shell with the command in the rest of arguments.
+const cgroupExecScript = `echo $$ > "$1" || exit 126; shift; exec "$@"`
+
+// cgroupSeq makes the integration cgroup names unique.
+var cgroupSeq uint64
+
+// limitResources creates a cgroup with the given resource limits, where the command is placed before being executed.
+// The returned function reads the resource usage and removes the cgroup, once the process has finished.
+func limitResources(cmd *exec.Cmd, name string, limits ResourceLimits) (func() (ResourceUsage, error), error) {
+	parent, err := integrationsTree.integrationsPath()
+	if err != nil {
+		return nil, err
+	}
+	cgroupName := fmt.Sprintf("%s-%d-%d", name, os.Getpid(), atomic.AddUint64(&cgroupSeq, 1))
+	cg, err := newCgroup(parent, cgroupName, limits)
+	if err != nil {
+		return nil, err
+	}
+	execInCgroup(cmd, cg.procsFile())
+	return func() (ResourceUsage, error) {
+		usage, err := cg.usage()
+		if rmErr := cg.remove(); err == nil {
+			err = rmErr
+		}
+		return usage, err
+	}, nil
+}
+
+// execInCgroup wraps the command in a shell that moves itself to the cgroup before executing the command, so the
+// command, and any process it spawns (e.g. the integration run by sudo), is in the cgroup from its start. As the
+// shell is replaced by the command, the process ID and exit code are the ones of the command.
+func execInCgroup(cmd *exec.Cmd, procsFile string) {
+	args := append([]string{"/bin/sh", "-c", cgroupExecScript, "sh", procsFile, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+	cmd.Args = args
+}
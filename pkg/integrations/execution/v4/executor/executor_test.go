@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"os/user"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -344,3 +345,23 @@ func execConfig(t require.TestingT) *Config {
 		Environment: nil,
 	}
 }
+
+func TestRunnable_Execute_OutputSocket(t *testing.T) {
+	// GIVEN a runnable instance sending its payloads through the output socket
+	cfg := execConfig(t)
+	cfg.Passthrough = []string{"GOCACHE", "GOPATH", "HOME", "PATH", "LOCALAPPDATA"}
+	cfg.OutputSocket = true
+	r := FromCmdSlice(testhelp.GoRun(fixtures.SocketGoFile), cfg)
+
+	// WHEN it is executed
+	to := r.Execute(context.Background(), nil, nil)
+
+	// THEN the payloads are received as the standard output
+	assert.Equal(t, `{"protocol_version":"4","integration":{"name":"nri-socket","version":"1.0.0"},"data":[]}`, testhelp.ChannelRead(to.Stdout))
+	payload := testhelp.ChannelRead(to.Stdout)
+	assert.True(t, len(payload) > 1024*1024)
+	assert.True(t, strings.HasPrefix(payload, "{\n  \"protocol_version\": \"4\""))
+
+	// AND the standard output is received as log output
+	assert.Equal(t, "sending payloads", testhelp.ChannelRead(to.Stderr))
+}
@@ -30,6 +30,11 @@ func startProcess(cmd *exec.Cmd) error {
 	return nil
 }
 
+// limitResources is not supported on Windows, so resource limits aren't applied.
+func limitResources(cmd *exec.Cmd, name string, limits ResourceLimits) (func() (ResourceUsage, error), error) {
+	return nil, errResourceLimitsUnsupported
+}
+
 // setPriorityClass will set the priorityClass of the agent to the cmd process
 func setPriorityClass(cmd *exec.Cmd) error {
 	priorityClass, err := windows.GetPriorityClass(windows.CurrentProcess())
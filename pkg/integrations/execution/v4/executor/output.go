@@ -10,6 +10,8 @@ type OutputSend struct {
 	Stderr chan<- []byte
 	// Errors receives any execution error or error exit status. It is closed when the task ends
 	Errors chan<- error
+	// Resources receives the resource usage when the task ends, if it was run with resource limits
+	Resources chan<- ResourceUsage
 	// Done is a channel that is closed when the integration has finished
 	Done chan<- struct{}
 }
@@ -22,6 +24,8 @@ type OutputReceive struct {
 	Stderr <-chan []byte
 	// Errors receives any execution error or error exit status. It is closed when the task ends
 	Errors <-chan error
+	// Resources receives the resource usage when the task ends, if it was run with resource limits
+	Resources <-chan ResourceUsage
 	// Done is a channel that is closed when the integration has finished
 	Done <-chan struct{}
 }
@@ -34,18 +38,21 @@ func NewOutput() (OutputSend, OutputReceive) {
 	sout := make(chan []byte, channelsCapacity)
 	serr := make(chan []byte, channelsCapacity)
 	errs := make(chan error, channelsCapacity)
+	resources := make(chan ResourceUsage, 1)
 	done := make(chan struct{})
 	return OutputSend{
-			Stdout: sout,
-			Stderr: serr,
-			Errors: errs,
-			Done:   done,
+			Stdout:    sout,
+			Stderr:    serr,
+			Errors:    errs,
+			Resources: resources,
+			Done:      done,
 		},
 		OutputReceive{
-			Stdout: sout,
-			Stderr: serr,
-			Errors: errs,
-			Done:   done,
+			Stdout:    sout,
+			Stderr:    serr,
+			Errors:    errs,
+			Resources: resources,
+			Done:      done,
 		}
 }
 
@@ -54,5 +61,6 @@ func (t *OutputSend) Close() {
 	close(t.Stdout)
 	close(t.Stderr)
 	close(t.Errors)
+	close(t.Resources)
 	close(t.Done)
 }
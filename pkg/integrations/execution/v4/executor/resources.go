@@ -0,0 +1,37 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"errors"
+	"time"
+)
+
+var errResourceLimitsUnsupported = errors.New("integration resource limits are only supported on Linux with cgroup v2")
+
+// ResourceLimits bound the host resources an executed process can use. Zero values aren't limited.
+type ResourceLimits struct {
+	// CPUMax is the cgroup cpu.max value: "<quota> <period>", in microseconds.
+	CPUMax string
+	// MemoryMax is the maximum memory usage, in bytes.
+	MemoryMax int64
+	// PidsMax is the maximum number of processes and threads.
+	PidsMax int64
+	// IOWeight is the relative block IO weight, from 1 to 10000.
+	IOWeight int64
+}
+
+// IsEmpty returns true if no limit is set.
+func (l ResourceLimits) IsEmpty() bool {
+	return l == ResourceLimits{}
+}
+
+// ResourceUsage reports how the resource limits affected an executed process.
+type ResourceUsage struct {
+	// OOMKills is the number of processes killed for exceeding the memory limit.
+	OOMKills uint64
+	// ThrottledPeriods is the number of CPU periods where the process was throttled for exceeding the CPU limit.
+	ThrottledPeriods uint64
+	// ThrottledTime is the total time the process was throttled for exceeding the CPU limit.
+	ThrottledTime time.Duration
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// OutputSocketEnv is the environment variable holding the path of the socket where the integrations run with an
+// output socket send their payloads.
+const OutputSocketEnv = "NRI_OUTPUT_SOCKET"
+
+const (
+	// payloads are prefixed by their size, as a 4-byte big-endian unsigned integer
+	payloadSizeBytes = 4
+	// maxSocketPayloadSize bounds the memory taken by a single payload
+	maxSocketPayloadSize = 64 * 1024 * 1024
+	// socketDrainTimeout bounds the time to read the pending payloads once the process has finished, in case the
+	// connection was inherited by a process that is still running
+	socketDrainTimeout = 5 * time.Second
+)
+
+// outputSocket is a Unix domain socket receiving the length-prefixed payloads of an integration run. Any number of
+// connections are accepted, and each connection can send any number of payloads.
+type outputSocket struct {
+	dir      string
+	path     string
+	listener net.Listener
+	served   chan struct{}
+	lock     sync.Mutex
+	conns    []net.Conn
+	reading  sync.WaitGroup
+}
+
+// newOutputSocket creates a socket in a new temporary folder. If the integration is run by another user, the socket
+// is accessible by any user knowing its path.
+func newOutputSocket(anyUser bool) (*outputSocket, error) {
+	dir, err := ioutil.TempDir("", "nri-output")
+	if err != nil {
+		return nil, fmt.Errorf("can't create output socket folder: %s", err)
+	}
+	path := filepath.Join(dir, "output.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("can't create output socket: %s", err)
+	}
+	if anyUser {
+		// the folder can't be listed, so only the processes receiving the path can connect
+		if err = os.Chmod(dir, 0711); err == nil {
+			err = os.Chmod(path, 0777)
+		}
+		if err != nil {
+			_ = listener.Close()
+			_ = os.RemoveAll(dir)
+			return nil, fmt.Errorf("can't set output socket permissions: %s", err)
+		}
+	}
+	return &outputSocket{
+		dir:      dir,
+		path:     path,
+		listener: listener,
+		served:   make(chan struct{}),
+	}, nil
+}
+
+// serve accepts connections and forwards the payloads they send until the socket is closed.
+func (s *outputSocket) serve(fwd chan<- []byte, errs chan<- error) {
+	defer close(s.served)
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		s.conns = append(s.conns, conn)
+		s.lock.Unlock()
+
+		s.reading.Add(1)
+		go func() {
+			defer s.reading.Done()
+			defer conn.Close()
+			forwardPayloads(conn, fwd, errs)
+		}()
+	}
+}
+
+// close stops accepting connections and waits until the payloads sent through the accepted ones are forwarded.
+func (s *outputSocket) close() {
+	_ = s.listener.Close()
+	<-s.served
+
+	deadline := time.Now().Add(socketDrainTimeout)
+	s.lock.Lock()
+	for _, conn := range s.conns {
+		_ = conn.SetReadDeadline(deadline)
+	}
+	s.lock.Unlock()
+	s.reading.Wait()
+
+	if err := os.RemoveAll(s.dir); err != nil {
+		illog.WithError(err).WithField("path", s.path).Debug("Can't remove output socket.")
+	}
+}
+
+// forwardPayloads reads the length-prefixed payloads from a connection and forwards them to the fwd channel.
+func forwardPayloads(conn io.Reader, fwd chan<- []byte, errs chan<- error) {
+	reader := bufio.NewReader(conn)
+	header := make([]byte, payloadSizeBytes)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err != io.EOF {
+				errs <- fmt.Errorf("can't read payload size from output socket: %s", err)
+			}
+			return
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size > maxSocketPayloadSize {
+			errs <- fmt.Errorf("output socket payload of %d bytes exceeds the maximum size of %d bytes", size, maxSocketPayloadSize)
+			return
+		}
+		// the buffer grows as the payload is received, instead of allocating the announced size upfront
+		var payload bytes.Buffer
+		if _, err := io.CopyN(&payload, reader, int64(size)); err != nil {
+			errs <- fmt.Errorf("can't read payload from output socket: %s", err)
+			return
+		}
+		fwd <- payload.Bytes()
+	}
+}
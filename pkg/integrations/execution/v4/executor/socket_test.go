@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sizeHeader(size uint32) []byte {
+	header := make([]byte, payloadSizeBytes)
+	binary.BigEndian.PutUint32(header, size)
+	return header
+}
+
+func TestForwardPayloads(t *testing.T) {
+	// GIVEN a connection sending two length-prefixed payloads
+	var conn bytes.Buffer
+	for _, payload := range []string{`{"first":1}`, `{"second":2}`} {
+		conn.Write(sizeHeader(uint32(len(payload))))
+		conn.WriteString(payload)
+	}
+	fwd := make(chan []byte, 2)
+	errs := make(chan error, 1)
+
+	// WHEN the payloads are forwarded
+	forwardPayloads(&conn, fwd, errs)
+
+	// THEN both payloads are received, without errors
+	require.Len(t, fwd, 2)
+	assert.Equal(t, `{"first":1}`, string(<-fwd))
+	assert.Equal(t, `{"second":2}`, string(<-fwd))
+	assert.Empty(t, errs)
+}
+
+func TestForwardPayloads_TooBig(t *testing.T) {
+	// GIVEN a connection announcing a payload over the maximum size
+	conn := bytes.NewReader(sizeHeader(maxSocketPayloadSize + 1))
+	fwd := make(chan []byte, 1)
+	errs := make(chan error, 1)
+
+	// WHEN the payloads are forwarded
+	forwardPayloads(conn, fwd, errs)
+
+	// THEN the payload is rejected
+	require.Len(t, errs, 1)
+	assert.Contains(t, (<-errs).Error(), "exceeds the maximum size")
+	assert.Empty(t, fwd)
+}
+
+func TestForwardPayloads_Truncated(t *testing.T) {
+	// GIVEN a connection announcing a payload bigger than what it sends
+	conn := bytes.NewReader(append(sizeHeader(maxSocketPayloadSize), []byte(`{"data":`)...))
+	fwd := make(chan []byte, 1)
+	errs := make(chan error, 1)
+
+	// WHEN the payloads are forwarded
+	forwardPayloads(conn, fwd, errs)
+
+	// THEN the truncated payload isn't forwarded
+	require.Len(t, errs, 1)
+	assert.Contains(t, (<-errs).Error(), "can't read payload")
+	assert.Empty(t, fwd)
+}
@@ -14,6 +14,7 @@ var (
 	TimestampDiscovery = testhelp.WrapScriptPath("..", "fixtures", "discoverer", "discoverer.go")
 	// The following test can't use `testhelp.WrapScriptPath` as it has arguments passed to it
 	InventoryGoFile = testhelp.Script(path.Join("..", "fixtures", "inventory", "inventory.go"))
+	SocketGoFile    = testhelp.Script(path.Join("..", "fixtures", "socket", "socket.go"))
 )
 
 func getExtension() string {
@@ -0,0 +1,44 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Dummy integration sending its payloads through the agent output socket, and logging through the standard output.
+
+func main() {
+	conn, err := net.Dial("unix", os.Getenv("NRI_OUTPUT_SOCKET"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	fmt.Println("sending payloads")
+	// the inventory payload spans multiple lines, which isn't possible through the standard output
+	payloads := []string{
+		`{"protocol_version":"4","integration":{"name":"nri-socket","version":"1.0.0"},"data":[]}`,
+		`{
+  "protocol_version": "4",
+  "integration": {"name": "nri-socket", "version": "1.0.0"},
+  "data": [{
+    "entity": {"name": "socket-entity", "type": "Socket"},
+    "inventory": {"config": {"value": "` + strings.Repeat("a", 1024*1024) + `"}}
+  }]
+}`,
+	}
+	for _, payload := range payloads {
+		header := make([]byte, 4)
+		binary.BigEndian.PutUint32(header, uint32(len(payload)))
+		if _, err := conn.Write(append(header, payload...)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
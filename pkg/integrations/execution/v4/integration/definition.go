@@ -20,6 +20,8 @@ import (
 
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -41,15 +43,20 @@ type Definition struct {
 	ConfigTemplate  []byte // external configuration file, if provided
 	InventorySource ids.PluginID
 	WhenConditions  []when.Condition
+	Retry           RetryPolicy
+	Daemon          bool                   // long-lived process supervised by the agent, restarted when it exits
 	CmdChanReq      *ctx.CmdChannelRequest // not empty: command-channel run/stop integration requests
 	CfgProtocol     *cfgreq.Context
+	ConfigPath      string // file the definition was loaded from, if any. Not part of the hash
+	DefinitionPath  string // v3 definition file of legacy integrations. Not part of the hash
+	TemplatePath    string // file the ConfigTemplate was loaded from, if any. Not part of the hash
 	runnable        executor.Executor
 	newTempFile     func(template []byte) (string, error)
 }
 
 func (d *Definition) Hash() string {
 	h := sha256.New()
-	identifier := fmt.Sprintf("%v%v%v%v%v%v%v%v%v%v%v%v",
+	identifier := fmt.Sprintf("%v%v%v%v%v%v%v%v%v%v%v%v%v",
 		d.Name,
 		d.Labels,
 		d.ExecutorConfig,
@@ -62,6 +69,7 @@ func (d *Definition) Hash() string {
 		d.runnable.Cfg,
 		d.runnable.Command,
 		d.CfgProtocol,
+		d.Daemon,
 	)
 	h.Write([]byte(identifier))
 	return fmt.Sprintf("%x", h.Sum(nil))
@@ -90,6 +98,22 @@ func (d *Definition) PluginID(integrationName string) ids.PluginID {
 	return ids.NewDefaultInventoryPluginID(d.Name)
 }
 
+// Executable returns the path of the file executed by the integration. As when the process is started, commands
+// without folder are looked up in the PATH, and relative commands are relative to the working directory.
+func (d *Definition) Executable() (string, error) {
+	command := d.runnable.Command
+	if command == "" {
+		return "", errors.New("integration has no executable")
+	}
+	if filepath.Base(command) == command {
+		return exec.LookPath(command)
+	}
+	if !filepath.IsAbs(command) && d.runnable.Cfg != nil && d.runnable.Cfg.Directory != "" {
+		return filepath.Join(d.runnable.Cfg.Directory, command), nil
+	}
+	return command, nil
+}
+
 func (d *Definition) Run(ctx context.Context, bindVals *databind.Values, pidC, exitCodeC chan<- int) ([]Output, error) {
 	logger := elog.WithField("integration_name", d.Name)
 	logger.Debug("Running task.")
@@ -7,6 +7,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/config"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -364,6 +365,38 @@ func TestDefinition_Hash(t *testing.T) {
 	assert.Equal(t, def3.Hash(), def2.Hash())
 }
 
+func TestDefinition_Executable(t *testing.T) {
+	goPath, err := exec.LookPath("go")
+	require.NoError(t, err)
+	workDir := filepath.Join("opt", "integrations")
+	absPath, err := filepath.Abs(filepath.Join("bin", "nri-foo"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		exec     string
+		expected string
+	}{
+		{"looked up in PATH", "go", goPath},
+		{"relative to working directory", filepath.Join(".", "bin", "nri-foo"), filepath.Join(workDir, "bin", "nri-foo")},
+		{"absolute", absPath, absPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := NewDefinition(config.ConfigEntry{
+				InstanceName: "foo",
+				Exec:         config.ShlexOpt{tt.exec},
+				WorkDir:      workDir,
+			}, ErrLookup, nil, nil)
+			require.NoError(t, err)
+
+			executable, err := def.Executable()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, executable)
+		})
+	}
+}
+
 func TestNewDefinition_LowerCasedEnvGetsUppercased(t *testing.T) {
 	const (
 		envA = "an_env_var"
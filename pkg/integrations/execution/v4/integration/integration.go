@@ -7,6 +7,7 @@ import (
 	"fmt"
 	config2 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/config"
 	"io/ioutil"
+	"regexp"
 	"time"
 
 	"github.com/newrelic/infrastructure-agent/pkg/config"
@@ -65,21 +66,52 @@ func newDefinitionWithoutLookup(ce config2.ConfigEntry, passthroughEnv []string,
 
 	ce.UppercaseEnvVars()
 
+	daemon := ce.Mode == config2.ModeDaemon
 	interval := getInterval(ce.Interval)
+	if daemon {
+		if ce.Interval != "" {
+			ilog.WithField("integration_name", ce.InstanceName).Warn("'interval' is ignored for daemon integrations")
+		}
+		interval = 0
+	}
 	// Reading this env the integration can know configured interval.
 	ce.Env[intervalEnvVarName] = fmt.Sprintf("%v", interval)
 
+	resources, err := resourceLimits(ce.Resources)
+	if err != nil {
+		return Definition{}, errors.New("Error parsing 'resources' YAML property: " + err.Error())
+	}
+
+	var retry RetryPolicy
+	if daemon {
+		retry, err = restartPolicy(ce.Retry)
+	} else {
+		retry, err = retryPolicy(ce.Retry, interval)
+	}
+	if err != nil {
+		return Definition{}, errors.New("Error parsing 'retry' YAML property: " + err.Error())
+	}
+
+	whenConditions, err := conditions(ce.When)
+	if err != nil {
+		return Definition{}, errors.New("Error parsing 'when' YAML property: " + err.Error())
+	}
+
 	d := Definition{
 		ExecutorConfig: executor.Config{
-			User:        ce.User,
-			Directory:   ce.WorkDir,
-			Environment: ce.Env,
-			Passthrough: passthroughEnv,
+			User:         ce.User,
+			Directory:    ce.WorkDir,
+			Environment:  ce.Env,
+			Passthrough:  passthroughEnv,
+			Resources:    resources,
+			OutputSocket: ce.Transport == config2.TransportSocket,
 		},
 		Labels:         ce.Labels,
 		Name:           ce.InstanceName,
 		Interval:       interval,
-		WhenConditions: conditions(ce.When),
+		WhenConditions: whenConditions,
+		Retry:          retry,
+		Daemon:         daemon,
 		ConfigTemplate: configTemplate,
 		newTempFile:    newTempFile,
 	}
@@ -247,7 +279,7 @@ func getInterval(duration string) time.Duration {
 }
 
 // get condition functions from the YAML 'when:' section
-func conditions(enabling config2.EnableConditions) []when.Condition {
+func conditions(enabling config2.EnableConditions) ([]when.Condition, error) {
 	var conds []when.Condition
 
 	// We do not consider here FeatureFlag as it is managed at the integrations manager
@@ -258,7 +290,37 @@ func conditions(enabling config2.EnableConditions) []when.Condition {
 	if len(enabling.EnvExists) > 0 {
 		conds = append(conds, when.EnvExists(enabling.EnvExists))
 	}
-	return conds
+
+	if enabling.FileContains != (config2.FileContains{}) {
+		if enabling.FileContains.Path == "" {
+			return nil, errors.New("file_contains requires a path")
+		}
+		regex, err := regexp.Compile(enabling.FileContains.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file_contains regex: %v", err)
+		}
+		conds = append(conds, when.FileContains(enabling.FileContains.Path, regex))
+	}
+
+	if enabling.ProcessRunning != "" {
+		regex, err := regexp.Compile(enabling.ProcessRunning)
+		if err != nil {
+			return nil, fmt.Errorf("invalid process_running regex: %v", err)
+		}
+		conds = append(conds, when.ProcessRunning(regex))
+	}
+
+	if enabling.PortListening != 0 {
+		if enabling.PortListening < 0 || enabling.PortListening > 65535 {
+			return nil, fmt.Errorf("invalid port_listening port: %d", enabling.PortListening)
+		}
+		conds = append(conds, when.PortListening(enabling.PortListening))
+	}
+
+	if len(enabling.CommandSucceeds) > 0 {
+		conds = append(conds, when.CommandSucceeds(enabling.CommandSucceeds))
+	}
+	return conds, nil
 }
 
 // ErrLookup is a test helper that returns errors.
@@ -9,9 +9,11 @@ import (
 	"io/ioutil"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
 	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/executor"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/fixtures"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/testhelp"
 	"github.com/stretchr/testify/assert"
@@ -225,6 +227,171 @@ timeout: 0
 	assert.False(t, i.TimeoutEnabled())
 }
 
+func TestResources(t *testing.T) {
+	// GIVEN a configuration with resource limits
+	var config config2.ConfigEntry
+	require.NoError(t, yaml.Unmarshal([]byte(`
+name: foo
+exec: bar
+resources:
+  cpu_max: 0.5
+  memory_max: 256Mi
+  pids_max: 32
+  io_weight: 50
+`), &config))
+
+	// WHEN the integration is loaded
+	i, err := NewDefinition(config, ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	// THEN the executor is configured with the limits
+	assert.Equal(t, executor.ResourceLimits{
+		CPUMax:    "50000 100000",
+		MemoryMax: 256 << 20,
+		PidsMax:   32,
+		IOWeight:  50,
+	}, i.ExecutorConfig.Resources)
+}
+
+func TestResources_Parse(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources config2.Resources
+		expected  executor.ResourceLimits
+		wantErr   bool
+	}{
+		{"empty", config2.Resources{}, executor.ResourceLimits{}, false},
+		{"cpus", config2.Resources{CPUMax: "1.5"}, executor.ResourceLimits{CPUMax: "150000 100000"}, false},
+		{"cpu quota and period", config2.Resources{CPUMax: "20000 50000"}, executor.ResourceLimits{CPUMax: "20000 50000"}, false},
+		{"too few cpus", config2.Resources{CPUMax: "0.001"}, executor.ResourceLimits{}, true},
+		{"invalid cpus", config2.Resources{CPUMax: "half"}, executor.ResourceLimits{}, true},
+		{"memory bytes", config2.Resources{MemoryMax: "1048576"}, executor.ResourceLimits{MemoryMax: 1 << 20}, false},
+		{"memory units", config2.Resources{MemoryMax: "2GB"}, executor.ResourceLimits{MemoryMax: 2 << 30}, false},
+		{"memory lowercase units", config2.Resources{MemoryMax: "512k"}, executor.ResourceLimits{MemoryMax: 512 << 10}, false},
+		{"invalid memory unit", config2.Resources{MemoryMax: "1X"}, executor.ResourceLimits{}, true},
+		{"negative pids", config2.Resources{PidsMax: -1}, executor.ResourceLimits{}, true},
+		{"io weight out of range", config2.Resources{IOWeight: 20000}, executor.ResourceLimits{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits, err := resourceLimits(tt.resources)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, limits)
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		retry    string
+		expected RetryPolicy
+		wantErr  bool
+	}{
+		{"disabled", "", RetryPolicy{}, false},
+		{"defaults", "retry:\n  max_attempts: 3", RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second}, false},
+		{"custom", "retry:\n  max_attempts: 2\n  initial_backoff: 2s\n  max_backoff: 10s", RetryPolicy{MaxAttempts: 2, InitialBackoff: 2 * time.Second, MaxBackoff: 10 * time.Second}, false},
+		{"negative attempts", "retry:\n  max_attempts: -1", RetryPolicy{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN a configuration with a 30s interval
+			var config config2.ConfigEntry
+			require.NoError(t, yaml.Unmarshal([]byte("name: foo\nexec: bar\ninterval: 30s\n"+tt.retry), &config))
+
+			// WHEN the integration is loaded
+			i, err := NewDefinition(config, ErrLookup, nil, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// THEN the retry backoff is bounded by the interval
+			assert.Equal(t, tt.expected, i.Retry)
+		})
+	}
+}
+
+func TestDaemonMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		daemon   bool
+		interval time.Duration
+		retry    RetryPolicy
+		wantErr  bool
+	}{
+		{"default", "", false, 30 * time.Second, RetryPolicy{}, false},
+		{"periodic", "mode: periodic", false, 30 * time.Second, RetryPolicy{}, false},
+		{"daemon", "mode: daemon", true, 0, RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Minute}, false},
+		{"unknown", "mode: forever", false, 0, RetryPolicy{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN a configuration with a 30s interval
+			var config config2.ConfigEntry
+			require.NoError(t, yaml.Unmarshal([]byte("name: foo\nexec: bar\ninterval: 30s\n"+tt.mode), &config))
+
+			// WHEN the integration is loaded
+			i, err := NewDefinition(config, ErrLookup, nil, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// THEN daemons ignore the interval and are always restarted
+			assert.Equal(t, tt.daemon, i.Daemon)
+			assert.Equal(t, tt.interval, i.Interval)
+			assert.Equal(t, tt.retry, i.Retry)
+		})
+	}
+}
+
+func TestWhenConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		when       string
+		conditions int
+		wantErr    bool
+	}{
+		{"none", "", 0, false},
+		{"all", `when:
+  file_exists: /etc/mysql/my.cnf
+  env_exists:
+    MYSQL: "true"
+  file_contains:
+    path: /etc/mysql/my.cnf
+    regex: port\s*=\s*3306
+  process_running: ^mysqld$
+  port_listening: 3306
+  command_succeeds: [mysqladmin, ping]`, 6, false},
+		{"file_contains without path", "when:\n  file_contains:\n    regex: foo", 0, true},
+		{"invalid file_contains regex", "when:\n  file_contains:\n    path: /etc/foo\n    regex: '['", 0, true},
+		{"invalid process_running regex", "when:\n  process_running: '['", 0, true},
+		{"invalid port", "when:\n  port_listening: 70000", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config config2.ConfigEntry
+			require.NoError(t, yaml.Unmarshal([]byte("name: foo\nexec: bar\n"+tt.when), &config))
+
+			i, err := NewDefinition(config, ErrLookup, nil, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, i.WhenConditions, tt.conditions)
+		})
+	}
+}
+
 func TestDefinition_fromName(t *testing.T) {
 	cfg := config2.ConfigEntry{
 		InstanceName: "nri-foo",
@@ -0,0 +1,90 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package integration
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/config"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/executor"
+)
+
+// cpuMaxPeriodUs is the cgroup CPU period used when cpu_max is provided as a number of CPUs.
+const cpuMaxPeriodUs = 100000
+
+const maxIOWeight = 10000
+
+var memoryUnits = map[string]int64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// resourceLimits converts the 'resources' YAML section into the executor resource limits.
+func resourceLimits(r config.Resources) (executor.ResourceLimits, error) {
+	var limits executor.ResourceLimits
+	var err error
+	if limits.CPUMax, err = parseCPUMax(r.CPUMax); err != nil {
+		return limits, err
+	}
+	if limits.MemoryMax, err = parseMemoryMax(r.MemoryMax); err != nil {
+		return limits, err
+	}
+	if r.PidsMax < 0 {
+		return limits, fmt.Errorf("'pids_max' must be positive: %d", r.PidsMax)
+	}
+	limits.PidsMax = int64(r.PidsMax)
+	if r.IOWeight < 0 || r.IOWeight > maxIOWeight {
+		return limits, fmt.Errorf("'io_weight' must be between 1 and %d: %d", maxIOWeight, r.IOWeight)
+	}
+	limits.IOWeight = int64(r.IOWeight)
+	return limits, nil
+}
+
+// parseCPUMax accepts either a number of CPUs (e.g. "1.5") or a cgroup cpu.max "<quota> <period>" value.
+func parseCPUMax(cpuMax string) (string, error) {
+	cpuMax = strings.TrimSpace(cpuMax)
+	if cpuMax == "" {
+		return "", nil
+	}
+	if cpus, err := strconv.ParseFloat(cpuMax, 64); err == nil {
+		quota := int64(math.Round(cpus * cpuMaxPeriodUs))
+		if quota < 1000 {
+			return "", fmt.Errorf("'cpu_max' must be at least 0.01 CPUs: %s", cpuMax)
+		}
+		return fmt.Sprintf("%d %d", quota, cpuMaxPeriodUs), nil
+	}
+	fields := strings.Fields(cpuMax)
+	if len(fields) == 2 {
+		quota, qErr := strconv.ParseUint(fields[0], 10, 64)
+		period, pErr := strconv.ParseUint(fields[1], 10, 64)
+		if qErr == nil && pErr == nil && quota > 0 && period > 0 {
+			return fmt.Sprintf("%d %d", quota, period), nil
+		}
+	}
+	return "", fmt.Errorf("'cpu_max' must be a number of CPUs or a '<quota> <period>' value: %s", cpuMax)
+}
+
+// parseMemoryMax accepts a number of bytes with an optional K, M, G or T suffix, e.g. "512M" or "1Gi".
+func parseMemoryMax(memoryMax string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(memoryMax))
+	if value == "" {
+		return 0, nil
+	}
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+	unit := strings.TrimLeft(value, "0123456789")
+	multiplier, ok := memoryUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("'memory_max' has an invalid unit: %s", memoryMax)
+	}
+	bytes, err := strconv.ParseInt(strings.TrimSuffix(value, unit), 10, 64)
+	if err != nil || bytes <= 0 {
+		return 0, fmt.Errorf("'memory_max' must be a positive amount of memory: %s", memoryMax)
+	}
+	return bytes * multiplier, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package integration
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/config"
+)
+
+// RetryPolicy defines how the failed runs of an integration are re-executed before waiting for the next interval,
+// or how the process of a daemon integration is restarted.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of re-executions after a failed run. Zero disables the retries, or restarts
+	// daemon integrations forever.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Enabled returns true if the failed runs are re-executed.
+func (p RetryPolicy) Enabled() bool {
+	return p.MaxAttempts > 0
+}
+
+// retryPolicy converts the 'retry' YAML section into a RetryPolicy. The backoff is bounded by the interval, so the
+// retries don't overlap with the next scheduled run.
+func retryPolicy(r config.Retry, interval time.Duration) (RetryPolicy, error) {
+	if r.MaxAttempts < 0 || r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+		return RetryPolicy{}, fmt.Errorf("'max_attempts', 'initial_backoff' and 'max_backoff' can't be negative")
+	}
+	if r.MaxAttempts == 0 {
+		return RetryPolicy{}, nil
+	}
+
+	return backoffPolicy(r, interval), nil
+}
+
+// restartPolicy converts the 'retry' YAML section into the policy restarting the process of a daemon integration.
+// Unlike the retries, restarts are always enabled.
+func restartPolicy(r config.Retry) (RetryPolicy, error) {
+	if r.MaxAttempts < 0 || r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+		return RetryPolicy{}, fmt.Errorf("'max_attempts', 'initial_backoff' and 'max_backoff' can't be negative")
+	}
+	return backoffPolicy(r, 0), nil
+}
+
+// backoffPolicy sets the default backoff of the unset retry fields. A non-zero interval bounds the backoff.
+func backoffPolicy(r config.Retry, interval time.Duration) RetryPolicy {
+	p := RetryPolicy{
+		MaxAttempts:    r.MaxAttempts,
+		InitialBackoff: r.InitialBackoff,
+		MaxBackoff:     r.MaxBackoff,
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = backoff.DefaultMin
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = backoff.DefaultMax
+	}
+	if interval > 0 && p.MaxBackoff > interval {
+		p.MaxBackoff = interval
+	}
+	if p.InitialBackoff > p.MaxBackoff {
+		p.InitialBackoff = p.MaxBackoff
+	}
+	return p
+}
@@ -21,6 +21,7 @@ var cfgLogger = log.WithComponent("integrations.Supervisor.Config").WithField("p
 
 // FluentBit default values.
 const (
+	usEndpoint              = "https://log-api.newrelic.com/log/v1"
 	euEndpoint              = "https://log-api.eu.newrelic.com/log/v1"
 	fedrampEndpoint         = "https://gov-log-api.newrelic.com/log/v1"
 	stagingEndpoint         = "https://staging-log-api.newrelic.com/log/v1"
@@ -62,6 +63,12 @@ const (
 	unixSocketRegex = `^unix_(udp|tcp):///.*`
 )
 
+// Log forwarders processing the LogCfg blocks
+const (
+	ForwarderFluentBit = "fluentbit" // default
+	ForwarderNative    = "native"
+)
+
 const (
 	rAttEntityGUID = "entity.guid.INFRA"
 	rAttFbInput    = "fb.input"
@@ -96,6 +103,11 @@ type LogCfg struct {
 	Tcp        *LogTcpCfg        `yaml:"tcp"`
 	Fluentbit  *LogExternalFBCfg `yaml:"fluentbit"`
 	Winlog     *LogWinlogCfg     `yaml:"winlog"`
+	Forwarder  string            `yaml:"forwarder"` // either "fluentbit" (default) or "native"
+	Multiline  *LogMultilineCfg  `yaml:"multiline"`
+	Parse      *LogParseCfg      `yaml:"parse"`
+	Mask       []LogMaskCfg      `yaml:"mask"`
+	Metrics    []LogMetricCfg    `yaml:"metrics"` // computed by the agent, whatever the forwarder
 }
 
 // LogSyslogCfg logging integration config from customer defined YAML, specific for the Syslog input plugin
@@ -127,12 +139,19 @@ func (l *LogCfg) IsValid() bool {
 	return l.Name != "" && (l.File != "" || l.Systemd != "" || l.Syslog != nil || l.Tcp != nil || l.Fluentbit != nil || l.Winlog != nil)
 }
 
+// IsNative returns whether the logs are forwarded by the native forwarder instead of Fluent Bit.
+func (l *LogCfg) IsNative() bool {
+	return l.Forwarder == ForwarderNative
+}
+
 // FBCfg FluentBit automatically generated configuration.
 type FBCfg struct {
 	Inputs      []FBCfgInput
 	Filters     []FBCfgFilter
 	ExternalCfg FBCfgExternal
 	Output      FBCfgOutput
+	Parsers     FBCfgParsers
+	ParsersFile string // temporary file defining the Parsers
 }
 
 // Format will return the FBCfg in the fluent bit config file format.
@@ -185,6 +204,7 @@ type FBCfgInput struct {
 	TcpFormat             string // plugin: tcp
 	TcpSeparator          string // plugin: tcp
 	TcpBufferSize         int    // plugin: tcp (note that the "tcp" plugin uses Buffer_Size (without "k"s!) instead of Buffer_Max_Size (with "k"s!))
+	MultilineParser       string // plugin: tail
 }
 
 // FBCfgFilter FluentBit FILTER config block, only "grep" plugin supported.
@@ -200,6 +220,11 @@ type FBCfgFilter struct {
 	Script    string            // plugin:lua-Script
 	Call      string            // plugin:lua-Script
 	Modifiers map[string]string //plugin: modify filter
+
+	MultilineKeyContent string // plugin: multiline
+	MultilineParser     string // plugin: multiline
+	ParserKeyName       string // plugin: parser
+	Parser              string // plugin: parser
 }
 
 // FBCfgOutput FluentBit Output config block, supporting NR output plugin.
@@ -251,13 +276,18 @@ func NewFBConf(loggingCfgs LogsCfg, logFwdCfg *config.LogForward, entityGUID, ho
 	}
 
 	for _, block := range loggingCfgs {
-		input, filters, external, err := parseConfigBlock(block, logFwdCfg.HomeDir)
+		if block.IsNative() {
+			continue
+		}
+		input, filters, external, parsers, err := parseConfigBlock(block, logFwdCfg.HomeDir)
 		if err != nil {
-			return
+			return fb, errors.Wrapf(err, "invalid logging config %s", block.Name)
 		}
 		if (input != FBCfgInput{}) {
 			fb.Inputs = append(fb.Inputs, input)
 		}
+		fb.Parsers.Parsers = append(fb.Parsers.Parsers, parsers.Parsers...)
+		fb.Parsers.MultilineParsers = append(fb.Parsers.MultilineParsers, parsers.MultilineParsers...)
 
 		fb.Filters = append(fb.Filters, filters...)
 
@@ -272,6 +302,17 @@ func NewFBConf(loggingCfgs LogsCfg, logFwdCfg *config.LogForward, entityGUID, ho
 		return
 	}
 
+	// FluentBit only reads the parsers from parsers files
+	if len(fb.Parsers.Parsers) > 0 || len(fb.Parsers.MultilineParsers) > 0 {
+		parsers, err := fb.Parsers.Format()
+		if err != nil {
+			return fb, err
+		}
+		if fb.ParsersFile, err = saveToTempFile("nr_fb_multiline_parsers", []byte(parsers)); err != nil {
+			return fb, err
+		}
+	}
+
 	// This record_modifier FILTER adds common attributes for all the log records
 	fb.Filters = append(fb.Filters, FBCfgFilter{
 		Name:  fbFilterTypeRecordModifier,
@@ -289,7 +330,18 @@ func NewFBConf(loggingCfgs LogsCfg, logFwdCfg *config.LogForward, entityGUID, ho
 	return
 }
 
-func parseConfigBlock(l LogCfg, logsHomeDir string) (input FBCfgInput, filters []FBCfgFilter, external FBCfgExternal, err error) {
+func parseConfigBlock(l LogCfg, logsHomeDir string) (input FBCfgInput, filters []FBCfgFilter, external FBCfgExternal, parsers FBCfgParsers, err error) {
+	if l.Forwarder != "" && l.Forwarder != ForwarderFluentBit {
+		err = fmt.Errorf("unknown log forwarder %q for %s, expected %s or %s", l.Forwarder, l.Name, ForwarderFluentBit, ForwarderNative)
+		return
+	}
+
+	// Fluent Bit can't replace regular expression matches, so the records are masked by the native forwarder
+	if len(l.Mask) > 0 {
+		err = fmt.Errorf("mask: only supported by the native forwarder (forwarder: %s)", ForwarderNative)
+		return
+	}
+
 	if l.Fluentbit != nil {
 		external = newFBExternalConfig(*l.Fluentbit)
 		return
@@ -298,13 +350,13 @@ func parseConfigBlock(l LogCfg, logsHomeDir string) (input FBCfgInput, filters [
 	dbPath := filepath.Join(logsHomeDir, fluentBitDbName)
 
 	if l.File != "" {
-		input, filters = parseFileInput(l, dbPath)
+		input, filters, parsers, err = parseFileInput(l, dbPath)
 	} else if l.Systemd != "" {
-		input, filters = parseSystemdInput(l, dbPath)
+		input, filters, parsers, err = parseSystemdInput(l, dbPath)
 	} else if l.Syslog != nil {
-		input, filters, err = parseSyslogInput(l)
+		input, filters, parsers, err = parseSyslogInput(l)
 	} else if l.Tcp != nil {
-		input, filters, err = parseTcpInput(l)
+		input, filters, parsers, err = parseTcpInput(l)
 	} else if l.Winlog != nil {
 		input, filters, err = parseWinlogInput(l, dbPath)
 	}
@@ -317,40 +369,49 @@ func parseConfigBlock(l LogCfg, logsHomeDir string) (input FBCfgInput, filters [
 		err = fmt.Errorf("invalid log integration config")
 		return
 	} else {
-		return input, filters, FBCfgExternal{}, nil
+		return input, filters, FBCfgExternal{}, parsers, nil
 	}
 }
 
 // Single file
-func parseFileInput(l LogCfg, dbPath string) (input FBCfgInput, filters []FBCfgFilter) {
+func parseFileInput(l LogCfg, dbPath string) (input FBCfgInput, filters []FBCfgFilter, parsers FBCfgParsers, err error) {
 	input = newFileInput(l.File, dbPath, l.Name, getBufferMaxSize(l))
 	filters = append(filters, newRecordModifierFilterForInput(l.Name, fbInputTypeTail, l.Attributes))
+	if filters, parsers, err = parseMessageProcessing(l, &input, fbGrepFieldForTail, filters); err != nil {
+		return FBCfgInput{}, nil, FBCfgParsers{}, err
+	}
 	filters = parsePattern(l, fbGrepFieldForTail, filters)
-	return input, filters
+	return input, filters, parsers, nil
 }
 
 // Systemd service: "system" plugin input
-func parseSystemdInput(l LogCfg, dbPath string) (input FBCfgInput, filters []FBCfgFilter) {
+func parseSystemdInput(l LogCfg, dbPath string) (input FBCfgInput, filters []FBCfgFilter, parsers FBCfgParsers, err error) {
 	input = newSystemdInput(l.Systemd, dbPath, l.Name)
 	filters = append(filters, newRecordModifierFilterForInput(l.Name, fbInputTypeSystemd, l.Attributes))
+	if filters, parsers, err = parseMessageProcessing(l, &input, fbGrepFieldForSystemd, filters); err != nil {
+		return FBCfgInput{}, nil, FBCfgParsers{}, err
+	}
 	filters = parsePattern(l, fbGrepFieldForSystemd, filters)
-	return input, filters
+	return input, filters, parsers, nil
 }
 
 // Syslog: "syslog" plugin
-func parseSyslogInput(l LogCfg) (input FBCfgInput, filters []FBCfgFilter, err error) {
+func parseSyslogInput(l LogCfg) (input FBCfgInput, filters []FBCfgFilter, parsers FBCfgParsers, err error) {
 	slIn, e := newSyslogInput(*l.Syslog, l.Name, getBufferMaxSize(l))
 	if e != nil {
-		return FBCfgInput{}, nil, e
+		return FBCfgInput{}, nil, FBCfgParsers{}, e
 	}
 	input = slIn
 	filters = append(filters, newRecordModifierFilterForInput(l.Name, fbInputTypeSyslog, l.Attributes))
+	if filters, parsers, err = parseMessageProcessing(l, &input, fbGrepFieldForSyslog, filters); err != nil {
+		return FBCfgInput{}, nil, FBCfgParsers{}, err
+	}
 	filters = parsePattern(l, fbGrepFieldForSyslog, filters)
-	return input, filters, nil
+	return input, filters, parsers, nil
 }
 
 // Tcp: "tcp plugin
-func parseTcpInput(l LogCfg) (input FBCfgInput, filters []FBCfgFilter, err error) {
+func parseTcpInput(l LogCfg) (input FBCfgInput, filters []FBCfgFilter, parsers FBCfgParsers, err error) {
 	tcpIn, e := newTcpInput(*l.Tcp, l.Name, getBufferMaxSize(l))
 	if e != nil {
 		err = e
@@ -358,21 +419,32 @@ func parseTcpInput(l LogCfg) (input FBCfgInput, filters []FBCfgFilter, err error
 	}
 	input = tcpIn
 	filters = append(filters, newRecordModifierFilterForInput(l.Name, fbInputTypeTcp, l.Attributes))
+	// JSON records have no known key holding the message
+	messageKey := ""
+	if l.Tcp.Format == "none" {
+		messageKey = fbGrepFieldForTcpPlain
+	}
+	if filters, parsers, err = parseMessageProcessing(l, &input, messageKey, filters); err != nil {
+		return FBCfgInput{}, nil, FBCfgParsers{}, err
+	}
 	if l.Tcp.Format == "none" {
 		filters = parsePattern(l, fbGrepFieldForTcpPlain, filters)
 	}
-	return input, filters, nil
+	return input, filters, parsers, nil
 }
 
 //Winlog: "winlog" plugin
 func parseWinlogInput(l LogCfg, dbPath string) (input FBCfgInput, filters []FBCfgFilter, err error) {
+	if l.Multiline != nil || l.Parse != nil {
+		return FBCfgInput{}, nil, fmt.Errorf("multiline and parse options aren't supported by the winlog input")
+	}
 	input = newWinlogInput(*l.Winlog, dbPath, l.Name)
 	filters = append(filters, newRecordModifierFilterForInput(l.Name, fbInputTypeWinlog, l.Attributes))
 	scriptContent, err := createLuaScript(*l.Winlog)
 	if err != nil {
 		return FBCfgInput{}, []FBCfgFilter{}, err
 	}
-	scriptName, err := saveToTempFile([]byte(scriptContent))
+	scriptName, err := saveToTempFile("nr_fb_lua_filter", []byte(scriptContent))
 	if err != nil {
 		return FBCfgInput{}, []FBCfgFilter{}, err
 	}
@@ -426,16 +498,16 @@ func createConditions(numberRanges []string, defaultIfEmpty string) (conditions
 	}
 }
 
-func saveToTempFile(config []byte) (string, error) {
+func saveToTempFile(prefix string, config []byte) (string, error) {
 	// create it
-	file, err := ioutil.TempFile("", "nr_fb_lua_filter")
+	file, err := ioutil.TempFile("", prefix)
 	if err != nil {
 		return "", err
 	}
 	defer file.Close()
 
 	cfgLogger.WithField("file", file.Name()).WithField("content", string(config)).
-		Debug("Creating temp lua filter for fb.")
+		Debug("Creating temp file for fb.")
 
 	if _, err := file.Write(config); err != nil {
 		return "", err
@@ -443,6 +515,32 @@ func saveToTempFile(config []byte) (string, error) {
 	return file.Name(), nil
 }
 
+// parseMessageProcessing adds the multiline and parsing setup of the log config, whose records hold the message in
+// the given key. Inputs without a known message key don't support them.
+func parseMessageProcessing(l LogCfg, input *FBCfgInput, key string, filters []FBCfgFilter) ([]FBCfgFilter, FBCfgParsers, error) {
+	var parsers FBCfgParsers
+	if key == "" && (l.Multiline != nil || l.Parse != nil) {
+		return nil, parsers, fmt.Errorf("multiline and parse options aren't supported by the input")
+	}
+
+	filters, multilineParser, err := parseMultiline(l, input, key, filters)
+	if err != nil {
+		return nil, parsers, err
+	}
+	if multilineParser != nil {
+		parsers.MultilineParsers = append(parsers.MultilineParsers, *multilineParser)
+	}
+
+	filters, parser, err := parseStructured(l, key, filters)
+	if err != nil {
+		return nil, parsers, err
+	}
+	if parser != nil {
+		parsers.Parsers = append(parsers.Parsers, *parser)
+	}
+	return filters, parsers, nil
+}
+
 func parsePattern(l LogCfg, fluentBitGrepField string, filters []FBCfgFilter) []FBCfgFilter {
 	if l.Pattern != "" {
 		return append(filters, newGrepFilter(l, fluentBitGrepField))
@@ -628,6 +726,14 @@ func newNROutput(cfg *config.LogForward) FBCfgOutput {
 	return ret
 }
 
+// logsEndpoint returns the URL of the Log API for the account region.
+func logsEndpoint(cfg *config.LogForward) string {
+	if endpoint := newNROutput(cfg).Endpoint; endpoint != "" {
+		return endpoint
+	}
+	return usEndpoint
+}
+
 func getBufferMaxSize(l LogCfg) int {
 	bufferSize := l.MaxLineKb
 	if bufferSize == 0 {
@@ -0,0 +1,167 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const defaultMaskReplacement = "[MASKED]"
+
+// maskDetector built-in rule detecting a kind of sensitive data.
+type maskDetector struct {
+	regex       *regexp.Regexp
+	replacement string
+	// check, if any, discards the matches that aren't sensitive data
+	check func(match string) bool
+}
+
+// Built-in masking detectors.
+var maskDetectors = map[string]maskDetector{
+	"credit_card": {
+		// 4-4-4-4 (Visa, Mastercard, Discover) and 4-6-5 (American Express) groups, or from 13 to 19 digits
+		regex:       regexp.MustCompile(`\b(?:\d{4}[ -]?\d{4}[ -]?\d{4}[ -]?\d{4}|\d{4}[ -]?\d{6}[ -]?\d{5}|\d{13,19})\b`),
+		replacement: defaultMaskReplacement,
+		check:       luhnValid,
+	},
+	"email": {
+		regex:       regexp.MustCompile(`[\w.%+-]+@[\w-]+\.[\w.-]*\w`),
+		replacement: defaultMaskReplacement,
+	},
+	"aws_key": {
+		regex:       regexp.MustCompile(`\bA[KS]IA[A-Z0-9]{16}\b`),
+		replacement: defaultMaskReplacement,
+	},
+	"bearer_token": {
+		regex:       regexp.MustCompile(`(?i)bearer\s+[\w.~+/-]+=*`),
+		replacement: "Bearer " + defaultMaskReplacement,
+	},
+	"ip": {
+		regex:       regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`),
+		replacement: defaultMaskReplacement,
+		check:       ipv4Valid,
+	},
+}
+
+// LogMaskCfg logging integration config from customer defined YAML, masking sensitive data in the records before they
+// leave the host. Either a built-in detector or a custom regular expression must be defined. Masking is applied by
+// the native forwarder, as Fluent Bit can't replace regular expression matches.
+type LogMaskCfg struct {
+	Detector    string `yaml:"detector"`    // credit_card, email, aws_key, bearer_token or ip (IPv4)
+	Regex       string `yaml:"regex"`       // RE2 regular expression matching the data to mask
+	Replacement string `yaml:"replacement"` // defaults to [MASKED]. $1 or ${name} expand the groups of the regex
+}
+
+// maskRule masking rule, whose matches are only masked if the check, if any, accepts them.
+type maskRule struct {
+	regex       *regexp.Regexp
+	replacement string
+	check       func(match string) bool
+}
+
+// logMasker masks the sensitive data of the records read by the native forwarder, applying the rules in order.
+type logMasker []maskRule
+
+// newLogMasker returns the masker of the mask config.
+func newLogMasker(mask []LogMaskCfg) (logMasker, error) {
+	var masker logMasker
+	for _, m := range mask {
+		r, err := newMaskRule(m)
+		if err != nil {
+			return nil, err
+		}
+		masker = append(masker, r)
+	}
+	return masker, nil
+}
+
+func newMaskRule(m LogMaskCfg) (maskRule, error) {
+	if (m.Detector == "") == (m.Regex == "") {
+		return maskRule{}, fmt.Errorf("mask: each rule requires either a detector or a regex")
+	}
+
+	if m.Regex != "" {
+		regex, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return maskRule{}, fmt.Errorf("mask: invalid regex: %s", err)
+		}
+		replacement := m.Replacement
+		if replacement == "" {
+			replacement = defaultMaskReplacement
+		}
+		return maskRule{regex: regex, replacement: replacement}, nil
+	}
+
+	detector, ok := maskDetectors[m.Detector]
+	if !ok {
+		return maskRule{}, fmt.Errorf("mask: unknown detector %q, expected credit_card, email, aws_key, bearer_token or ip", m.Detector)
+	}
+	replacement := m.Replacement
+	if replacement == "" {
+		replacement = detector.replacement
+	}
+	return maskRule{regex: detector.regex, replacement: replacement, check: detector.check}, nil
+}
+
+// mask returns the value with the sensitive data replaced.
+func (m logMasker) mask(value string) string {
+	for _, rule := range m {
+		if rule.check == nil {
+			value = rule.regex.ReplaceAllString(value, rule.replacement)
+			continue
+		}
+		check, replacement := rule.check, rule.replacement
+		value = rule.regex.ReplaceAllStringFunc(value, func(match string) string {
+			if !check(match) {
+				return match
+			}
+			return replacement
+		})
+	}
+	return value
+}
+
+// maskAttributes masks the string attributes of a record, in place.
+func (m logMasker) maskAttributes(attributes map[string]interface{}) {
+	for key, value := range attributes {
+		if s, ok := value.(string); ok {
+			attributes[key] = m.mask(s)
+		}
+	}
+}
+
+// luhnValid returns whether the digits of the match pass the Luhn checksum of the credit card numbers.
+func luhnValid(match string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(match)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum, double := 0, false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ipv4Valid returns whether no octet of the match exceeds 255.
+func ipv4Valid(match string) bool {
+	for _, octet := range strings.Split(match, ".") {
+		value := 0
+		for i := 0; i < len(octet); i++ {
+			value = value*10 + int(octet[i]-'0')
+		}
+		if value > 255 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Log-derived metric types.
+const (
+	logMetricTypeCount   = "count"
+	logMetricTypeGauge   = "gauge"
+	logMetricTypeSummary = "summary"
+)
+
+// LogMetricCfg logging integration config from customer defined YAML, computing a dimensional metric from the lines
+// of the log. The named groups of the pattern, as (?P<name>...) or (?<name>...), become attributes of the metric.
+type LogMetricCfg struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"`    // count (matching lines), gauge (last value) or summary (count, min, max, sum)
+	Pattern string `yaml:"pattern"` // regex matched against the lines
+	Value   string `yaml:"value"`   // gauge and summary: named group holding the value, defaults to the first group
+}
+
+// logMetric is a validated LogMetricCfg.
+type logMetric struct {
+	name       string
+	metricType string
+	pattern    *regexp.Regexp
+	// valueGroup is the index of the group holding the value, unused for counts.
+	valueGroup int
+	// dimensions are the names of the groups used as attributes, by group index.
+	dimensions map[int]string
+}
+
+func newLogMetric(m LogMetricCfg) (logMetric, error) {
+	if m.Name == "" {
+		return logMetric{}, fmt.Errorf("metrics: name is required")
+	}
+	pattern, err := regexp.Compile(onigmoNamedGroup.ReplaceAllString(m.Pattern, "(?P<$1>"))
+	if err != nil || m.Pattern == "" {
+		return logMetric{}, fmt.Errorf("metrics: invalid pattern %q for %s", m.Pattern, m.Name)
+	}
+
+	lm := logMetric{
+		name:       m.Name,
+		metricType: m.Type,
+		pattern:    pattern,
+		dimensions: map[int]string{},
+	}
+	switch m.Type {
+	case logMetricTypeCount:
+		if m.Value != "" {
+			return logMetric{}, fmt.Errorf("metrics: count %s doesn't take a value", m.Name)
+		}
+	case logMetricTypeGauge, logMetricTypeSummary:
+		if lm.valueGroup = valueGroupIndex(pattern, m.Value); lm.valueGroup <= 0 {
+			return logMetric{}, fmt.Errorf("metrics: pattern of %s has no group holding the value", m.Name)
+		}
+	default:
+		return logMetric{}, fmt.Errorf("metrics: unknown type %q for %s, expected count, gauge or summary", m.Type, m.Name)
+	}
+
+	for i, group := range pattern.SubexpNames() {
+		if group != "" && i != lm.valueGroup {
+			lm.dimensions[i] = group
+		}
+	}
+	return lm, nil
+}
+
+// valueGroupIndex returns the index of the named group, or of the first group if no name is given. It returns 0 if
+// there isn't such group.
+func valueGroupIndex(pattern *regexp.Regexp, name string) int {
+	if name == "" {
+		if pattern.NumSubexp() > 0 {
+			return 1
+		}
+		return 0
+	}
+	if i := pattern.SubexpIndex(name); i > 0 {
+		return i
+	}
+	return 0
+}
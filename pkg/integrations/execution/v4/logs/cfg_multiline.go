@@ -0,0 +1,175 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// FluentBit multiline values.
+const (
+	fbFilterTypeMultiline       = "multiline"
+	fbMultilineParserPrefix     = "multiline-"
+	fbLuaFnNameMultilineMaxLine = "multilineMaxLines"
+	defaultMultilineFlushMs     = 1000
+)
+
+// Multiline presets, matching the FluentBit built-in multiline parsers.
+var multilinePresets = map[string]bool{
+	"java":   true,
+	"python": true,
+	"go":     true,
+}
+
+// LogMultilineCfg logging integration config from customer defined YAML, grouping the lines of a record spanning
+// several lines (as stack traces) into a single record. Either a preset or custom rules can be defined.
+type LogMultilineCfg struct {
+	Preset         string `yaml:"preset"`           // java, python or go
+	Start          string `yaml:"start"`            // custom rules: regex matching the first line of the records
+	Continuation   string `yaml:"continuation"`     // custom rules: regex matching the following lines of the records
+	FlushTimeoutMs int    `yaml:"flush_timeout_ms"` // custom rules: time to wait for more lines before sending a record
+	MaxLines       int    `yaml:"max_lines"`        // lines after the limit are discarded, 0 keeps all of them
+}
+
+// FBCfgMultilineParser FluentBit MULTILINE_PARSER block, written in a parsers file, for custom multiline rules.
+//
+//	[MULTILINE_PARSER]
+//	  name          multiline-some-file
+//	  type          regex
+//	  flush_timeout 1000
+//	  rule          "start_state" "/^\d{4}-\d{2}-\d{2}/" "cont"
+//	  rule          "cont" "/^\s+/" "cont"
+type FBCfgMultilineParser struct {
+	Name         string
+	FlushTimeout int
+	Start        string
+	Continuation string
+}
+
+// FBMultilineLuaScript truncates the records grouped by the multiline parsers to their first lines.
+type FBMultilineLuaScript struct {
+	FnName   string
+	Key      string
+	MaxLines int
+}
+
+// Format will return the formatted lua script that fluent bit config is pointing to.
+func (script FBMultilineLuaScript) Format() (result string, err error) {
+	return formatTemplate("fb multiline lua", fbLuaMultilineMaxLinesFormat, script)
+}
+
+func formatTemplate(name, format string, data interface{}) (string, error) {
+	buf := new(bytes.Buffer)
+	tpl, err := template.New(name).Parse(format)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot parse log-forwarder template")
+	}
+	if err = tpl.Execute(buf, data); err != nil {
+		return "", errors.Wrap(err, "cannot write log-forwarder template")
+	}
+	return buf.String(), nil
+}
+
+// parseMultiline sets the multiline parser of the log config either in the input, for tail inputs, or in a multiline
+// filter reading the record key, for the rest of the inputs. Custom rules are returned as a parser definition.
+func parseMultiline(l LogCfg, input *FBCfgInput, key string, filters []FBCfgFilter) ([]FBCfgFilter, *FBCfgMultilineParser, error) {
+	if l.Multiline == nil {
+		return filters, nil, nil
+	}
+	parserName, parser, err := newMultilineParser(l.Name, *l.Multiline)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if input.Name == fbInputTypeTail {
+		input.MultilineParser = parserName
+	} else {
+		filters = append(filters, FBCfgFilter{
+			Name:                fbFilterTypeMultiline,
+			Match:               l.Name,
+			MultilineKeyContent: key,
+			MultilineParser:     parserName,
+		})
+	}
+
+	if l.Multiline.MaxLines > 0 {
+		maxLinesFilter, err := newMultilineMaxLinesFilter(l.Name, key, l.Multiline.MaxLines)
+		if err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, maxLinesFilter)
+	}
+	return filters, parser, nil
+}
+
+// newMultilineParser returns the name of the parser of the multiline config, along with its definition for custom
+// rules. Presets use the FluentBit built-in parsers.
+func newMultilineParser(name string, m LogMultilineCfg) (string, *FBCfgMultilineParser, error) {
+	if m.Preset != "" {
+		if m.Start != "" || m.Continuation != "" {
+			return "", nil, fmt.Errorf("multiline: %s defines both a preset and custom rules", name)
+		}
+		if !multilinePresets[m.Preset] {
+			return "", nil, fmt.Errorf("multiline: unknown preset %s, expected java, python or go", m.Preset)
+		}
+		return m.Preset, nil, nil
+	}
+
+	if m.Start == "" && m.Continuation == "" {
+		return "", nil, fmt.Errorf("multiline: %s requires either a preset or start/continuation rules", name)
+	}
+	for _, rule := range []string{m.Start, m.Continuation} {
+		if strings.Contains(rule, `"`) {
+			return "", nil, fmt.Errorf("multiline: double quotes aren't supported in rule %s", rule)
+		}
+		if _, err := regexp.Compile(rule); err != nil {
+			return "", nil, fmt.Errorf("multiline: invalid rule %s: %s", rule, err)
+		}
+	}
+
+	parser := FBCfgMultilineParser{
+		Name:         fbMultilineParserPrefix + name,
+		FlushTimeout: m.FlushTimeoutMs,
+		Start:        m.Start,
+		Continuation: m.Continuation,
+	}
+	if parser.FlushTimeout <= 0 {
+		parser.FlushTimeout = defaultMultilineFlushMs
+	}
+	// lines not matching the start rule are appended to the current record
+	if parser.Continuation == "" {
+		parser.Continuation = fmt.Sprintf("^(?!.*(?:%s))", m.Start)
+	}
+	// any line not matching the continuation rule starts a new record
+	if parser.Start == "" {
+		parser.Start = ".*"
+	}
+	return parser.Name, &parser, nil
+}
+
+func newMultilineMaxLinesFilter(tag, key string, maxLines int) (FBCfgFilter, error) {
+	script, err := FBMultilineLuaScript{
+		FnName:   fbLuaFnNameMultilineMaxLine,
+		Key:      key,
+		MaxLines: maxLines,
+	}.Format()
+	if err != nil {
+		return FBCfgFilter{}, err
+	}
+	scriptName, err := saveToTempFile("nr_fb_lua_filter", []byte(script))
+	if err != nil {
+		return FBCfgFilter{}, err
+	}
+	return FBCfgFilter{
+		Name:   fbFilterTypeLua,
+		Match:  tag,
+		Script: scriptName,
+		Call:   fbLuaFnNameMultilineMaxLine,
+	}, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFBConf_MultilinePreset(t *testing.T) {
+	fbConf, err := NewFBConf(LogsCfg{
+		{
+			Name:      "java-app",
+			File:      "/var/log/app.log",
+			Pattern:   "Exception",
+			Multiline: &LogMultilineCfg{Preset: "java"},
+		},
+	}, logFwdCfg, "0", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "java", fbConf.Inputs[0].MultilineParser)
+	assert.Empty(t, fbConf.Parsers.MultilineParsers, "presets use the built-in parsers")
+	assert.Empty(t, fbConf.ParsersFile)
+	assert.Equal(t, []FBCfgFilter{
+		inputRecordModifier("tail", "java-app"),
+		{Name: "grep", Match: "java-app", Regex: "log Exception"},
+		filterEntityBlock,
+	}, fbConf.Filters)
+}
+
+func TestNewFBConf_MultilineCustomRules(t *testing.T) {
+	fbConf, err := NewFBConf(LogsCfg{
+		{
+			Name:    "service",
+			Systemd: "service",
+			Multiline: &LogMultilineCfg{
+				Start:          `^\d{4}-\d{2}-\d{2}`,
+				FlushTimeoutMs: 2000,
+				MaxLines:       50,
+			},
+		},
+	}, logFwdCfg, "0", "")
+	require.NoError(t, err)
+	defer removeTempFile(t, fbConf.ParsersFile)
+	defer removeTempFile(t, fbConf.Filters[2].Script)
+
+	assert.Empty(t, fbConf.Inputs[0].MultilineParser)
+	assert.Equal(t, FBCfgFilter{
+		Name:                "multiline",
+		Match:               "service",
+		MultilineKeyContent: "MESSAGE",
+		MultilineParser:     "multiline-service",
+	}, fbConf.Filters[1])
+	assert.Equal(t, "lua", fbConf.Filters[2].Name)
+	assert.Equal(t, "multilineMaxLines", fbConf.Filters[2].Call)
+
+	parsers, err := ioutil.ReadFile(fbConf.ParsersFile)
+	require.NoError(t, err)
+	assert.Equal(t, `
+[MULTILINE_PARSER]
+    name          multiline-service
+    type          regex
+    flush_timeout 2000
+    rule          "start_state" "/^\d{4}-\d{2}-\d{2}/" "cont"
+    rule          "cont" "/^(?!.*(?:^\d{4}-\d{2}-\d{2}))/" "cont"
+`, string(parsers))
+
+	script, err := ioutil.ReadFile(fbConf.Filters[2].Script)
+	require.NoError(t, err)
+	assert.Contains(t, string(script), `local content = record["MESSAGE"]`)
+	assert.Contains(t, string(script), `for i = 1, 50 do`)
+}
+
+func TestParseConfigBlock_InvalidMultiline(t *testing.T) {
+	tests := map[string]LogCfg{
+		"no rules":      {Name: "file", File: "/a.log", Multiline: &LogMultilineCfg{}},
+		"unknown":       {Name: "file", File: "/a.log", Multiline: &LogMultilineCfg{Preset: "ruby"}},
+		"preset+rules":  {Name: "file", File: "/a.log", Multiline: &LogMultilineCfg{Preset: "go", Start: "^panic"}},
+		"invalid regex": {Name: "file", File: "/a.log", Multiline: &LogMultilineCfg{Start: "("}},
+		"quotes":        {Name: "file", File: "/a.log", Multiline: &LogMultilineCfg{Start: `^"`}},
+		"tcp json":      {Name: "tcp", Tcp: &LogTcpCfg{Uri: "tcp://0.0.0.0:5170", Format: "json"}, Multiline: &LogMultilineCfg{Preset: "go"}},
+		"winlog":        {Name: "win", Winlog: &LogWinlogCfg{Channel: "Security"}, Multiline: &LogMultilineCfg{Preset: "go"}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, _, _, err := parseConfigBlock(cfg, "")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFBCfgFormat_Multiline(t *testing.T) {
+	expected := `
+[SERVICE]
+    Parsers_File /tmp/parsers.conf
+
+[INPUT]
+    Name tail
+    Path /var/log/app.log
+    Tag  app
+    multiline.parser go
+
+[FILTER]
+    Name  multiline
+    Match syslog
+    multiline.key_content message
+    multiline.parser multiline-syslog
+
+[OUTPUT]
+    Name                newrelic
+    Match               *
+    licenseKey          licenseKey
+`
+
+	fbCfg := FBCfg{
+		Inputs: []FBCfgInput{
+			{Name: "tail", Tag: "app", Path: "/var/log/app.log", MultilineParser: "go"},
+		},
+		Filters: []FBCfgFilter{
+			{Name: "multiline", Match: "syslog", MultilineKeyContent: "message", MultilineParser: "multiline-syslog"},
+		},
+		Output: FBCfgOutput{
+			Name:          "newrelic",
+			Match:         "*",
+			LicenseKey:    "licenseKey",
+			ValidateCerts: true,
+		},
+		ParsersFile: "/tmp/parsers.conf",
+	}
+
+	result, _, err := fbCfg.Format()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// FluentBit parsing values.
+const (
+	fbFilterTypeParser = "parser"
+	fbParserPrefix     = "parse-"
+)
+
+// Record formats of the parse option.
+const (
+	parseFormatJSON   = "json"
+	parseFormatLogfmt = "logfmt"
+	parseFormatRegex  = "regex"
+)
+
+// onigmoNamedGroup matches the named groups in the FluentBit regex syntax, (?<name>...), to validate them as Go
+// regexes, whose syntax is (?P<name>...).
+var onigmoNamedGroup = regexp.MustCompile(`\(\?<([a-zA-Z_][a-zA-Z0-9_]*)>`)
+
+// LogParseCfg logging integration config from customer defined YAML, parsing the records message to promote its
+// fields to top-level attributes.
+type LogParseCfg struct {
+	Format     string `yaml:"format"`      // json, logfmt or regex
+	Regex      string `yaml:"regex"`       // regex format: named groups, as (?<name>...), become attributes
+	TimeKey    string `yaml:"time_key"`    // field holding the record timestamp
+	TimeFormat string `yaml:"time_format"` // strptime format of the time_key field
+}
+
+// FBCfgParser FluentBit PARSER block, written in a parsers file.
+//
+//	[PARSER]
+//	  Name        parse-some-file
+//	  Format      regex
+//	  Regex       ^(?<level>[A-Z]+) (?<message>.*)$
+//	  Time_Key    time
+//	  Time_Format %Y-%m-%dT%H:%M:%S
+//	  Time_Keep   On
+type FBCfgParser struct {
+	Name       string
+	Format     string
+	Regex      string
+	TimeKey    string
+	TimeFormat string
+}
+
+// FBCfgParsers FluentBit parsers for the logging configs. FluentBit doesn't read them from the main config file, so
+// they are written in a separate parsers file.
+type FBCfgParsers struct {
+	Parsers          []FBCfgParser
+	MultilineParsers []FBCfgMultilineParser
+}
+
+// Format will return the parsers in the fluent bit parsers file format.
+func (p FBCfgParsers) Format() (result string, err error) {
+	return formatTemplate("fb parsers", fbParsersFormat, p)
+}
+
+// parseStructured adds a parser filter promoting the fields of the message, held in the record key, to attributes.
+// The original message and the rest of the record attributes are kept.
+func parseStructured(l LogCfg, key string, filters []FBCfgFilter) ([]FBCfgFilter, *FBCfgParser, error) {
+	if l.Parse == nil {
+		return filters, nil, nil
+	}
+	parser, err := newParser(l.Name, *l.Parse)
+	if err != nil {
+		return nil, nil, err
+	}
+	filters = append(filters, FBCfgFilter{
+		Name:          fbFilterTypeParser,
+		Match:         l.Name,
+		ParserKeyName: key,
+		Parser:        parser.Name,
+	})
+	return filters, &parser, nil
+}
+
+func newParser(name string, p LogParseCfg) (FBCfgParser, error) {
+	switch p.Format {
+	case parseFormatJSON, parseFormatLogfmt:
+		if p.Regex != "" {
+			return FBCfgParser{}, fmt.Errorf("parse: regex is only supported by the regex format")
+		}
+	case parseFormatRegex:
+		if err := validateParseRegex(p.Regex); err != nil {
+			return FBCfgParser{}, err
+		}
+	default:
+		return FBCfgParser{}, fmt.Errorf("parse: unknown format %q, expected json, logfmt or regex", p.Format)
+	}
+	if (p.TimeKey == "") != (p.TimeFormat == "") {
+		return FBCfgParser{}, fmt.Errorf("parse: time_key and time_format must be set together")
+	}
+
+	return FBCfgParser{
+		Name:       fbParserPrefix + name,
+		Format:     p.Format,
+		Regex:      p.Regex,
+		TimeKey:    p.TimeKey,
+		TimeFormat: p.TimeFormat,
+	}, nil
+}
+
+// validateParseRegex checks the regex compiles and has named groups to extract the attributes from.
+func validateParseRegex(regex string) error {
+	if regex == "" {
+		return fmt.Errorf("parse: the regex format requires a regex")
+	}
+	re, err := regexp.Compile(onigmoNamedGroup.ReplaceAllString(regex, "(?P<$1>"))
+	if err != nil {
+		return fmt.Errorf("parse: invalid regex %s: %s", regex, err)
+	}
+	for _, group := range re.SubexpNames() {
+		if group != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("parse: regex %s has no named groups, as (?<name>...)", regex)
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFBConf_Parse(t *testing.T) {
+	fbConf, err := NewFBConf(LogsCfg{
+		{
+			Name:    "json-file",
+			File:    "/var/log/app.log",
+			Pattern: "ERROR",
+			Parse:   &LogParseCfg{Format: "json", TimeKey: "ts", TimeFormat: "%Y-%m-%dT%H:%M:%S"},
+		},
+		{
+			Name:    "regex-service",
+			Systemd: "service",
+			Parse:   &LogParseCfg{Format: "regex", Regex: `^(?<level>[A-Z]+) (?<msg>.*)$`},
+		},
+	}, logFwdCfg, "0", "")
+	require.NoError(t, err)
+	defer removeTempFile(t, fbConf.ParsersFile)
+
+	assert.Equal(t, []FBCfgFilter{
+		inputRecordModifier("tail", "json-file"),
+		{Name: "parser", Match: "json-file", ParserKeyName: "log", Parser: "parse-json-file"},
+		{Name: "grep", Match: "json-file", Regex: "log ERROR"},
+		inputRecordModifier("systemd", "regex-service"),
+		{Name: "parser", Match: "regex-service", ParserKeyName: "MESSAGE", Parser: "parse-regex-service"},
+		filterEntityBlock,
+	}, fbConf.Filters)
+
+	parsers, err := ioutil.ReadFile(fbConf.ParsersFile)
+	require.NoError(t, err)
+	assert.Equal(t, `
+[PARSER]
+    Name   parse-json-file
+    Format json
+    Time_Key    ts
+    Time_Format %Y-%m-%dT%H:%M:%S
+    Time_Keep   On
+
+[PARSER]
+    Name   parse-regex-service
+    Format regex
+    Regex  ^(?<level>[A-Z]+) (?<msg>.*)$
+`, string(parsers))
+}
+
+func TestParseConfigBlock_InvalidParse(t *testing.T) {
+	tests := map[string]*LogParseCfg{
+		"unknown format":      {Format: "xml"},
+		"missing regex":       {Format: "regex"},
+		"invalid regex":       {Format: "regex", Regex: "(?<level>[A-Z]+"},
+		"no named groups":     {Format: "regex", Regex: "^[A-Z]+ .*$"},
+		"regex in json":       {Format: "json", Regex: "(?<level>[A-Z]+)"},
+		"time key w/o format": {Format: "logfmt", TimeKey: "ts"},
+	}
+	for name, parse := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, _, _, err := parseConfigBlock(LogCfg{Name: "file", File: "/a.log", Parse: parse}, "")
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("tcp json", func(t *testing.T) {
+		_, _, _, _, err := parseConfigBlock(LogCfg{
+			Name:  "tcp",
+			Tcp:   &LogTcpCfg{Uri: "tcp://0.0.0.0:5170", Format: "json"},
+			Parse: &LogParseCfg{Format: "json"},
+		}, "")
+		assert.Error(t, err)
+	})
+}
+
+func TestFBCfgFormat_Parse(t *testing.T) {
+	expected := `
+[FILTER]
+    Name  parser
+    Match app
+    Key_Name log
+    Parser parse-app
+    Reserve_Data On
+    Preserve_Key On
+`
+
+	fbCfg := FBCfg{
+		Filters: []FBCfgFilter{
+			{Name: "parser", Match: "app", ParserKeyName: "log", Parser: "parse-app"},
+		},
+	}
+
+	result, _, err := fbCfg.Format()
+	assert.NoError(t, err)
+	assert.Contains(t, result, expected)
+}
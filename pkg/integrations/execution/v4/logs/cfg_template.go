@@ -2,7 +2,12 @@
 // SPDX-License-Identifier: Apache-2.0
 package logs
 
-var fbConfigFormat = `{{- range .Inputs }}
+var fbConfigFormat = `{{- if .ParsersFile }}
+[SERVICE]
+    Parsers_File {{ .ParsersFile }}
+{{ end -}}
+
+{{- range .Inputs }}
 [INPUT]
     Name {{ .Name }}
     {{- if .Path }}
@@ -65,6 +70,9 @@ var fbConfigFormat = `{{- range .Inputs }}
     {{- if .TcpBufferSize }}
     Buffer_Size {{ .TcpBufferSize }}
     {{- end }}
+    {{- if .MultilineParser }}
+    multiline.parser {{ .MultilineParser }}
+    {{- end }}
 {{ end -}}
 
 {{- range .Filters }}
@@ -94,6 +102,18 @@ var fbConfigFormat = `{{- range .Inputs }}
     {{- if .Call }}
     call {{ .Call }}
     {{- end }}
+    {{- if .MultilineKeyContent }}
+    multiline.key_content {{ .MultilineKeyContent }}
+    {{- end }}
+    {{- if .MultilineParser }}
+    multiline.parser {{ .MultilineParser }}
+    {{- end }}
+    {{- if .Parser }}
+    Key_Name {{ .ParserKeyName }}
+    Parser {{ .Parser }}
+    Reserve_Data On
+    Preserve_Key On
+    {{- end }}
 {{ end -}}
 
 {{- if .Output }}
@@ -140,3 +160,43 @@ var fbLuaScriptFormat = `function {{ .FnName }}(tag, timestamp, record)
     -- If there is not any matching conditions discard everything
     return -1, 0, 0
  end`
+
+var fbParsersFormat = `{{- range .Parsers }}
+[PARSER]
+    Name   {{ .Name }}
+    Format {{ .Format }}
+    {{- if .Regex }}
+    Regex  {{ .Regex }}
+    {{- end }}
+    {{- if .TimeKey }}
+    Time_Key    {{ .TimeKey }}
+    Time_Format {{ .TimeFormat }}
+    Time_Keep   On
+    {{- end }}
+{{ end -}}
+
+{{- range .MultilineParsers }}
+[MULTILINE_PARSER]
+    name          {{ .Name }}
+    type          regex
+    flush_timeout {{ .FlushTimeout }}
+    rule          "start_state" "/{{ .Start }}/" "cont"
+    rule          "cont" "/{{ .Continuation }}/" "cont"
+{{ end -}}`
+
+var fbLuaMultilineMaxLinesFormat = `function {{ .FnName }}(tag, timestamp, record)
+    local content = record["{{ .Key }}"]
+    if type(content) ~= "string" then
+        return 0, 0, 0
+    end
+    -- Keep the content up to the end of the last allowed line
+    local pos = 0
+    for i = 1, {{ .MaxLines }} do
+        pos = string.find(content, "\n", pos + 1, true)
+        if pos == nil then
+            return 0, 0, 0
+        end
+    end
+    record["{{ .Key }}"] = string.sub(content, 1, pos - 1)
+    return 2, timestamp, record
+ end`
@@ -22,6 +22,10 @@ const (
 	fluentBitTagTroubleshoot = "nri-troubleshoot"
 )
 
+// ErrNoFluentBitConfigs is returned when all the logging configs are forwarded by the native forwarder, so Fluent Bit
+// isn't needed.
+var ErrNoFluentBitConfigs = errors.New("all the logging configs are forwarded by the native forwarder")
+
 type CfgLoader struct {
 	config           config.LogForward
 	loadFilesFn      fs.FilesInFolderFn
@@ -45,14 +49,47 @@ func (l *CfgLoader) GetConfigDir() string {
 // LoadAll loads and parses the logging configuration. It returns ok=false in case an error occurred, which should block
 // the start of the log forwarding feature.
 func (l *CfgLoader) LoadAll() (c FBCfg, ok bool) {
+	allFilesCfgs, agentGUID, hostname, ok := l.loadCfgs()
+	if !ok {
+		return FBCfg{}, false
+	}
+
+	c, err := NewFBConf(allFilesCfgs, &(l.config), agentGUID, hostname)
+	if err != nil {
+		loaderLogger.WithError(err).Error("could not process logging configurations")
+		return FBCfg{}, false
+	}
+
+	return
+}
+
+// LoadNative loads and parses the logging configuration forwarded by the native forwarder. It returns ok=false in case
+// an error occurred, which should block the start of the native log forwarding.
+func (l *CfgLoader) LoadNative() (c NativeCfg, ok bool) {
+	allFilesCfgs, agentGUID, hostname, ok := l.loadCfgs()
+	if !ok {
+		return NativeCfg{}, false
+	}
+
+	c, err := NewNativeConf(allFilesCfgs, &(l.config), agentGUID, hostname)
+	if err != nil {
+		loaderLogger.WithError(err).Error("could not process native logging configurations")
+		return NativeCfg{}, false
+	}
+
+	return c, true
+}
+
+// loadCfgs loads all the logging configurations, along with the agent entity GUID and the short hostname.
+func (l *CfgLoader) loadCfgs() (cfgs LogsCfg, agentGUID string, hostname string, ok bool) {
 	if l.config.ConfigsDir == "" && !l.config.Troubleshoot.Enabled {
 		loaderLogger.Error("invalid config, lacking config folder or troubleshoot mode")
-		return FBCfg{}, false
+		return nil, "", "", false
 	}
 
 	allFilesCfgs, ok := l.loadFolderCfgs()
 	if !ok {
-		return FBCfg{}, false
+		return nil, "", "", false
 	}
 
 	if t := l.loadTroubleshootCfg(); t != nil {
@@ -61,23 +98,17 @@ func (l *CfgLoader) LoadAll() (c FBCfg, ok bool) {
 
 	if len(allFilesCfgs) == 0 {
 		loaderLogger.Debug("Could not find any configuration for logging forwarder.")
-		return FBCfg{}, false
+		return nil, "", "", false
 	}
 
 	// single FluentBit instance config for all logs in all files
-	agentGUID := l.agentIDFn().GUID // blocks until ID is available
+	agentGUID = l.agentIDFn().GUID.String() // blocks until ID is available
 	_, shortHostName, err := l.hostnameResolver.Query()
 	if err != nil {
 		loaderLogger.Debug("Could not determine hostname.")
 	}
 
-	c, err = NewFBConf(allFilesCfgs, &(l.config), agentGUID.String(), shortHostName)
-	if err != nil {
-		loaderLogger.WithError(err).Error("could not process logging configurations")
-		return FBCfg{}, false
-	}
-
-	return
+	return allFilesCfgs, agentGUID, shortHostName, true
 }
 
 // loadFolderCfgs loads all YAML logging configuration files from the logging configuration folder and parses them
@@ -162,6 +193,9 @@ func (l *CfgLoader) LoadAndFormat() (string, FBCfgExternal, error) {
 	if !ok {
 		return "", FBCfgExternal{}, errors.New("failed to load log configs")
 	}
+	if len(fbConfig.Inputs) == 0 && fbConfig.ExternalCfg == (FBCfgExternal{}) {
+		return "", FBCfgExternal{}, ErrNoFluentBitConfigs
+	}
 	return fbConfig.Format()
 }
 
@@ -107,6 +107,15 @@ logs:
 	addFile(t, exampleFileAndValidCfg, "file.yml.example", validContent)
 	addFile(t, exampleFileAndValidCfg, "valid.yml", validContent)
 
+	// Directory containing a configuration file with an invalid parse option
+	invalidParseCfg, err := ioutil.TempDir("", "test-load-content")
+	defer os.RemoveAll(invalidParseCfg)
+	require.NoError(t, err)
+	addFile(t, invalidParseCfg, "invalid.yml", validContent+`
+    parse:
+      format: xml
+`)
+
 	tests := []struct {
 		name     string
 		folder   string
@@ -119,6 +128,7 @@ logs:
 		{"folder with valid file", onlyValidCfg, expectedCfg, true},
 		{"folder with only example (non-yml) files", onlyExampleFile, emptyCfg, false},
 		{"folder with a valid file and example (non-yml) files", exampleFileAndValidCfg, expectedCfg, true},
+		{"folder with an invalid parse option", invalidParseCfg, emptyCfg, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -132,6 +142,44 @@ logs:
 	}
 }
 
+func TestCfgLoader_LoadNative(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-load-native")
+	defer os.RemoveAll(dir)
+	require.NoError(t, err)
+	addFile(t, dir, "logs.yml", `
+logs:
+  - name: foo
+    file: /file/path
+  - name: bar
+    file: /other/path
+    forwarder: native
+`)
+
+	cfg, ok := NewFolderLoader(newTestConf(dir, disabledTroubleshootCfg), idnProvide, hostnameProvider).LoadNative()
+	require.True(t, ok)
+
+	require.Len(t, cfg.Pipelines, 1)
+	assert.Equal(t, "bar", cfg.Pipelines[0].Name)
+	assert.Equal(t, "FOOBAR", cfg.CommonAttributes["entity.guid.INFRA"])
+	assert.Equal(t, hostName, cfg.CommonAttributes["hostname"])
+}
+
+func TestCfgLoader_LoadAndFormat_OnlyNative(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-load-only-native")
+	defer os.RemoveAll(dir)
+	require.NoError(t, err)
+	addFile(t, dir, "logs.yml", `
+logs:
+  - name: foo
+    file: /file/path
+    forwarder: native
+`)
+
+	// Fluent Bit isn't run when all the logs are forwarded by the native forwarder
+	_, _, err = NewFolderLoader(newTestConf(dir, disabledTroubleshootCfg), idnProvide, hostnameProvider).LoadAndFormat()
+	assert.Equal(t, ErrNoFluentBitConfigs, err)
+}
+
 func TestCfgLoader_LoadAll_TroubleshootDisabed(t *testing.T) {
 	disabledTroubleshootCfg := config.NewTroubleshootCfg(false, false, "")
 	_, ok := NewFolderLoader(newTestConf("", disabledTroubleshootCfg), idnProvide, hostnameProvider).LoadAll()
@@ -0,0 +1,249 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/id"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/dm"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/hostname"
+)
+
+var nativeLogger = log.WithComponent("integrations.NativeLogForwarder").WithField("process", "log-forwarder")
+
+const (
+	// nativeQueueSize is the number of records waiting to be sent before the inputs stop reading.
+	nativeQueueSize = 5000
+	// nativePositionsSaveInterval is how often the positions DB is written to disk.
+	nativePositionsSaveInterval = 5 * time.Second
+	// nativeInputMaxBackoff bounds the time to wait before restarting a failed input.
+	nativeInputMaxBackoff = time.Minute
+	// nativeObserverName identifies the native forwarder among the hostname change observers.
+	nativeObserverName = "NativeLogForwarder"
+)
+
+// record is a log record read by the native forwarder, as sent to the Log API.
+type record struct {
+	Timestamp  int64                  `json:"timestamp"` // milliseconds since epoch
+	Message    string                 `json:"message"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// NativeForwarder forwards logs without Fluent Bit: it reads the inputs of the logging configs with the native
+// forwarder and sends their records to the Log API through the agent HTTP client. It also computes the metrics
+// defined by the logging configs, which are sent through the dimensional metrics emitter. It is restarted whenever
+// the logging configs change, and whenever the agent entity GUID or the short hostname added to the records change,
+// as the Fluent Bit supervisor does.
+type NativeForwarder struct {
+	cfgLoader              *CfgLoader
+	client                 backendhttp.Client
+	userAgent              string
+	dmEmitter              dm.Emitter
+	watchChanges           func(ctx context.Context, changes chan<- struct{})
+	listenAgentIDChanges   id.UpdateNotifyFn
+	hostnameChangeNotifier hostname.ChangeNotifier
+}
+
+// NewNativeForwarder creates a native log forwarder for the configs loaded by the cfgLoader.
+func NewNativeForwarder(cfgLoader *CfgLoader, client backendhttp.Client, userAgent string, dmEmitter dm.Emitter, agentIDNotifier id.UpdateNotifyFn, notifier hostname.ChangeNotifier) *NativeForwarder {
+	cw := NewConfigChangesWatcher(cfgLoader.GetConfigDir())
+	return &NativeForwarder{
+		cfgLoader:              cfgLoader,
+		client:                 client,
+		userAgent:              userAgent,
+		dmEmitter:              dmEmitter,
+		watchChanges:           cw.Watch,
+		listenAgentIDChanges:   agentIDNotifier,
+		hostnameChangeNotifier: notifier,
+	}
+}
+
+// Run forwards the logs until the context is cancelled.
+func (f *NativeForwarder) Run(ctx context.Context) {
+	changes := make(chan struct{}, 1)
+	f.watchChanges(ctx, changes)
+	f.listenAgentIDChanges(changes, id.NotifyOnReconnect)
+
+	hostnameChanges := make(chan hostname.ChangeNotification, 1)
+	f.hostnameChangeNotifier.AddObserver(nativeObserverName, hostnameChanges)
+	defer f.hostnameChangeNotifier.RemoveObserver(nativeObserverName)
+
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f.forward(runCtx)
+		}()
+
+		for reload := false; !reload; {
+			select {
+			case <-ctx.Done():
+				cancel()
+				<-done
+				return
+			case <-changes:
+				nativeLogger.Debug("Logging configuration or agent ID changed. Reloading native log forwarder.")
+				reload = true
+			case change := <-hostnameChanges:
+				// only the short hostname is added to the records
+				if change.What == hostname.Short || change.What == hostname.ShortAndFull {
+					nativeLogger.Debug("Hostname changed. Reloading native log forwarder.")
+					reload = true
+				}
+			}
+		}
+		cancel()
+		<-done
+	}
+}
+
+// forward runs the pipelines of the native logging configs until the context is cancelled.
+func (f *NativeForwarder) forward(ctx context.Context) {
+	cfg, ok := f.cfgLoader.LoadNative()
+	if !ok || len(cfg.Pipelines) == 0 {
+		return
+	}
+	nativeLogger.WithField("pipelines", len(cfg.Pipelines)).Info("Starting native log forwarder.")
+
+	pos := loadPositions(cfg.PositionsFile)
+	records := make(chan record, nativeQueueSize)
+	output := newNativeOutput(cfg, f.client, f.userAgent)
+	// the output is stopped once the inputs are stopped, to send all their records
+	outputCtx, stopOutput := context.WithCancel(context.Background())
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		output.run(outputCtx, records)
+	}()
+
+	metrics := newMetricsAggregator(time.Now())
+	var wg sync.WaitGroup
+	for _, pCfg := range cfg.Pipelines {
+		p := &nativePipeline{
+			cfg:       pCfg,
+			positions: pos,
+			out:       records,
+			metrics:   metrics,
+			log:       nativeLogger.WithField("name", pCfg.Name),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.run(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(nativePositionsSaveInterval)
+	defer ticker.Stop()
+	metricsTicker := time.NewTicker(logMetricsInterval)
+	defer metricsTicker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			savePositions(pos)
+		case <-metricsTicker.C:
+			sendLogMetrics(f.dmEmitter, metrics.flush(time.Now()))
+		case <-ctx.Done():
+			wg.Wait()
+			stopOutput()
+			<-outputDone
+			sendLogMetrics(f.dmEmitter, metrics.flush(time.Now()))
+			savePositions(pos)
+			nativeLogger.Debug("Native log forwarder stopped.")
+			return
+		}
+	}
+}
+
+func savePositions(pos *positions) {
+	if err := pos.save(); err != nil {
+		nativeLogger.WithError(err).WithField("file", pos.path).Warn("can't save log positions")
+	}
+}
+
+// nativePipeline reads the records of a logging config, filtering and enriching them before they are sent.
+type nativePipeline struct {
+	cfg       NativePipelineCfg
+	positions *positions
+	out       chan<- record
+	metrics   *metricsAggregator
+	log       log.Entry
+}
+
+// run reads the input of the pipeline until the context is cancelled, restarting it with backoff when it fails.
+func (p *nativePipeline) run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := p.runInput(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > nativeInputMaxBackoff {
+			backoff = time.Second
+		}
+		p.log.WithError(err).WithField("backoff", backoff).Warn("log input stopped, restarting it")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > nativeInputMaxBackoff {
+			backoff = nativeInputMaxBackoff
+		}
+	}
+}
+
+func (p *nativePipeline) runInput(ctx context.Context) error {
+	switch p.cfg.InputType {
+	case nativeInputTypeTail:
+		return p.tail(ctx)
+	case fbInputTypeSystemd:
+		return p.readJournal(ctx)
+	case nativeInputTypeTcp:
+		return p.listenTcp(ctx)
+	case fbInputTypeSyslog:
+		return p.listenSyslog(ctx)
+	}
+	return fmt.Errorf("unsupported input: %s", p.cfg.InputType)
+}
+
+// emit masks a record read by the input and computes its metrics, then filters and sends it, along with the pipeline
+// attributes, to the output. It returns false if the context was cancelled while waiting for the output.
+func (p *nativePipeline) emit(ctx context.Context, message string, attributes map[string]interface{}) bool {
+	if len(p.cfg.Masker) > 0 {
+		message = p.cfg.Masker.mask(message)
+		p.cfg.Masker.maskAttributes(attributes)
+	}
+	p.metrics.observe(p.cfg, message)
+	if p.cfg.MetricsOnly {
+		return ctx.Err() == nil
+	}
+	if p.cfg.Pattern != nil && !p.cfg.Pattern.MatchString(message) {
+		return true
+	}
+	if attributes == nil {
+		attributes = make(map[string]interface{}, len(p.cfg.Attributes))
+	}
+	for key, value := range p.cfg.Attributes {
+		attributes[key] = value
+	}
+
+	select {
+	case p.out <- record{
+		Timestamp:  time.Now().UnixNano() / int64(time.Millisecond),
+		Message:    message,
+		Attributes: attributes,
+	}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
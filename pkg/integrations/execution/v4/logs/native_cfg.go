@@ -0,0 +1,164 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+// Native forwarder default values.
+const (
+	nativePositionsDbName = "native-positions.json"
+	nativeInputTypeTail   = "tail" // same input names as Fluent Bit, so records can be queried the same way
+	nativeInputTypeTcp    = "tcp"
+)
+
+// NativeCfg is the configuration of the native log forwarder, built from the LogCfg blocks with the native forwarder.
+type NativeCfg struct {
+	Pipelines []NativePipelineCfg
+	// CommonAttributes are added to all the log records.
+	CommonAttributes map[string]string
+	Endpoint         string
+	License          string
+	// PositionsFile stores how far the files and journals have been read, to resume after restarts.
+	PositionsFile string
+}
+
+// NativePipelineCfg is the validated configuration of a LogCfg block processed by the native forwarder.
+type NativePipelineCfg struct {
+	LogCfg
+	// InputType identifies the input in the "fb.input" attribute of the records.
+	InputType string
+	// Pattern filters the records whose message doesn't match it. Nil forwards all the records.
+	Pattern *regexp.Regexp
+	// Attributes are added to the records of the block.
+	Attributes map[string]string
+	// MaxLineBytes skips longer lines.
+	MaxLineBytes int
+	// Metrics are computed from all the records read by the input, before they are filtered.
+	Metrics []logMetric
+	// MetricsOnly pipelines only compute the metrics of logging configs whose records are forwarded by Fluent Bit.
+	MetricsOnly bool
+	// Masker masks the records, before the metrics are computed. Empty doesn't mask them.
+	Masker logMasker
+}
+
+// NewNativeConf creates the native forwarder config from the logging configs using the native forwarder, along with
+// the logging configs forwarded by Fluent Bit defining metrics, which are read by the agent to compute them.
+func NewNativeConf(loggingCfgs LogsCfg, logFwdCfg *config.LogForward, entityGUID, hostname string) (NativeCfg, error) {
+	nc := NativeCfg{
+		CommonAttributes: map[string]string{
+			rAttEntityGUID: entityGUID,
+			rAttPluginType: logRecordModifierSource,
+			rAttHostname:   hostname,
+		},
+		Endpoint:      logsEndpoint(logFwdCfg),
+		License:       logFwdCfg.License,
+		PositionsFile: filepath.Join(logFwdCfg.HomeDir, nativePositionsDbName),
+	}
+
+	for _, block := range loggingCfgs {
+		metricsOnly := !block.IsNative()
+		if metricsOnly && len(block.Metrics) == 0 {
+			continue
+		}
+		pipeline, err := newNativePipelineCfg(block, metricsOnly)
+		if err != nil {
+			return NativeCfg{}, err
+		}
+		nc.Pipelines = append(nc.Pipelines, pipeline)
+	}
+	return nc, nil
+}
+
+func newNativePipelineCfg(l LogCfg, metricsOnly bool) (NativePipelineCfg, error) {
+	p := NativePipelineCfg{
+		LogCfg:       l,
+		MaxLineBytes: getBufferMaxSize(l) * 1024,
+		Attributes:   map[string]string{},
+		MetricsOnly:  metricsOnly,
+	}
+
+	masker, err := newLogMasker(l.Mask)
+	if err != nil {
+		return NativePipelineCfg{}, fmt.Errorf("%s: %s", l.Name, err)
+	}
+	p.Masker = masker
+
+	for _, m := range l.Metrics {
+		metric, err := newLogMetric(m)
+		if err != nil {
+			return NativePipelineCfg{}, fmt.Errorf("%s: %s", l.Name, err)
+		}
+		p.Metrics = append(p.Metrics, metric)
+	}
+
+	// the rest of the options are applied by Fluent Bit, which listens on the network inputs itself
+	if metricsOnly {
+		if l.File == "" && l.Systemd == "" {
+			return NativePipelineCfg{}, fmt.Errorf("%s: metrics are only supported for file and systemd inputs, unless using the native forwarder", l.Name)
+		}
+	} else if l.Multiline != nil {
+		return NativePipelineCfg{}, fmt.Errorf("%s: multiline records aren't supported by the native log forwarder", l.Name)
+	} else if l.Parse != nil {
+		return NativePipelineCfg{}, fmt.Errorf("%s: the parse option isn't supported by the native log forwarder", l.Name)
+	}
+
+	switch {
+	case l.File != "":
+		p.InputType = nativeInputTypeTail
+	case l.Systemd != "":
+		p.InputType = fbInputTypeSystemd
+	case l.Syslog != nil:
+		if _, err := newSyslogInput(*l.Syslog, l.Name, 0); err != nil {
+			return NativePipelineCfg{}, err
+		}
+		if parser := getSyslogParser(l.Syslog.Parser); parser != "rfc3164" && parser != "rfc5424" {
+			return NativePipelineCfg{}, fmt.Errorf("syslog: unsupported parser %s for the native forwarder, expected rfc3164 or rfc5424", parser)
+		}
+		p.InputType = fbInputTypeSyslog
+	case l.Tcp != nil:
+		if _, err := newTcpInput(*l.Tcp, l.Name, 0); err != nil {
+			return NativePipelineCfg{}, err
+		}
+		if l.Tcp.Format != "none" && l.Tcp.Format != "json" {
+			return NativePipelineCfg{}, fmt.Errorf("tcp: unsupported format %s, expected none or json", l.Tcp.Format)
+		}
+		p.InputType = nativeInputTypeTcp
+	default:
+		return NativePipelineCfg{}, fmt.Errorf("%s: the native log forwarder only supports file, systemd, syslog and tcp inputs", l.Name)
+	}
+
+	// as Fluent Bit, the records received in JSON format aren't filtered
+	if !metricsOnly && l.Pattern != "" && (l.Tcp == nil || l.Tcp.Format == "none") {
+		pattern, err := regexp.Compile(l.Pattern)
+		if err != nil {
+			return NativePipelineCfg{}, fmt.Errorf("%s: invalid pattern: %s", l.Name, err)
+		}
+		p.Pattern = pattern
+	}
+
+	for key, value := range l.Attributes {
+		if isReserved(key) {
+			cfgLogger.WithField("attribute", key).Warn("attribute name is a reserved keyword and will be ignored, please use a different name")
+			continue
+		}
+		p.Attributes[key] = value
+	}
+	p.Attributes[rAttFbInput] = p.InputType
+	return p, nil
+}
+
+// tcpSeparator returns the separator of the records received by the tcp input with format "none".
+func tcpSeparator(t LogTcpCfg) string {
+	if t.Separator == "" {
+		return "\n"
+	}
+	replacer := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\\`, `\`)
+	return replacer.Replace(t.Separator)
+}
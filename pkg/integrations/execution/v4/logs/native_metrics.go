@@ -0,0 +1,202 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/fwrequest"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/dm"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/protocol"
+)
+
+const (
+	// logMetricsInterval is how often the log-derived metrics are aggregated and sent.
+	logMetricsInterval        = 30 * time.Second
+	logMetricsIntegrationName = "com.newrelic.log-metrics"
+	// rAttLogName is the metrics attribute holding the name of the logging config.
+	rAttLogName = "logName"
+	// logMetricMaxSeries bounds the series, by distinct attributes, aggregated for each metric until they are sent.
+	logMetricMaxSeries = 1000
+)
+
+// metricsAggregator aggregates the metrics computed from the log records of all the pipelines until they are sent.
+type metricsAggregator struct {
+	lock   sync.Mutex
+	series map[string]*metricSeries
+	since  time.Time
+	// seriesCount is the number of series of each metric, and dropped the number of observations discarded for
+	// exceeding logMetricMaxSeries.
+	seriesCount map[*logMetric]int
+	dropped     map[*logMetric]int
+}
+
+// metricSeries is the aggregation of a metric for a set of attributes.
+type metricSeries struct {
+	metric     *logMetric
+	attributes map[string]interface{}
+	count      float64
+	sum        float64
+	min        float64
+	max        float64
+	last       float64
+}
+
+func newMetricsAggregator(now time.Time) *metricsAggregator {
+	return &metricsAggregator{
+		series:      map[string]*metricSeries{},
+		since:       now,
+		seriesCount: map[*logMetric]int{},
+		dropped:     map[*logMetric]int{},
+	}
+}
+
+// observe computes the metrics of the pipeline for a record. Records not matching a metric pattern, or whose value
+// isn't a number, are ignored by that metric. The records are observed once masked, so the attributes of the
+// metrics hold the same data as the forwarded records.
+func (a *metricsAggregator) observe(cfg NativePipelineCfg, message string) {
+	if a == nil || len(cfg.Metrics) == 0 {
+		return
+	}
+	for i := range cfg.Metrics {
+		m := &cfg.Metrics[i]
+		match := m.pattern.FindStringSubmatch(message)
+		if match == nil {
+			continue
+		}
+		value := 1.0
+		if m.metricType != logMetricTypeCount {
+			v, err := strconv.ParseFloat(match[m.valueGroup], 64)
+			if err != nil {
+				continue
+			}
+			value = v
+		}
+
+		attributes := map[string]interface{}{rAttLogName: cfg.Name}
+		for key, attr := range cfg.Attributes {
+			if !isReserved(key) {
+				attributes[key] = attr
+			}
+		}
+		for group, name := range m.dimensions {
+			if match[group] != "" {
+				attributes[name] = match[group]
+			}
+		}
+		a.add(m, attributes, value)
+	}
+}
+
+func (a *metricsAggregator) add(m *logMetric, attributes map[string]interface{}, value float64) {
+	key := seriesKey(m, attributes)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	s, ok := a.series[key]
+	if !ok {
+		if a.seriesCount[m] >= logMetricMaxSeries {
+			a.dropped[m]++
+			return
+		}
+		a.seriesCount[m]++
+		s = &metricSeries{metric: m, attributes: attributes, min: value, max: value}
+		a.series[key] = s
+	}
+	s.count++
+	s.sum += value
+	s.last = value
+	if value < s.min {
+		s.min = value
+	}
+	if value > s.max {
+		s.max = value
+	}
+}
+
+// seriesKey identifies the series of a metric by its name, type and attributes.
+func seriesKey(m *logMetric, attributes map[string]interface{}) string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(m.name)
+	b.WriteString("\x00" + m.metricType)
+	for _, key := range keys {
+		b.WriteString(fmt.Sprintf("\x00%s=%v", key, attributes[key]))
+	}
+	return b.String()
+}
+
+// flush returns the metrics aggregated since the previous flush, and starts a new aggregation.
+func (a *metricsAggregator) flush(now time.Time) []protocol.Metric {
+	a.lock.Lock()
+	series := a.series
+	dropped := a.dropped
+	interval := now.Sub(a.since).Milliseconds()
+	a.series = map[string]*metricSeries{}
+	a.seriesCount = map[*logMetric]int{}
+	a.dropped = map[*logMetric]int{}
+	a.since = now
+	a.lock.Unlock()
+
+	for m, count := range dropped {
+		nativeLogger.
+			WithField("metric", m.name).
+			WithField("maxSeries", logMetricMaxSeries).
+			WithField("dropped", count).
+			Warn("too many distinct attributes for a log metric, discarding the new ones. Reduce the named groups of its pattern")
+	}
+
+	timestamp := now.Unix()
+	metrics := make([]protocol.Metric, 0, len(series))
+	for _, s := range series {
+		var value interface{}
+		switch s.metric.metricType {
+		case logMetricTypeCount:
+			value = s.count
+		case logMetricTypeGauge:
+			value = s.last
+		case logMetricTypeSummary:
+			value = protocol.SummaryValue{Count: s.count, Min: s.min, Max: s.max, Sum: s.sum}
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+
+		metric := protocol.Metric{
+			Name:       s.metric.name,
+			Type:       protocol.MetricType(s.metric.metricType),
+			Timestamp:  &timestamp,
+			Attributes: s.attributes,
+			Value:      raw,
+		}
+		if metric.Type.HasInterval() {
+			metricInterval := interval
+			metric.Interval = &metricInterval
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+// sendLogMetrics sends the metrics as dimensional metrics of the agent entity.
+func sendLogMetrics(emitter dm.Emitter, metrics []protocol.Metric) {
+	if emitter == nil || len(metrics) == 0 {
+		return
+	}
+	data := protocol.NewData(logMetricsIntegrationName, "1", []protocol.Dataset{{Metrics: metrics}})
+	def := integration.Definition{Name: logMetricsIntegrationName, Interval: logMetricsInterval}
+	emitter.Send(fwrequest.NewFwRequest(def, nil, nil, data))
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogMetric_Invalid(t *testing.T) {
+	tests := map[string]LogMetricCfg{
+		"no name":        {Type: "count", Pattern: "ERROR"},
+		"no pattern":     {Name: "errors", Type: "count"},
+		"invalid regex":  {Name: "errors", Type: "count", Pattern: "("},
+		"unknown type":   {Name: "errors", Type: "rate", Pattern: "ERROR"},
+		"count value":    {Name: "errors", Type: "count", Pattern: "ERROR (\\d+)", Value: "code"},
+		"no value group": {Name: "latency", Type: "gauge", Pattern: "took \\d+ms"},
+		"unknown value":  {Name: "latency", Type: "summary", Pattern: "took (?<ms>\\d+)ms", Value: "duration"},
+	}
+	for name, m := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newLogMetric(m)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewNativeConf_Metrics(t *testing.T) {
+	nc, err := NewNativeConf(LogsCfg{
+		{Name: "fb-file", File: "/var/log/fb.log"},
+		{
+			Name:    "fb-metrics",
+			File:    "/var/log/app.log",
+			Pattern: "(",
+			Parse:   &LogParseCfg{Format: "json"},
+			Metrics: []LogMetricCfg{{Name: "app.errors", Type: "count", Pattern: "ERROR"}},
+		},
+	}, logFwdCfg, "guid", "host")
+	require.NoError(t, err)
+
+	// the records forwarded by Fluent Bit are only read to compute the metrics
+	require.Len(t, nc.Pipelines, 1)
+	assert.True(t, nc.Pipelines[0].MetricsOnly)
+	assert.Nil(t, nc.Pipelines[0].Pattern)
+	require.Len(t, nc.Pipelines[0].Metrics, 1)
+
+	_, err = NewNativeConf(LogsCfg{{
+		Name:    "fb-tcp",
+		Tcp:     &LogTcpCfg{Uri: "tcp://127.0.0.1:5170", Format: "none"},
+		Metrics: []LogMetricCfg{{Name: "app.errors", Type: "count", Pattern: "ERROR"}},
+	}}, logFwdCfg, "guid", "host")
+	assert.Error(t, err)
+}
+
+func TestMetricsAggregator(t *testing.T) {
+	cfg, err := newNativePipelineCfg(LogCfg{
+		Name:       "app",
+		File:       "/var/log/app.log",
+		Attributes: map[string]string{"service": "checkout"},
+		Metrics: []LogMetricCfg{
+			{Name: "app.errors", Type: "count", Pattern: `(?<level>ERROR|FATAL) `},
+			{Name: "app.queue", Type: "gauge", Pattern: `queue=(\d+)`},
+			{Name: "app.latency", Type: "summary", Pattern: `(?<endpoint>/\w+) took (?<ms>[\d.]+)ms`, Value: "ms"},
+		},
+	}, false)
+	require.NoError(t, err)
+
+	start := time.Unix(1600000000, 0)
+	a := newMetricsAggregator(start)
+	for _, line := range []string{
+		"ERROR /pay took 120ms queue=3",
+		"ERROR connection reset",
+		"FATAL out of memory",
+		"INFO /pay took 80.5ms queue=5",
+		"INFO /cart took 10ms",
+		"INFO queue=full",
+	} {
+		a.observe(cfg, line)
+	}
+
+	metrics := a.flush(start.Add(30 * time.Second))
+	sort.Slice(metrics, func(i, j int) bool {
+		return seriesKey(&logMetric{name: metrics[i].Name}, metrics[i].Attributes) <
+			seriesKey(&logMetric{name: metrics[j].Name}, metrics[j].Attributes)
+	})
+	require.Len(t, metrics, 5)
+
+	interval := int64(30000)
+	assertMetric := func(m protocol.Metric, name string, metricType protocol.MetricType, attributes map[string]interface{}, value interface{}) {
+		t.Helper()
+		attributes["logName"] = "app"
+		attributes["service"] = "checkout"
+		assert.Equal(t, name, m.Name)
+		assert.Equal(t, metricType, m.Type)
+		assert.Equal(t, attributes, m.Attributes)
+		assert.Equal(t, int64(1600000030), *m.Timestamp)
+		expected, err := json.Marshal(value)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(m.Value))
+		if metricType.HasInterval() {
+			assert.Equal(t, &interval, m.Interval)
+		} else {
+			assert.Nil(t, m.Interval)
+		}
+	}
+	assertMetric(metrics[0], "app.errors", protocol.MetricTypeCount, map[string]interface{}{"level": "ERROR"}, 2)
+	assertMetric(metrics[1], "app.errors", protocol.MetricTypeCount, map[string]interface{}{"level": "FATAL"}, 1)
+	assertMetric(metrics[2], "app.latency", protocol.MetricTypeSummary, map[string]interface{}{"endpoint": "/cart"},
+		protocol.SummaryValue{Count: 1, Min: 10, Max: 10, Sum: 10})
+	assertMetric(metrics[3], "app.latency", protocol.MetricTypeSummary, map[string]interface{}{"endpoint": "/pay"},
+		protocol.SummaryValue{Count: 2, Min: 80.5, Max: 120, Sum: 200.5})
+	assertMetric(metrics[4], "app.queue", protocol.MetricTypeGauge, map[string]interface{}{}, 5)
+
+	assert.Empty(t, a.flush(start.Add(time.Minute)), "series are reset once flushed")
+}
+
+func TestNativePipeline_MasksMetricsAttributes(t *testing.T) {
+	// GIVEN a native pipeline with masking, whose metric takes the client IP as attribute
+	p, _ := newTestPipeline(t, LogCfg{
+		Name:    "access",
+		File:    "/var/log/access.log",
+		Mask:    []LogMaskCfg{{Detector: "ip"}, {Detector: "email"}},
+		Metrics: []LogMetricCfg{{Name: "access.requests", Type: "count", Pattern: `^(?<client>\S+) (?<user>\S+) GET`}},
+	})
+	start := time.Unix(1600000000, 0)
+	p.metrics = newMetricsAggregator(start)
+
+	// WHEN the lines are emitted
+	require.True(t, p.emit(context.Background(), "10.0.0.1 jane@example.com GET /", nil))
+	require.True(t, p.emit(context.Background(), "10.0.0.2 john@example.com GET /", nil))
+
+	// THEN the attributes hold the masked data, as the forwarded records
+	metrics := p.metrics.flush(start.Add(30 * time.Second))
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"logName": "access", "client": "[MASKED]", "user": "[MASKED]"}, metrics[0].Attributes)
+	assert.JSONEq(t, "2", string(metrics[0].Value))
+}
+
+func TestMetricsAggregator_LimitsSeries(t *testing.T) {
+	// GIVEN a metric whose attribute takes a distinct value on every line
+	cfg, err := newNativePipelineCfg(LogCfg{
+		Name: "app",
+		File: "/var/log/app.log",
+		Metrics: []LogMetricCfg{
+			{Name: "app.requests", Type: "count", Pattern: `request (?<id>\d+)`},
+			{Name: "app.errors", Type: "count", Pattern: `ERROR`},
+		},
+	}, false)
+	require.NoError(t, err)
+
+	// WHEN more lines than the series limit are observed
+	start := time.Unix(1600000000, 0)
+	a := newMetricsAggregator(start)
+	for i := 0; i < logMetricMaxSeries+10; i++ {
+		a.observe(cfg, fmt.Sprintf("ERROR request %d", i))
+	}
+
+	// THEN the new series of the metric are discarded, without affecting the rest of metrics
+	metrics := a.flush(start.Add(30 * time.Second))
+	assert.Len(t, metrics, logMetricMaxSeries+1)
+
+	// AND the limit is reset once flushed
+	a.observe(cfg, "request 1")
+	assert.Len(t, a.flush(start.Add(time.Minute)), 1)
+}
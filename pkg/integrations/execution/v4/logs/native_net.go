@@ -0,0 +1,276 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Syslog formats parsed by the native forwarder, as defined in the Fluent Bit parsers.conf.
+var syslogParsers = map[string]*regexp.Regexp{
+	"rfc3164": regexp.MustCompile(`^<(?P<pri>[0-9]+)>(?P<time>[^ ]* {1,2}[^ ]* [^ ]*) (?P<host>[^ ]*) (?P<ident>[a-zA-Z0-9_/.\-]*)(?:\[(?P<pid>[0-9]+)\])?(?:[^:]*:)? *(?P<message>.*)$`),
+	"rfc5424": regexp.MustCompile(`^<(?P<pri>[0-9]{1,5})>1 (?P<time>[^ ]+) (?P<host>[^ ]+) (?P<ident>[^ ]+) (?P<pid>[-0-9]+) (?P<msgid>[^ ]+) (?P<extradata>(?:\[.*?\]|-)) (?P<message>.+)$`),
+}
+
+// Keys holding the message of the records received in JSON format.
+var jsonMessageKeys = []string{"message", "log"}
+
+// listenTcp forwards the records received through TCP connections, either separated by the configured separator or
+// as JSON objects.
+func (p *nativePipeline) listenTcp(ctx context.Context) error {
+	listener, err := net.Listen("tcp", strings.TrimPrefix(p.cfg.Tcp.Uri, "tcp://"))
+	if err != nil {
+		return err
+	}
+	if p.cfg.Tcp.Format == "json" {
+		return p.serveStream(ctx, listener, p.readJSONRecords)
+	}
+	separator := []byte(tcpSeparator(*p.cfg.Tcp))
+	return p.serveStream(ctx, listener, func(ctx context.Context, conn io.Reader) {
+		p.readRecords(ctx, conn, separator, func(line string) (string, map[string]interface{}) {
+			return line, nil
+		})
+	})
+}
+
+// listenSyslog forwards the syslog messages received through TCP connections, UDP datagrams or Unix sockets.
+func (p *nativePipeline) listenSyslog(ctx context.Context) error {
+	protocolPath := strings.SplitN(p.cfg.Syslog.URI, "://", 2)
+	protocol, address := protocolPath[0], protocolPath[1]
+	parser := syslogParsers[getSyslogParser(p.cfg.Syslog.Parser)]
+	parse := func(line string) (string, map[string]interface{}) {
+		return parseSyslog(parser, line)
+	}
+
+	switch protocol {
+	case "tcp", "unix_tcp":
+		network := "tcp"
+		if protocol == "unix_tcp" {
+			network = "unix"
+			_ = os.Remove(address)
+		}
+		listener, err := net.Listen(network, address)
+		if err != nil {
+			return err
+		}
+		if err := p.setSocketPermissions(protocol, address); err != nil {
+			_ = listener.Close()
+			return err
+		}
+		return p.serveStream(ctx, listener, func(ctx context.Context, conn io.Reader) {
+			p.readRecords(ctx, conn, []byte("\n"), parse)
+		})
+	default:
+		network := "udp"
+		if protocol == "unix_udp" {
+			network = "unixgram"
+			_ = os.Remove(address)
+			defer os.Remove(address)
+		}
+		conn, err := net.ListenPacket(network, address)
+		if err != nil {
+			return err
+		}
+		if err := p.setSocketPermissions(protocol, address); err != nil {
+			_ = conn.Close()
+			return err
+		}
+		return p.serveDatagrams(ctx, conn, parse)
+	}
+}
+
+func (p *nativePipeline) setSocketPermissions(protocol, path string) error {
+	if !strings.HasPrefix(protocol, "unix_") || p.cfg.Syslog.UnixPermissions == "" {
+		return nil
+	}
+	mode, err := strconv.ParseUint(p.cfg.Syslog.UnixPermissions, 8, 32)
+	if err != nil {
+		return fmt.Errorf("syslog: invalid unix_permissions %s", p.cfg.Syslog.UnixPermissions)
+	}
+	return os.Chmod(path, os.FileMode(mode))
+}
+
+// serveStream handles the connections accepted by the listener until the context is cancelled.
+func (p *nativePipeline) serveStream(ctx context.Context, listener net.Listener, handle func(context.Context, io.Reader)) error {
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	conns := map[net.Conn]struct{}{}
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		}
+		_ = listener.Close()
+		lock.Lock()
+		for conn := range conns {
+			_ = conn.Close()
+		}
+		lock.Unlock()
+	}()
+	defer func() {
+		close(stopped)
+		wg.Wait()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		lock.Lock()
+		conns[conn] = struct{}{}
+		lock.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handle(ctx, conn)
+			_ = conn.Close()
+			lock.Lock()
+			delete(conns, conn)
+			lock.Unlock()
+		}()
+	}
+}
+
+// serveDatagrams forwards a record per received datagram until the context is cancelled.
+func (p *nativePipeline) serveDatagrams(ctx context.Context, conn net.PacketConn, parse func(string) (string, map[string]interface{})) error {
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		}
+		_ = conn.Close()
+	}()
+
+	buf := make([]byte, p.cfg.MaxLineBytes)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		message, attributes := parse(strings.TrimRight(string(buf[:n]), "\r\n"))
+		if !p.emit(ctx, message, attributes) {
+			return nil
+		}
+	}
+}
+
+// readRecords forwards the records of a stream separated by the separator. The connection is closed if a record is
+// longer than the maximum line length.
+func (p *nativePipeline) readRecords(ctx context.Context, conn io.Reader, separator []byte, parse func(string) (string, map[string]interface{})) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), p.cfg.MaxLineBytes)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, separator); i >= 0 {
+			return i + len(separator), data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		message, attributes := parse(line)
+		if !p.emit(ctx, message, attributes) {
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		p.log.WithError(err).Debug("Closing log records connection.")
+	}
+}
+
+// readJSONRecords forwards the JSON objects of a stream, promoting their fields to attributes. The connection is
+// closed if an object is longer than the maximum line length.
+func (p *nativePipeline) readJSONRecords(ctx context.Context, conn io.Reader) {
+	reader := &recordLimitReader{reader: conn}
+	decoder := json.NewDecoder(reader)
+	for {
+		reader.limit = decoder.InputOffset() + int64(p.cfg.MaxLineBytes)
+		var fields map[string]interface{}
+		if err := decoder.Decode(&fields); err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				p.log.WithError(err).Debug("Closing JSON log records connection.")
+			}
+			return
+		}
+		var message string
+		for _, key := range jsonMessageKeys {
+			if m, ok := fields[key].(string); ok {
+				message = m
+				delete(fields, key)
+				break
+			}
+		}
+		if !p.emit(ctx, message, fields) {
+			return
+		}
+	}
+}
+
+// recordLimitReader stops reading a stream past the limit, which is set to the maximum length of a record from the
+// end of the last one, so the records can't exceed the maximum length.
+type recordLimitReader struct {
+	reader io.Reader
+	read   int64
+	limit  int64
+}
+
+func (r *recordLimitReader) Read(b []byte) (int, error) {
+	remaining := r.limit - r.read
+	if remaining <= 0 {
+		return 0, bufio.ErrTooLong
+	}
+	if int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+	n, err := r.reader.Read(b)
+	r.read += int64(n)
+	return n, err
+}
+
+// parseSyslog extracts the fields of a syslog message as attributes. Messages not matching the format are forwarded
+// as they are.
+func parseSyslog(parser *regexp.Regexp, line string) (message string, attributes map[string]interface{}) {
+	match := parser.FindStringSubmatch(line)
+	if match == nil {
+		return line, nil
+	}
+	attributes = map[string]interface{}{}
+	for i, name := range parser.SubexpNames() {
+		if name == "" || match[i] == "" {
+			continue
+		}
+		if name == "message" {
+			message = match[i]
+		} else {
+			attributes[name] = match[i]
+		}
+	}
+	return message, attributes
+}
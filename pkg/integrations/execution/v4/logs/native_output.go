@@ -0,0 +1,187 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+)
+
+const (
+	// batches are sent when they reach any of the limits, or after the flush interval
+	nativeMaxBatchRecords = 1000
+	nativeMaxBatchBytes   = 512 * 1024 // uncompressed, as the Log API limits payloads to 1MB compressed
+	nativeFlushInterval   = 5 * time.Second
+	// failed batches are retried with exponential backoff before dropping them
+	nativeSendAttempts   = 5
+	nativeSendRetryDelay = time.Second
+	// nativeFlushTimeout bounds the time to send the pending records once the forwarder is stopped
+	nativeFlushTimeout = 5 * time.Second
+)
+
+// nativeOutput sends batches of log records to the Log API.
+type nativeOutput struct {
+	client     backendhttp.Client
+	endpoint   string
+	license    string
+	userAgent  string
+	common     map[string]interface{}
+	retryDelay time.Duration
+}
+
+// logsPayload is the Log API detailed JSON payload.
+type logsPayload struct {
+	Common struct {
+		Attributes map[string]interface{} `json:"attributes"`
+	} `json:"common"`
+	Logs []record `json:"logs"`
+}
+
+func newNativeOutput(cfg NativeCfg, client backendhttp.Client, userAgent string) *nativeOutput {
+	common := make(map[string]interface{}, len(cfg.CommonAttributes))
+	for key, value := range cfg.CommonAttributes {
+		common[key] = value
+	}
+	return &nativeOutput{
+		client:     client,
+		endpoint:   cfg.Endpoint,
+		license:    cfg.License,
+		userAgent:  userAgent,
+		common:     common,
+		retryDelay: nativeSendRetryDelay,
+	}
+}
+
+// run batches the records and sends them until the context is cancelled, flushing the pending ones before returning.
+func (o *nativeOutput) run(ctx context.Context, records <-chan record) {
+	var batch []record
+	batchBytes := 0
+	ticker := time.NewTicker(nativeFlushInterval)
+	defer ticker.Stop()
+
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := o.send(ctx, batch); err != nil {
+			nativeLogger.WithError(err).WithField("records", len(batch)).Warn("can't send log records, dropping them")
+		}
+		batch = nil
+		batchBytes = 0
+	}
+
+	for {
+		select {
+		case r := <-records:
+			batch = append(batch, r)
+			batchBytes += len(r.Message)
+			if len(batch) >= nativeMaxBatchRecords || batchBytes >= nativeMaxBatchBytes {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			// the records already queued are sent too, as their position may have been saved
+			for pending := true; pending; {
+				select {
+				case r := <-records:
+					batch = append(batch, r)
+				default:
+					pending = false
+				}
+			}
+			flushCtx, cancel := context.WithTimeout(context.Background(), nativeFlushTimeout)
+			for len(batch) > 0 {
+				chunk := batch
+				if len(chunk) > nativeMaxBatchRecords {
+					chunk = chunk[:nativeMaxBatchRecords]
+				}
+				batch = batch[len(chunk):]
+				if err := o.send(flushCtx, chunk); err != nil {
+					nativeLogger.WithError(err).WithField("records", len(chunk)).Warn("can't send log records, dropping them")
+				}
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// send posts a batch of records, retrying the failed requests.
+func (o *nativeOutput) send(ctx context.Context, batch []record) error {
+	payload, err := o.compress(batch)
+	if err != nil {
+		return err
+	}
+
+	delay := o.retryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := o.post(ctx, payload)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == nativeSendAttempts {
+			return err
+		}
+		nativeLogger.WithError(err).WithField("attempt", attempt).Debug("Log records not sent, retrying.")
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// compress returns the gzipped Log API payload of the batch.
+func (o *nativeOutput) compress(batch []record) ([]byte, error) {
+	payload := []logsPayload{{Logs: batch}}
+	payload[0].Common.Attributes = o.common
+
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if err := json.NewEncoder(zw).Encode(payload); err != nil {
+		return nil, fmt.Errorf("can't encode log records: %s", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("can't compress log records: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends the payload, returning whether the request should be retried if it failed.
+func (o *nativeOutput) post(ctx context.Context, payload []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, o.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-License-Key", o.license)
+	req.Header.Set("User-Agent", o.userAgent)
+
+	resp, err := o.client(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("log API responded with status %d", resp.StatusCode)
+	// client errors other than throttling won't succeed when retried
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout
+	return retry, err
+}
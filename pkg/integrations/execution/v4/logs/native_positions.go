@@ -0,0 +1,130 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// filePosition is how far a tailed file has been read.
+type filePosition struct {
+	Offset int64 `json:"offset"`
+	// Fingerprint is the digest of the first bytes of the file, to detect whether it was rotated while the forwarder
+	// wasn't running.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// positions is the position DB of the native forwarder, storing how far the files and journals have been read by
+// each pipeline, so pipelines reading the same file or journal don't share their positions.
+type positions struct {
+	path      string
+	lock      sync.Mutex
+	Pipelines map[string]*pipelinePositions `json:"pipelines"` // by logging config name
+	dirty     bool
+}
+
+// pipelinePositions are the positions of the files and journals read by a pipeline.
+type pipelinePositions struct {
+	Files   map[string]filePosition `json:"files"`
+	Cursors map[string]string       `json:"cursors"` // journal cursor per systemd unit
+}
+
+// loadPositions reads the position DB from the file, if it exists.
+func loadPositions(path string) *positions {
+	p := &positions{
+		path:      path,
+		Pipelines: map[string]*pipelinePositions{},
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			cfgLogger.WithError(err).WithField("file", path).Warn("can't read log positions, forwarding logs from their end")
+		}
+		return p
+	}
+	if err := json.Unmarshal(content, p); err != nil {
+		cfgLogger.WithError(err).WithField("file", path).Warn("invalid log positions file, forwarding logs from their end")
+	}
+	if p.Pipelines == nil {
+		p.Pipelines = map[string]*pipelinePositions{}
+	}
+	return p
+}
+
+// pipeline returns the positions of the pipeline, creating them if needed. The lock must be held.
+func (p *positions) pipeline(name string) *pipelinePositions {
+	pp, ok := p.Pipelines[name]
+	if !ok || pp == nil {
+		pp = &pipelinePositions{}
+		p.Pipelines[name] = pp
+	}
+	if pp.Files == nil {
+		pp.Files = map[string]filePosition{}
+	}
+	if pp.Cursors == nil {
+		pp.Cursors = map[string]string{}
+	}
+	return pp
+}
+
+func (p *positions) file(pipeline, path string) (pos filePosition, ok bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	pos, ok = p.pipeline(pipeline).Files[path]
+	return
+}
+
+func (p *positions) setFile(pipeline, path string, pos filePosition) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	files := p.pipeline(pipeline).Files
+	if files[path] != pos {
+		files[path] = pos
+		p.dirty = true
+	}
+}
+
+func (p *positions) cursor(pipeline, unit string) string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.pipeline(pipeline).Cursors[unit]
+}
+
+func (p *positions) setCursor(pipeline, unit, cursor string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	cursors := p.pipeline(pipeline).Cursors
+	if cursors[unit] != cursor {
+		cursors[unit] = cursor
+		p.dirty = true
+	}
+}
+
+// save writes the position DB to its file if it changed since the last save.
+func (p *positions) save() error {
+	p.lock.Lock()
+	if !p.dirty {
+		p.lock.Unlock()
+		return nil
+	}
+	content, err := json.Marshal(p)
+	p.dirty = false
+	p.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// written into a temporary file first, so a crash doesn't leave it half written
+	tmp := p.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	journalctlCommand = "journalctl"
+	// journal fields with special meaning
+	journalMessageField = "MESSAGE"
+	journalCursorField  = "__CURSOR"
+)
+
+// readJournal forwards the journal entries of the systemd unit, by following the JSON output of journalctl. It
+// resumes from the last forwarded entry, or from the end of the journal if the unit wasn't read before.
+func (p *nativePipeline) readJournal(ctx context.Context) error {
+	unit := p.cfg.Systemd + ".service"
+	args := []string{"--follow", "--output=json", "--unit=" + unit}
+	if cursor := p.positions.cursor(p.cfg.Name, unit); cursor != "" {
+		args = append(args, "--after-cursor="+cursor)
+	} else {
+		args = append(args, "--lines=0")
+	}
+
+	cmdCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(cmdCtx, journalctlCommand, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("can't read systemd journal: %s", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), p.cfg.MaxLineBytes+64*1024)
+	for scanner.Scan() {
+		message, attributes, cursor, ok := parseJournalEntry(scanner.Bytes())
+		if !ok {
+			continue
+		}
+		if !p.emit(ctx, message, attributes) {
+			break
+		}
+		p.positions.setCursor(p.cfg.Name, unit, cursor)
+	}
+	// journalctl is stopped, as it could be blocked writing an entry that wasn't read
+	cancel()
+	waitErr := cmd.Wait()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("journalctl exited: %v", waitErr)
+}
+
+// parseJournalEntry returns the message, the attributes and the cursor of a journal entry in JSON format. Binary
+// fields and the journal internal fields are ignored.
+func parseJournalEntry(entry []byte) (message string, attributes map[string]interface{}, cursor string, ok bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(entry, &fields); err != nil {
+		return "", nil, "", false
+	}
+	message, ok = fields[journalMessageField].(string)
+	if !ok {
+		return "", nil, "", false
+	}
+	cursor, _ = fields[journalCursorField].(string)
+
+	attributes = make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if key == journalMessageField || strings.HasPrefix(key, "__") {
+			continue
+		}
+		if s, isString := value.(string); isString {
+			attributes[key] = s
+		}
+	}
+	return message, attributes, cursor, true
+}
@@ -0,0 +1,282 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// nativeTailPollInterval is how often the tailed files are checked for new lines, rotations and truncations.
+	nativeTailPollInterval = time.Second
+	// nativeTailRefreshInterval is how often new files matching a path with wildcards are looked for.
+	nativeTailRefreshInterval = 60 * time.Second
+	// nativeTailRotateWait is how long rotated files are still read, for the lines written before they are released,
+	// as the Rotate_Wait option of the Fluent Bit "tail" input.
+	nativeTailRotateWait = 5 * time.Second
+	// fingerprintSize is the number of bytes at the beginning of a file identifying it across rotations.
+	fingerprintSize = 1024
+	tailReadSize    = 64 * 1024
+	// rAttFilePath is the attribute holding the path of the tailed file, as the Fluent Bit "tail" input does.
+	rAttFilePath = "filePath"
+)
+
+// tail forwards the lines appended to the files matching the path of the logging config, following them when they
+// are rotated or truncated. Files found when it starts are read from their saved position, or from their end if they
+// weren't read before, whereas files created later are read from their beginning. Rotated files are still read for
+// nativeTailRotateWait.
+func (p *nativePipeline) tail(ctx context.Context) error {
+	refreshInterval := nativeTailPollInterval
+	if strings.ContainsAny(p.cfg.File, "*?[") {
+		refreshInterval = nativeTailRefreshInterval
+	}
+
+	tailers := map[string]*fileTailer{}
+	var rotated []*fileTailer
+	defer func() {
+		for _, t := range tailers {
+			t.close()
+		}
+		for _, t := range rotated {
+			t.close()
+		}
+	}()
+
+	var lastRefresh time.Time
+	for firstScan := true; ; firstScan = false {
+		if time.Since(lastRefresh) >= refreshInterval {
+			matches, err := filepath.Glob(p.cfg.File)
+			if err != nil {
+				return err
+			}
+			for _, path := range matches {
+				if _, ok := tailers[path]; ok {
+					continue
+				}
+				t := &fileTailer{path: path, pipeline: p}
+				if err := t.open(true, !firstScan); err != nil {
+					p.log.WithError(err).WithField("file", path).Debug("Can't open log file.")
+					continue
+				}
+				tailers[path] = t
+			}
+			lastRefresh = time.Now()
+		}
+
+		for path, t := range tailers {
+			if !t.poll(ctx) {
+				return nil
+			}
+			if t.rotated() {
+				// the new file, if already created, is read from its beginning
+				rotated = append(rotated, t)
+				delete(tailers, path)
+				newTailer := &fileTailer{path: path, pipeline: p}
+				if err := newTailer.open(false, true); err == nil {
+					tailers[path] = newTailer
+				}
+			} else if t.file == nil {
+				delete(tailers, path)
+			}
+		}
+
+		for i := 0; i < len(rotated); {
+			t := rotated[i]
+			if !t.poll(ctx) {
+				return nil
+			}
+			if time.Now().Before(t.rotatedUntil) {
+				i++
+				continue
+			}
+			t.close()
+			rotated = append(rotated[:i], rotated[i+1:]...)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(nativeTailPollInterval):
+		}
+	}
+}
+
+// fileTailer reads the lines appended to a file.
+type fileTailer struct {
+	path     string
+	pipeline *nativePipeline
+	file     *os.File
+	info     os.FileInfo
+	// offset is the read position, whereas committed is the position after the last forwarded line.
+	offset    int64
+	committed int64
+	// head holds the first bytes of the file, to compute its fingerprint.
+	head []byte
+	// pending holds the last line until it is complete.
+	pending []byte
+	// skipping is set while discarding a line longer than the maximum length.
+	skipping bool
+	// rotatedUntil is set once the file is rotated, until when it is still read.
+	rotatedUntil time.Time
+}
+
+// open opens the file. If resume is set, it continues from the saved position, unless the file was rotated since it
+// was saved. Otherwise, the file is read from its beginning or its end.
+func (t *fileTailer) open(resume, fromStart bool) error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	t.file = file
+	t.info = info
+	t.pending = nil
+	t.skipping = false
+
+	t.offset = 0
+	if pos, ok := t.pipeline.positions.file(t.pipeline.cfg.Name, t.path); resume && ok && pos.Offset <= info.Size() {
+		t.readHead(pos.Offset)
+		if t.fingerprint() == pos.Fingerprint {
+			t.offset = pos.Offset
+		}
+	} else if !fromStart {
+		t.offset = info.Size()
+	}
+	t.readHead(t.offset)
+	t.committed = t.offset
+	_, err = file.Seek(t.offset, io.SeekStart)
+	return err
+}
+
+// readHead reads the first bytes of the file, up to the given offset.
+func (t *fileTailer) readHead(offset int64) {
+	size := offset
+	if size > fingerprintSize {
+		size = fingerprintSize
+	}
+	t.head = make([]byte, size)
+	n, _ := t.file.ReadAt(t.head, 0)
+	t.head = t.head[:n]
+}
+
+func (t *fileTailer) fingerprint() string {
+	sum := sha256.Sum256(t.head)
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *fileTailer) close() {
+	if t.file != nil {
+		_ = t.file.Close()
+		t.file = nil
+	}
+}
+
+// rotated returns whether the file was rotated, or removed, so it's no longer at its path.
+func (t *fileTailer) rotated() bool {
+	return !t.rotatedUntil.IsZero()
+}
+
+// poll forwards the new lines of the file and checks whether it was rotated or truncated. Once rotated, the file is
+// only read, as its path belongs to a new file, whose position is saved instead. It returns false if the context was
+// cancelled.
+func (t *fileTailer) poll(ctx context.Context) bool {
+	if !t.readLines(ctx) {
+		return false
+	}
+	if t.rotated() {
+		return ctx.Err() == nil
+	}
+
+	info, err := os.Stat(t.path)
+	switch {
+	case err != nil || !os.SameFile(t.info, info):
+		// removed or rotated, maybe without a new file yet: it will be found again when it's created
+		t.pipeline.log.WithField("file", t.path).Debug("Log file rotated.")
+		t.rotatedUntil = time.Now().Add(nativeTailRotateWait)
+		return ctx.Err() == nil
+	case info.Size() < t.offset:
+		t.pipeline.log.WithField("file", t.path).Debug("Log file truncated.")
+		t.offset, t.committed = 0, 0
+		t.head, t.pending, t.skipping = nil, nil, false
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			t.close()
+		}
+	}
+
+	t.pipeline.positions.setFile(t.pipeline.cfg.Name, t.path, filePosition{Offset: t.committed, Fingerprint: t.fingerprint()})
+	return ctx.Err() == nil
+}
+
+// readLines forwards the complete lines appended to the file. It returns false if the context was cancelled.
+func (t *fileTailer) readLines(ctx context.Context) bool {
+	buf := make([]byte, tailReadSize)
+	for {
+		n, err := t.file.Read(buf)
+		if n > 0 {
+			data := buf[:n]
+			if missing := fingerprintSize - len(t.head); missing > 0 && t.offset < fingerprintSize {
+				if missing > n {
+					missing = n
+				}
+				t.head = append(t.head, data[:missing]...)
+			}
+			t.offset += int64(n)
+			if !t.consume(ctx, data) {
+				return false
+			}
+		}
+		if err != nil || n == 0 {
+			return true
+		}
+	}
+}
+
+// consume splits the data in lines and forwards the complete ones, skipping the lines longer than the maximum.
+func (t *fileTailer) consume(ctx context.Context, data []byte) bool {
+	maxLine := t.pipeline.cfg.MaxLineBytes
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			if !t.skipping {
+				t.pending = append(t.pending, data...)
+				if len(t.pending) > maxLine {
+					t.pipeline.log.WithField("file", t.path).Debug("Skipping line longer than max_line_kb.")
+					t.pending, t.skipping = nil, true
+				}
+			}
+			return true
+		}
+
+		line := data[:i]
+		data = data[i+1:]
+		lineSize := int64(len(t.pending) + i + 1)
+		if t.skipping {
+			t.skipping = false
+			t.committed = t.offset - int64(len(data))
+			continue
+		}
+		if len(t.pending) > 0 {
+			line = append(t.pending, line...)
+			t.pending = nil
+		}
+		if len(line) > maxLine {
+			t.pipeline.log.WithField("file", t.path).Debug("Skipping line longer than max_line_kb.")
+		} else if !t.pipeline.emit(ctx, string(bytes.TrimSuffix(line, []byte("\r"))), map[string]interface{}{rAttFilePath: t.path}) {
+			return false
+		}
+		t.committed += lineSize
+	}
+	return true
+}
@@ -0,0 +1,483 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/id"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/hostname"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNativeConf(t *testing.T) {
+	cfgs := LogsCfg{
+		{Name: "fb-file", File: "/var/log/fb.log"},
+		{
+			Name:       "native-file",
+			File:       "/var/log/native.log",
+			Forwarder:  ForwarderNative,
+			Pattern:    "WARN|ERROR",
+			Attributes: map[string]string{"application": "tomcat", rAttHostname: "reserved"},
+		},
+		{Name: "native-tcp", Tcp: &LogTcpCfg{Uri: "tcp://127.0.0.1:5170", Format: "json"}, Pattern: "ignored", Forwarder: ForwarderNative},
+	}
+
+	nc, err := NewNativeConf(cfgs, logFwdCfg, "guid", "host")
+	require.NoError(t, err)
+
+	assert.Equal(t, usEndpoint, nc.Endpoint)
+	assert.Equal(t, "licenseKey", nc.License)
+	assert.Equal(t, filepath.Join(logFwdCfg.HomeDir, "native-positions.json"), nc.PositionsFile)
+	assert.Equal(t, map[string]string{"entity.guid.INFRA": "guid", "plugin.type": "nri-agent", "hostname": "host"}, nc.CommonAttributes)
+
+	require.Len(t, nc.Pipelines, 2)
+	file := nc.Pipelines[0]
+	assert.Equal(t, "native-file", file.Name)
+	assert.Equal(t, "tail", file.InputType)
+	assert.Equal(t, 128*1024, file.MaxLineBytes)
+	assert.True(t, file.Pattern.MatchString("an ERROR line"))
+	assert.Equal(t, map[string]string{"application": "tomcat", "fb.input": "tail"}, file.Attributes)
+	assert.Nil(t, nc.Pipelines[1].Pattern, "JSON records aren't filtered")
+}
+
+func TestNewNativeConf_Invalid(t *testing.T) {
+	tests := map[string]LogCfg{
+		"winlog":     {Name: "winlog", Winlog: &LogWinlogCfg{Channel: "Security"}},
+		"fluentbit":  {Name: "fb", Fluentbit: &LogExternalFBCfg{CfgPath: "/fb.conf"}},
+		"tcp uri":    {Name: "tcp", Tcp: &LogTcpCfg{Uri: "udp://127.0.0.1:5170", Format: "none"}},
+		"tcp format": {Name: "tcp", Tcp: &LogTcpCfg{Uri: "tcp://127.0.0.1:5170", Format: "msgpack"}},
+		"syslog":     {Name: "syslog", Syslog: &LogSyslogCfg{URI: "tcp://127.0.0.1:5140", Parser: "custom"}},
+		"pattern":    {Name: "file", File: "/var/log/a.log", Pattern: "("},
+		"multiline":  {Name: "file", File: "/var/log/a.log", Multiline: &LogMultilineCfg{Preset: "java"}},
+		"parse":      {Name: "file", File: "/var/log/a.log", Parse: &LogParseCfg{Format: "json"}},
+		"mask":       {Name: "file", File: "/var/log/a.log", Mask: []LogMaskCfg{{Regex: `(\d+`}}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg.Forwarder = ForwarderNative
+			_, err := NewNativeConf(LogsCfg{cfg}, logFwdCfg, "guid", "host")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewFBConf_SkipsNativeForwarder(t *testing.T) {
+	fb, err := NewFBConf(LogsCfg{
+		{Name: "native-file", File: "/var/log/native.log", Forwarder: ForwarderNative},
+	}, logFwdCfg, "guid", "host")
+	require.NoError(t, err)
+	assert.Empty(t, fb.Inputs)
+
+	_, _, _, _, err = parseConfigBlock(LogCfg{Name: "file", File: "/var/log/a.log", Forwarder: "unknown"}, "")
+	assert.Error(t, err)
+}
+
+// newTestPipeline returns a pipeline sending its records to the returned channel.
+func newTestPipeline(t *testing.T, l LogCfg) (*nativePipeline, chan record) {
+	l.Forwarder = ForwarderNative
+	cfg, err := newNativePipelineCfg(l, false)
+	require.NoError(t, err)
+	out := make(chan record, 100)
+	return &nativePipeline{
+		cfg:       cfg,
+		positions: loadPositions(filepath.Join(os.TempDir(), "non-existing", "positions.json")),
+		out:       out,
+		log:       nativeLogger,
+	}, out
+}
+
+func readMessages(out chan record) (messages []string) {
+	for {
+		select {
+		case r := <-out:
+			messages = append(messages, r.Message)
+		default:
+			return
+		}
+	}
+}
+
+func TestFileTailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "native-tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("previous line\n"), 0644))
+
+	p, out := newTestPipeline(t, LogCfg{Name: "app", File: path, Pattern: "^[a-z]", MaxLineKb: 1})
+	ctx := context.Background()
+
+	// GIVEN a file found when the forwarder starts
+	tailer := &fileTailer{path: path, pipeline: p}
+	require.NoError(t, tailer.open(true, false))
+	defer tailer.close()
+
+	// WHEN lines are appended
+	appendLines(t, path, "first line\nFILTERED line\nsecond ")
+	require.True(t, tailer.poll(ctx))
+
+	// THEN only the complete lines matching the pattern are forwarded, without the previous contents
+	assert.Equal(t, []string{"first line"}, readMessages(out))
+
+	// AND long lines are skipped
+	appendLines(t, path, "line\n"+string(make([]byte, 2048))+"\nthird line\r\n")
+	require.True(t, tailer.poll(ctx))
+	assert.Equal(t, []string{"second line", "third line"}, readMessages(out))
+
+	// AND the position is saved after the last forwarded line
+	pos, ok := p.positions.file("app", path)
+	require.True(t, ok)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), pos.Offset)
+
+	// WHEN the file is rotated
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, ioutil.WriteFile(path, []byte("rotated line\n"), 0644))
+	require.True(t, tailer.poll(ctx))
+	assert.True(t, tailer.rotated())
+
+	// THEN the lines written to the rotated file are still forwarded
+	appendLines(t, path+".1", "late line\n")
+	require.True(t, tailer.poll(ctx))
+	assert.Equal(t, []string{"late line"}, readMessages(out))
+
+	// AND the new file is read from the beginning
+	newTailer := &fileTailer{path: path, pipeline: p}
+	require.NoError(t, newTailer.open(false, true))
+	defer newTailer.close()
+	require.True(t, newTailer.poll(ctx))
+	assert.Equal(t, []string{"rotated line"}, readMessages(out))
+
+	// AND the position of the new file is saved
+	pos, ok = p.positions.file("app", path)
+	require.True(t, ok)
+	assert.Equal(t, int64(len("rotated line\n")), pos.Offset)
+
+	// WHEN the file is truncated
+	require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+	require.True(t, newTailer.poll(ctx))
+	appendLines(t, path, "truncated line\n")
+	require.True(t, newTailer.poll(ctx))
+
+	// THEN it is read from the beginning
+	assert.Equal(t, []string{"truncated line"}, readMessages(out))
+}
+
+func TestNativePipeline_TailDrainsRotatedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "native-tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+
+	// GIVEN a pipeline tailing a file from its beginning
+	p, out := newTestPipeline(t, LogCfg{Name: "app", File: path})
+	emptyHead := &fileTailer{}
+	p.positions.setFile("app", path, filePosition{Offset: 0, Fingerprint: emptyHead.fingerprint()})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = p.tail(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	received := func(expected ...string) func() bool {
+		var messages []string
+		return func() bool {
+			messages = append(messages, readMessages(out)...)
+			return assert.ObjectsAreEqual(expected, messages)
+		}
+	}
+	appendLines(t, path, "first line\n")
+	require.Eventually(t, received("first line"), 5*time.Second, 50*time.Millisecond)
+
+	// WHEN the file is rotated while its writer still appends lines to it
+	writer, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer writer.Close()
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, ioutil.WriteFile(path, []byte("new file line\n"), 0644))
+	require.Eventually(t, received("new file line"), 5*time.Second, 50*time.Millisecond)
+	_, err = writer.WriteString("late line\n")
+	require.NoError(t, err)
+
+	// THEN the lines written to the rotated file aren't lost
+	require.Eventually(t, received("late line"), 5*time.Second, 50*time.Millisecond)
+}
+
+func TestPositions_ByPipeline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "native-positions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	positionsFile := filepath.Join(dir, "positions.json")
+
+	// GIVEN two pipelines reading the same file and journal
+	pos := loadPositions(positionsFile)
+	pos.setFile("errors", "/var/log/app.log", filePosition{Offset: 10, Fingerprint: "a"})
+	pos.setFile("all", "/var/log/app.log", filePosition{Offset: 20, Fingerprint: "a"})
+	pos.setCursor("errors", "app.service", "cursor-1")
+	pos.setCursor("all", "app.service", "cursor-2")
+	require.NoError(t, pos.save())
+
+	// WHEN the positions are loaded again
+	pos = loadPositions(positionsFile)
+
+	// THEN each pipeline keeps its own positions
+	errorsPos, ok := pos.file("errors", "/var/log/app.log")
+	require.True(t, ok)
+	assert.Equal(t, int64(10), errorsPos.Offset)
+	allPos, ok := pos.file("all", "/var/log/app.log")
+	require.True(t, ok)
+	assert.Equal(t, int64(20), allPos.Offset)
+	assert.Equal(t, "cursor-1", pos.cursor("errors", "app.service"))
+	assert.Equal(t, "cursor-2", pos.cursor("all", "app.service"))
+	_, ok = pos.file("other", "/var/log/app.log")
+	assert.False(t, ok)
+}
+
+func TestFileTailer_ResumesFromSavedPosition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "native-tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("forwarded line\n"), 0644))
+
+	// GIVEN a file partially forwarded before the forwarder stopped
+	p, out := newTestPipeline(t, LogCfg{Name: "app", File: path})
+	tailer := &fileTailer{path: path, pipeline: p}
+	require.NoError(t, tailer.open(true, true))
+	require.True(t, tailer.poll(context.Background()))
+	tailer.close()
+	assert.Equal(t, []string{"forwarded line"}, readMessages(out))
+	p.positions.path = filepath.Join(dir, "positions.json")
+	require.NoError(t, p.positions.save())
+
+	// WHEN the forwarder starts again
+	appendLines(t, path, "pending line\n")
+	p.positions = loadPositions(filepath.Join(dir, "positions.json"))
+	tailer = &fileTailer{path: path, pipeline: p}
+	require.NoError(t, tailer.open(true, false))
+	defer tailer.close()
+	require.True(t, tailer.poll(context.Background()))
+
+	// THEN it forwards the lines written since the last saved position
+	assert.Equal(t, []string{"pending line"}, readMessages(out))
+}
+
+func appendLines(t *testing.T, path, lines string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(lines)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestNativePipeline_ListenTcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	// GIVEN a pipeline receiving JSON records through TCP
+	p, out := newTestPipeline(t, LogCfg{
+		Name:       "tcp",
+		Tcp:        &LogTcpCfg{Uri: "tcp://" + addr, Format: "json"},
+		Attributes: map[string]string{"application": "tomcat"},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.listenTcp(ctx) }()
+
+	// WHEN a record is sent
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", addr)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	_, err = conn.Write([]byte(`{"message":"hello","level":"info"}`))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// THEN it is forwarded with its fields as attributes
+	select {
+	case r := <-out:
+		assert.Equal(t, "hello", r.Message)
+		assert.Equal(t, map[string]interface{}{"level": "info", "application": "tomcat", "fb.input": "tcp"}, r.Attributes)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "record not received")
+	}
+
+	// AND the input stops with the context
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestNativePipeline_ReadJSONRecordsSkipsLongRecords(t *testing.T) {
+	// GIVEN a pipeline receiving JSON records with a maximum length
+	p, out := newTestPipeline(t, LogCfg{
+		Name: "tcp",
+		Tcp:  &LogTcpCfg{Uri: "tcp://127.0.0.1:0", Format: "json"},
+	})
+	p.cfg.MaxLineBytes = 64
+
+	// WHEN a stream with a record longer than the maximum is read
+	stream := `{"message":"first"} {"message":"second"}` + "\n" +
+		`{"message":"` + strings.Repeat("a", 100) + `"} {"message":"after"}`
+	p.readJSONRecords(context.Background(), strings.NewReader(stream))
+
+	// THEN the records before the long one are forwarded, and the connection is closed
+	assert.Equal(t, []string{"first", "second"}, readMessages(out))
+}
+
+func TestParseSyslog(t *testing.T) {
+	message, attributes := parseSyslog(syslogParsers["rfc3164"], "<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed")
+	assert.Equal(t, "'su root' failed", message)
+	assert.Equal(t, map[string]interface{}{"pri": "34", "time": "Oct 11 22:14:15", "host": "mymachine", "ident": "su", "pid": "123"}, attributes)
+
+	message, attributes = parseSyslog(syslogParsers["rfc5424"], `<165>1 2003-10-11T22:14:15.003Z mymachine evntslog - ID47 [exampleSDID@32473 iut="3"] An application event`)
+	assert.Equal(t, "An application event", message)
+	assert.Equal(t, "evntslog", attributes["ident"])
+	assert.Equal(t, "ID47", attributes["msgid"])
+
+	message, attributes = parseSyslog(syslogParsers["rfc3164"], "not a syslog message")
+	assert.Equal(t, "not a syslog message", message)
+	assert.Nil(t, attributes)
+}
+
+func TestParseJournalEntry(t *testing.T) {
+	message, attributes, cursor, ok := parseJournalEntry([]byte(`{"__CURSOR":"s=abc","MESSAGE":"Started agent","_SYSTEMD_UNIT":"newrelic-infra.service","_PID":"42","_BINARY":[1,2]}`))
+	require.True(t, ok)
+	assert.Equal(t, "Started agent", message)
+	assert.Equal(t, "s=abc", cursor)
+	assert.Equal(t, map[string]interface{}{"_SYSTEMD_UNIT": "newrelic-infra.service", "_PID": "42"}, attributes)
+
+	_, _, _, ok = parseJournalEntry([]byte(`{"MESSAGE":[104,105]}`))
+	assert.False(t, ok)
+}
+
+func TestNativeOutput_Send(t *testing.T) {
+	var requests int32
+	var received []logsPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// first request fails, to be retried
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "licenseKey", r.Header.Get("X-License-Key"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(zr).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	o := newNativeOutput(NativeCfg{
+		CommonAttributes: map[string]string{"hostname": "host"},
+		Endpoint:         server.URL,
+		License:          "licenseKey",
+	}, server.Client().Do, "agent")
+	o.retryDelay = time.Millisecond
+
+	err := o.send(context.Background(), []record{{Timestamp: 1, Message: "hello", Attributes: map[string]interface{}{"fb.input": "tail"}}})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+	require.Len(t, received, 1)
+	assert.Equal(t, map[string]interface{}{"hostname": "host"}, received[0].Common.Attributes)
+	require.Len(t, received[0].Logs, 1)
+	assert.Equal(t, "hello", received[0].Logs[0].Message)
+}
+
+func TestNativeOutput_SendDoesNotRetryClientErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	o := newNativeOutput(NativeCfg{Endpoint: server.URL}, server.Client().Do, "agent")
+	o.retryDelay = time.Millisecond
+
+	assert.Error(t, o.send(context.Background(), []record{{Message: "hello"}}))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+}
+
+type hostnameNotifierMock struct {
+	observers chan chan<- hostname.ChangeNotification
+}
+
+func (n *hostnameNotifierMock) AddObserver(_ string, ch chan<- hostname.ChangeNotification) {
+	n.observers <- ch
+}
+
+func (n *hostnameNotifierMock) RemoveObserver(_ string) {}
+
+func TestNativeForwarder_ReloadsOnIdentityChanges(t *testing.T) {
+	// GIVEN a native logging config
+	dir, err := ioutil.TempDir("", "native-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	addFile(t, dir, "logs.yml", "logs:\n  - name: native\n    file: "+filepath.Join(dir, "app.log")+"\n    forwarder: native\n")
+
+	// AND a native forwarder counting the loads of its config
+	var loads int32
+	countLoads := func() entity.Identity {
+		atomic.AddInt32(&loads, 1)
+		return idnProvide()
+	}
+	var agentIDChanges chan<- struct{}
+	notifier := &hostnameNotifierMock{observers: make(chan chan<- hostname.ChangeNotification, 1)}
+	f := &NativeForwarder{
+		cfgLoader:    NewFolderLoader(newTestConf(dir, disabledTroubleshootCfg), countLoads, hostnameProvider),
+		watchChanges: func(context.Context, chan<- struct{}) {},
+		listenAgentIDChanges: func(ch chan<- struct{}, _ id.AgentIDNotifyMode) {
+			agentIDChanges = ch
+		},
+		hostnameChangeNotifier: notifier,
+	}
+	expectLoads := func(n int32) {
+		require.Eventually(t, func() bool { return atomic.LoadInt32(&loads) == n }, 5*time.Second, 10*time.Millisecond)
+	}
+
+	// WHEN the forwarder runs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.Run(ctx)
+	hostnameChanges := <-notifier.observers
+	expectLoads(1)
+
+	// THEN it reloads its config when the short hostname changes
+	hostnameChanges <- hostname.ChangeNotification{What: hostname.Short}
+	expectLoads(2)
+
+	// AND when the agent ID changes
+	agentIDChanges <- struct{}{}
+	expectLoads(3)
+
+	// BUT not when only the full hostname changes
+	hostnameChanges <- hostname.ChangeNotification{What: hostname.Full}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&loads))
+}
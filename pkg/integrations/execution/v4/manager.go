@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"github.com/newrelic/infrastructure-agent/pkg/config/migrate"
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
+	v3 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v3"
 	config_v32 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v3/config"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/allowlist"
 	config2 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/config"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/fs"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/emitter"
@@ -25,6 +27,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/constants"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/files"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/runner"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/track"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
@@ -116,6 +119,8 @@ type Manager struct {
 	tracker                  *track.Tracker
 	idLookup                 host.IDLookup
 	pluginRegistry           *config_v32.PluginRegistry
+	monitor                  *monitor.Monitor
+	scheduler                *runner.Scheduler
 }
 
 // groupContext pairs a runner.Group with its cancellation context
@@ -170,6 +175,14 @@ type Configuration struct {
 	Verbose int
 	// PassthroughEnvironment holds a copy of its homonym in config.Config.
 	PassthroughEnvironment []string
+	// MaxConcurrentIntegrations holds a copy of its homonym in config.Config.
+	MaxConcurrentIntegrations int
+	// StartJitter spreads the first execution of the integrations across their interval.
+	StartJitter bool
+	// ValidatePayloads validates the protocol v4 payloads of the integrations, reporting the rejected items.
+	ValidatePayloads bool
+	// Verifier refuses to run the integrations whose files aren't in the integrations allow-list. Nil allows any.
+	Verifier *allowlist.Verifier
 }
 
 func NewConfig(verbose int, features map[string]bool, passthroughEnvs, configFolders, definitionFolders []string) Configuration {
@@ -182,6 +195,27 @@ func NewConfig(verbose int, features map[string]bool, passthroughEnvs, configFol
 	}
 }
 
+// NewInstancesLookup creates an instance lookup that:
+// - looks in the v3 legacy definitions repository for defined commands
+// - looks in the definition folders (and bin/ subfolders) for executable names
+func NewInstancesLookup(cfg Configuration) integration.InstancesLookup {
+	const executablesSubFolder = "bin"
+
+	var execFolders []string
+	for _, df := range cfg.DefinitionFolders {
+		execFolders = append(execFolders, df)
+		execFolders = append(execFolders, filepath.Join(df, executablesSubFolder))
+	}
+	legacyDefinedCommands := v3.NewDefinitionsRepo(v3.LegacyConfig{
+		DefinitionFolders: cfg.DefinitionFolders,
+		Verbose:           cfg.Verbose,
+	})
+	return integration.InstancesLookup{
+		Legacy: legacyDefinedCommands.NewDefinitionCommand,
+		ByName: files.Executables{Folders: execFolders}.Path,
+	}
+}
+
 // NewManager loads all the integration configuration files from the given folders. It discards the integrations
 // not belonging to the protocol V4.
 // Usually, "configFolders" will be the value of the "pluginInstanceDir" configuration option
@@ -196,12 +230,17 @@ func NewManager(
 	tracker *track.Tracker,
 	idLookup host.IDLookup,
 	pluginRegistry *config_v32.PluginRegistry,
+	monitor *monitor.Monitor,
 ) *Manager {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		illog.WithError(err).Warn("can't enable hot reload")
 	}
 
+	if cfg.ValidatePayloads {
+		emitter = runner.NewValidatingEmitter(emitter, monitor)
+	}
+
 	mgr := Manager{
 		config:                   cfg,
 		runners:                  newRunnerGroupsPerCfgPath(),
@@ -217,6 +256,8 @@ func NewManager(
 		tracker:                  tracker,
 		idLookup:                 idLookup,
 		pluginRegistry:           pluginRegistry,
+		monitor:                  monitor,
+		scheduler:                runner.NewScheduler(cfg.MaxConcurrentIntegrations, cfg.StartJitter),
 	}
 
 	// Loads all the configuration files in the passed configFolders
@@ -312,9 +353,13 @@ func (mgr *Manager) loadEnabledRunnerGroups(cfgs map[string]config2.YAML) {
 }
 
 func (mgr *Manager) loadRunnerGroup(path string, cfg config2.YAML, cmdFF *runner.CmdFF) (*groupContext, error) {
+	// no runners are built for configs outside the allow-list, as their discovery and conditions can run commands
+	if err := mgr.config.Verifier.Verify(path); err != nil {
+		return nil, err
+	}
 	f := runner.NewFeatures(mgr.config.AgentFeatures, cmdFF)
 	loader := runner.NewLoadFn(cfg, f)
-	gr, fc, err := runner.NewGroup(loader, mgr.lookup, mgr.config.PassthroughEnvironment, mgr.emitter, mgr.handleCmdReq, mgr.handleConfig, path, mgr.terminateDefinitionQueue, mgr.idLookup)
+	gr, fc, err := runner.NewGroup(loader, mgr.lookup, mgr.config.PassthroughEnvironment, mgr.emitter, mgr.handleCmdReq, mgr.handleConfig, path, mgr.terminateDefinitionQueue, mgr.idLookup, mgr.monitor, mgr.scheduler, mgr.config.Verifier)
 	if err != nil {
 		return nil, err
 	}
@@ -331,7 +376,7 @@ func (mgr *Manager) handleRequestsQueue(ctx context.Context) {
 			return
 
 		case def := <-mgr.definitionQueue:
-			r := runner.NewRunner(def, mgr.emitter, nil, nil, mgr.handleCmdReq, nil, mgr.terminateDefinitionQueue, mgr.idLookup)
+			r := runner.NewRunner(def, mgr.emitter, nil, nil, mgr.handleCmdReq, nil, mgr.terminateDefinitionQueue, mgr.idLookup, mgr.monitor, mgr.scheduler, mgr.config.Verifier)
 			if def.CmdChanReq != nil {
 				// tracking so cmd requests can be stopped by hash
 				runCtx, pidWCh := mgr.tracker.Track(ctx, def.CmdChanReq.CmdChannelCmdHash, &def)
@@ -346,7 +391,7 @@ func (mgr *Manager) handleRequestsQueue(ctx context.Context) {
 			}
 		case entry := <-mgr.configEntryQueue:
 			ds, _ := entry.Databind.DataSources()
-			r := runner.NewRunner(entry.Definition, mgr.emitter, ds, nil, nil, nil, mgr.terminateDefinitionQueue, mgr.idLookup)
+			r := runner.NewRunner(entry.Definition, mgr.emitter, ds, nil, nil, nil, mgr.terminateDefinitionQueue, mgr.idLookup, mgr.monitor, mgr.scheduler, mgr.config.Verifier)
 			runCtx, pidWCh := mgr.tracker.Track(ctx, entry.Definition.Hash(), &entry.Definition)
 			go r.Run(runCtx, pidWCh, nil)
 
@@ -521,7 +566,7 @@ func (mgr *Manager) configFilesIn(folder string) (map[string]config2.YAML, error
 	return configs, nil
 }
 
-func (mgr *Manager) loadConfigIntoBytes(path string) ([]byte, error) {
+func loadConfigIntoBytes(path string, pluginRegistry *config_v32.PluginRegistry) ([]byte, error) {
 	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		return bytes, nil
@@ -536,7 +581,10 @@ func (mgr *Manager) loadConfigIntoBytes(path string) ([]byte, error) {
 		}
 
 		// Reading old Definition file
-		v3Definition, err := mgr.pluginRegistry.GetPlugin(v3Configuration.IntegrationName)
+		if pluginRegistry == nil {
+			return nil, errors.New("v3 integrations config requires the integration definition files")
+		}
+		v3Definition, err := pluginRegistry.GetPlugin(v3Configuration.IntegrationName)
 		if err != nil {
 			return nil, fmt.Errorf("error reading old config configuration: %w", err)
 		}
@@ -554,7 +602,13 @@ func (mgr *Manager) loadConfigIntoBytes(path string) ([]byte, error) {
 }
 
 func (mgr *Manager) loadConfig(path string) (config2.YAML, error) {
-	bytes, err := mgr.loadConfigIntoBytes(path)
+	return LoadConfig(path, mgr.pluginRegistry)
+}
+
+// LoadConfig loads an integrations config file, expanding its environment variables. Configs in the v3 format are
+// migrated to v4, looking for their integration definitions in the plugin registry, which is optional otherwise.
+func LoadConfig(path string, pluginRegistry *config_v32.PluginRegistry) (config2.YAML, error) {
+	bytes, err := loadConfigIntoBytes(path, pluginRegistry)
 	cy := config2.YAML{}
 	if err != nil {
 		return cy, err
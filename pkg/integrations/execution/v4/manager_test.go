@@ -4,10 +4,15 @@ package v4
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
 	v3 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v3"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/allowlist"
 	config2 "github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v3/config"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/config"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/fixtures"
@@ -165,7 +170,7 @@ func TestManager_StartIntegrations(t *testing.T) {
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager loads and executes the integrations in the folder
 	ctx, cancel := context.WithCancel(context.Background())
@@ -201,7 +206,7 @@ integrations:
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager loads and executes the integration
 	ctx, cancel := context.WithCancel(context.Background())
@@ -227,7 +232,7 @@ integrations:
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager loads and executes the integration
 	ctx, cancel := context.WithCancel(context.Background())
@@ -263,7 +268,7 @@ func TestManager_ErrorLoadingV3IntegrationsIfNoDefinition(t *testing.T) {
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	_ = NewManager(Configuration{ConfigFolders: []string{dir}}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	_ = NewManager(Configuration{ConfigFolders: []string{dir}}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// THEN no log entries found
 	for i := range hook.AllEntries() {
@@ -289,7 +294,7 @@ func TestManager_LogWarningForInvalidYaml(t *testing.T) {
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	_ = NewManager(Configuration{ConfigFolders: []string{dir}}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	_ = NewManager(Configuration{ConfigFolders: []string{dir}}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// THEN one long entry found
 	require.NotEmpty(t, hook.AllEntries())
@@ -309,7 +314,7 @@ func TestManager_Config_EmbeddedYAML(t *testing.T) {
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager loads and executes the integrations in the folder
 	ctx, cancel := context.WithCancel(context.Background())
@@ -335,7 +340,7 @@ func TestManager_HotReload_Add(t *testing.T) {
 
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -370,7 +375,7 @@ func TestManager_HotReload_Modify(t *testing.T) {
 
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -418,7 +423,7 @@ func TestManager_HotReload_ModifyLinkFile(t *testing.T) {
 
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -468,7 +473,7 @@ func TestManager_HotReload_Delete(t *testing.T) {
 
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -519,7 +524,7 @@ integrations:
 		ConfigFolders:          []string{configDir},
 		DefinitionFolders:      []string{niDir},
 		PassthroughEnvironment: []string{"VALUE"},
-	}, emitter, instancesLookupReturning(execPath), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, emitter, instancesLookupReturning(execPath), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -557,7 +562,7 @@ integrations:
 		ConfigFolders:          []string{configDir},
 		DefinitionFolders:      []string{niDir},
 		PassthroughEnvironment: []string{"VALUE"},
-	}, emitter, instancesLookupReturning(execPath), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, emitter, instancesLookupReturning(execPath), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -602,7 +607,7 @@ integrations:
 	mgr := NewManager(Configuration{
 		ConfigFolders:     []string{configDir},
 		DefinitionFolders: []string{definitionsDir},
-	}, emitter, instancesLookupLegacy(definitionsDir), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, emitter, instancesLookupLegacy(definitionsDir), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -651,7 +656,7 @@ integrations:
 		ConfigFolders:          []string{configDir},
 		DefinitionFolders:      []string{definitionsDir},
 		PassthroughEnvironment: []string{"VALUE"},
-	}, emitter, instancesLookupLegacy(definitionsDir), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, emitter, instancesLookupLegacy(definitionsDir), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -695,7 +700,7 @@ integrations:
 	mgr := NewManager(Configuration{
 		ConfigFolders:     []string{configDir},
 		DefinitionFolders: []string{niDir, ciDir, "unexisting-dir"},
-	}, emitter, instancesLookupReturning(execPath1, execPath2), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, emitter, instancesLookupReturning(execPath1, execPath2), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -736,7 +741,7 @@ integrations:
 	mgr := NewManager(Configuration{
 		ConfigFolders:     []string{configDir},
 		DefinitionFolders: []string{niDir},
-	}, emitter, instancesLookupReturning(execPath), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, emitter, instancesLookupReturning(execPath), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -762,7 +767,7 @@ func TestManager_EnableFeature_WhenFeatureOnOHICfgAndAgentCfgIsDisabledAndEnable
 		ConfigFolders:          []string{dir},
 		PassthroughEnvironment: passthroughEnv,
 		//AgentFeatures: map[string]bool{"docker_enabled": false},
-	}, e, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, e, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// AND the manager loads and executes the integrations in the folder
 	ctx, cancel := context.WithCancel(context.Background())
@@ -795,7 +800,7 @@ func TestManager_EnableFeatureFromAgentConfig(t *testing.T) {
 		ConfigFolders:          []string{dir},
 		AgentFeatures:          map[string]bool{"docker_enabled": true},
 		PassthroughEnvironment: passthroughEnv,
-	}, e, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, e, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// AND the manager starts
 	ctx, cancel := context.WithCancel(context.Background())
@@ -823,7 +828,7 @@ func TestManager_CCDisablesAgentEnabledFeature(t *testing.T) {
 		ConfigFolders:          []string{dir},
 		AgentFeatures:          map[string]bool{"docker_enabled": true},
 		PassthroughEnvironment: passthroughEnv,
-	}, e, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, e, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// AND manager loads and executes the integrations in the folder
 	ctx, cancel := context.WithCancel(context.Background())
@@ -859,7 +864,7 @@ func TestManager_CCDisablesPreviouslyEnabledFeature(t *testing.T) {
 	mgr := NewManager(Configuration{
 		ConfigFolders:          []string{dir},
 		PassthroughEnvironment: passthroughEnv,
-	}, e, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, e, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// AND manager loads and executes the integrations in the folder
 	ctx, cancel := context.WithCancel(context.Background())
@@ -903,7 +908,7 @@ func TestManager_WhenFileExists(t *testing.T) {
 
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager loads and executes the integrations in the folder
 	ctx, cancel := context.WithCancel(context.Background())
@@ -926,7 +931,7 @@ func TestManager_WhenFileDoesNotExist(t *testing.T) {
 
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager loads and executes the integrations in the folder
 	ctx, cancel := context.WithCancel(context.Background())
@@ -952,7 +957,7 @@ func TestManager_StartWithVerbose(t *testing.T) {
 		ConfigFolders:          []string{dir},
 		PassthroughEnvironment: passthroughEnv,
 		Verbose:                1,
-	}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// AND the manager starts
 	ctx, cancel := context.WithCancel(context.Background())
@@ -985,7 +990,7 @@ func TestManager_StartWithVerboseFalse(t *testing.T) {
 		ConfigFolders:          []string{dir},
 		PassthroughEnvironment: passthroughEnv,
 		Verbose:                0,
-	}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// AND the manager starts
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1039,7 +1044,7 @@ func TestManager_anIntegrationCanSpawnAnotherOne(t *testing.T) {
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager executes the integration
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1062,7 +1067,7 @@ func TestManager_cfgProtocolSpawnIntegrationV3Payload(t *testing.T) {
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager executes the integration
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1085,7 +1090,7 @@ func TestManager_cfgProtocolSpawnIntegrationV4Payload(t *testing.T) {
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager executes the integration
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1111,7 +1116,7 @@ func TestManager_cfgProtocolSpawnedIntegrationCannotSpawnIntegration(t *testing.
 	// AND an integrations manager
 	emitter := &testemit.RecordEmitter{}
 	pluginRegistry := &config2.PluginRegistry{}
-	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 
 	// WHEN the manager executes the integration
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1162,7 +1167,7 @@ integrations:
 	mgr := NewManager(Configuration{
 		ConfigFolders:     []string{configDir},
 		DefinitionFolders: []string{niDir},
-	}, emitter, instancesLookupReturning(execPath), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry)
+	}, emitter, instancesLookupReturning(execPath), definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, pluginRegistry, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go mgr.Start(ctx)
@@ -1256,3 +1261,32 @@ func instancesLookupLegacy(definitionFolders ...string) integration.InstancesLoo
 		},
 	}
 }
+
+func TestManager_RefusesConfigsOutsideTheAllowList(t *testing.T) {
+	// GIVEN a configuration file
+	dir, err := tempFiles(map[string]string{
+		"v4-integrations.yaml": v4File,
+	})
+	require.NoError(t, err)
+	defer removeTempFiles(t, dir)
+
+	// AND a signed allow-list that doesn't contain it
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherDigest := sha256.Sum256([]byte("other"))
+	list := []byte(hex.EncodeToString(otherDigest[:]) + "  /etc/other.yml\n")
+	listPath := filepath.Join(dir, "allow-list.sha256")
+	require.NoError(t, ioutil.WriteFile(listPath, list, 0644))
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, list))
+	require.NoError(t, ioutil.WriteFile(listPath+allowlist.SignatureExtension, []byte(signature), 0644))
+	verifier, err := allowlist.Load(listPath, publicKey)
+	require.NoError(t, err)
+
+	// WHEN the manager loads the integrations in the folder
+	emitter := &testemit.RecordEmitter{}
+	mgr := NewManager(Configuration{ConfigFolders: []string{dir}, PassthroughEnvironment: passthroughEnv, Verifier: verifier}, emitter, integration.ErrLookup, definitionQ, terminateDefinitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{}, &config2.PluginRegistry{}, nil)
+
+	// THEN no runners are built for the configuration file
+	_, ok := mgr.runners.Get(filepath.Join(dir, "v4-integrations.yaml"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,446 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+// Package monitor keeps track of the execution status of the v4 integrations, which is exposed through the status
+// API, and reports the execution issues to New Relic as agent events.
+package monitor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/executor"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// maxPayloadErrors bounds the validation errors kept and reported for each invalid payload.
+const maxPayloadErrors = 10
+
+// SendEventFn wrapper for sending events to nr.
+type SendEventFn func(event sample.Event, entityKey entity.Key)
+
+// Executions counts the integration runs waiting for an execution slot and the ones being executed.
+type Executions struct {
+	Running int `json:"running"`
+	Queued  int `json:"queued"`
+}
+
+// Integration identifies an integration definition tracked by the Monitor.
+type Integration struct {
+	Name string
+	// ConfigPath is the configuration file the definition was loaded from, empty if it wasn't loaded from a file.
+	ConfigPath string
+	Hash       string
+}
+
+// IntegrationStatus is the execution status of an integration definition.
+type IntegrationStatus struct {
+	Name                string          `json:"name"`
+	ConfigPath          string          `json:"config_path,omitempty"`
+	Hash                string          `json:"hash"`
+	LastStart           *time.Time      `json:"last_start,omitempty"`
+	LastEnd             *time.Time      `json:"last_end,omitempty"`
+	LastExitCode        *int            `json:"last_exit_code,omitempty"`
+	LastDurationMs      int64           `json:"last_duration_ms"`
+	ConsecutiveFailures int             `json:"consecutive_failures"`
+	LastError           string          `json:"last_error,omitempty"`
+	LastStderr          []string        `json:"last_stderr,omitempty"`
+	NextRun             *time.Time      `json:"next_run,omitempty"`
+	Restarts            uint64          `json:"restarts,omitempty"` // daemon integrations only
+	LastRestart         *time.Time      `json:"last_restart,omitempty"`
+	VerificationError   string          `json:"verification_error,omitempty"` // set while refused by the allow-list
+	Resources           *ResourceStatus `json:"resources,omitempty"`
+	Payloads            *PayloadStatus  `json:"payloads,omitempty"`
+}
+
+// ResourceStatus accumulates how the resource limits affected the executions of an integration.
+type ResourceStatus struct {
+	OOMKills         uint64     `json:"oom_kills"`
+	LastOOMKill      *time.Time `json:"last_oom_kill,omitempty"`
+	ThrottledPeriods uint64     `json:"throttled_periods"`
+	ThrottledTimeMs  int64      `json:"throttled_time_ms"`
+}
+
+// PayloadStatus accumulates the payload items of an integration rejected by the protocol validation.
+type PayloadStatus struct {
+	RejectedItems uint64     `json:"rejected_items"`
+	LastRejection *time.Time `json:"last_rejection,omitempty"`
+	// LastErrors holds the first validation errors of the last invalid payload.
+	LastErrors []string `json:"last_errors,omitempty"`
+}
+
+// ResourceEvent is the InfrastructureEvent reported when an integration is OOM killed or throttled for exceeding
+// its resource limits.
+type ResourceEvent struct {
+	sample.BaseEvent
+	Summary          string `json:"summary"`
+	IntegrationName  string `json:"integrationName"`
+	OOMKills         uint64 `json:"oomKills"`
+	ThrottledPeriods uint64 `json:"throttledPeriods"`
+	ThrottledTimeMs  int64  `json:"throttledTimeMs"`
+}
+
+// Failure describes a failed integration run.
+type Failure struct {
+	// Attempts is the number of executions of the run, including the retries.
+	Attempts int
+	// ExitCode is the exit status of the integration, or -1 if it didn't exit with an error status.
+	ExitCode int
+	Error    string
+	// Stderr holds the last standard error lines of the run.
+	Stderr []string
+}
+
+// FailureEvent is the IntegrationFailure event reported when an integration run fails after all its retries.
+type FailureEvent struct {
+	sample.BaseEvent
+	IntegrationName string `json:"integrationName"`
+	Attempts        int    `json:"attempts"`
+	ExitCode        int    `json:"exitCode"`
+	Error           string `json:"error"`
+	Stderr          string `json:"stderr"`
+}
+
+// PayloadErrorEvent is the IntegrationPayloadError event reported when an integration payload doesn't conform to
+// the integrations protocol.
+type PayloadErrorEvent struct {
+	sample.BaseEvent
+	IntegrationName string `json:"integrationName"`
+	RejectedItems   int    `json:"rejectedItems"`
+	Errors          string `json:"errors"`
+}
+
+// VerificationEvent is the IntegrationVerificationFailure event reported when an integration is refused because its
+// executable or configuration file isn't in the integrations allow-list.
+type VerificationEvent struct {
+	sample.BaseEvent
+	IntegrationName string `json:"integrationName"`
+	File            string `json:"file"`
+	Error           string `json:"error"`
+}
+
+// Monitor keeps the execution status of the integrations. A nil Monitor discards all the reports.
+type Monitor struct {
+	lock         sync.RWMutex
+	sendEvent    SendEventFn
+	integrations map[Integration]*IntegrationStatus
+	executions   Executions
+	now          func() time.Time
+}
+
+// New creates a Monitor that reports the integration execution issues through the sendEvent function.
+func New(sendEvent SendEventFn) *Monitor {
+	return &Monitor{
+		sendEvent:    sendEvent,
+		integrations: map[Integration]*IntegrationStatus{},
+		now:          time.Now,
+	}
+}
+
+// Track starts tracking the status of an integration definition.
+func (m *Monitor) Track(integration Integration) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.status(integration)
+}
+
+// Untrack stops tracking the status of an integration definition, once it is no longer executed.
+func (m *Monitor) Untrack(integration Integration) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.integrations, integration)
+}
+
+// RunScheduled records the time of the next run of an integration.
+func (m *Monitor) RunScheduled(integration Integration, next time.Time) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.status(integration).NextRun = &next
+}
+
+// RunRestarted records the restart of the process of a daemon integration.
+func (m *Monitor) RunRestarted(integration Integration) {
+	if m == nil {
+		return
+	}
+	now := m.now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	status := m.status(integration)
+	status.Restarts++
+	status.LastRestart = &now
+}
+
+// RunQueued records an integration run waiting for an execution slot.
+func (m *Monitor) RunQueued(integration Integration) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.executions.Queued++
+}
+
+// RunCancelled records a queued integration run that was cancelled before being executed.
+func (m *Monitor) RunCancelled(integration Integration) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.executions.Queued--
+}
+
+// RunStarted records the execution of a queued integration run.
+func (m *Monitor) RunStarted(integration Integration) {
+	if m == nil {
+		return
+	}
+	now := m.now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.executions.Queued--
+	m.executions.Running++
+
+	status := m.status(integration)
+	status.LastStart = &now
+	status.NextRun = nil
+}
+
+// RunFinished records the end of an integration run, along with the last standard error lines of the integration.
+// The failure is nil if the run succeeded.
+func (m *Monitor) RunFinished(integration Integration, stderr []string, failure *Failure) {
+	if m == nil {
+		return
+	}
+	now := m.now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.executions.Running--
+
+	status := m.status(integration)
+	status.LastEnd = &now
+	if status.LastStart != nil {
+		status.LastDurationMs = now.Sub(*status.LastStart).Milliseconds()
+	}
+	status.LastStderr = stderr
+	exitCode := 0
+	if failure == nil {
+		status.ConsecutiveFailures = 0
+		status.LastError = ""
+	} else {
+		exitCode = failure.ExitCode
+		status.ConsecutiveFailures++
+		status.LastError = failure.Error
+	}
+	status.LastExitCode = &exitCode
+}
+
+// Executions returns the number of queued and running integration runs.
+func (m *Monitor) Executions() Executions {
+	if m == nil {
+		return Executions{}
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.executions
+}
+
+// ReportResourceUsage records the resource usage of an integration execution, sending an event if it was OOM
+// killed or throttled.
+func (m *Monitor) ReportResourceUsage(integration Integration, usage executor.ResourceUsage) {
+	if m == nil {
+		return
+	}
+	now := m.now()
+
+	m.lock.Lock()
+	resources := m.status(integration).resources()
+	resources.OOMKills += usage.OOMKills
+	if usage.OOMKills > 0 {
+		resources.LastOOMKill = &now
+	}
+	resources.ThrottledPeriods += usage.ThrottledPeriods
+	resources.ThrottledTimeMs += usage.ThrottledTime.Milliseconds()
+	m.lock.Unlock()
+
+	var summary string
+	switch {
+	case usage.OOMKills > 0:
+		summary = "Integration OOM killed for exceeding its memory limit"
+	case usage.ThrottledPeriods > 0:
+		summary = "Integration throttled for exceeding its CPU limit"
+	default:
+		return
+	}
+	if m.sendEvent != nil {
+		m.sendEvent(&ResourceEvent{
+			BaseEvent: sample.BaseEvent{
+				EventType: "InfrastructureEvent",
+				Timestmp:  now.Unix(),
+			},
+			Summary:          summary,
+			IntegrationName:  integration.Name,
+			OOMKills:         usage.OOMKills,
+			ThrottledPeriods: usage.ThrottledPeriods,
+			ThrottledTimeMs:  usage.ThrottledTime.Milliseconds(),
+		}, entity.EmptyKey)
+	}
+}
+
+// ReportFailure sends an IntegrationFailure event for a run that failed after all its retries.
+func (m *Monitor) ReportFailure(integration Integration, failure Failure) {
+	if m == nil || m.sendEvent == nil {
+		return
+	}
+	m.sendEvent(&FailureEvent{
+		BaseEvent: sample.BaseEvent{
+			EventType: "IntegrationFailure",
+			Timestmp:  m.now().Unix(),
+		},
+		IntegrationName: integration.Name,
+		Attempts:        failure.Attempts,
+		ExitCode:        failure.ExitCode,
+		Error:           failure.Error,
+		Stderr:          strings.Join(failure.Stderr, "\n"),
+	}, entity.EmptyKey)
+}
+
+// ReportPayloadErrors records the validation errors of an integration payload, one per rejected item, sending an
+// IntegrationPayloadError event.
+func (m *Monitor) ReportPayloadErrors(integration Integration, errs []error) {
+	if m == nil || len(errs) == 0 {
+		return
+	}
+	now := m.now()
+	firstErrs := make([]string, 0, maxPayloadErrors)
+	for i := 0; i < len(errs) && i < maxPayloadErrors; i++ {
+		firstErrs = append(firstErrs, errs[i].Error())
+	}
+
+	m.lock.Lock()
+	payloads := m.status(integration).payloads()
+	payloads.RejectedItems += uint64(len(errs))
+	payloads.LastRejection = &now
+	payloads.LastErrors = firstErrs
+	m.lock.Unlock()
+
+	if m.sendEvent != nil {
+		m.sendEvent(&PayloadErrorEvent{
+			BaseEvent: sample.BaseEvent{
+				EventType: "IntegrationPayloadError",
+				Timestmp:  now.Unix(),
+			},
+			IntegrationName: integration.Name,
+			RejectedItems:   len(errs),
+			Errors:          strings.Join(firstErrs, "\n"),
+		}, entity.EmptyKey)
+	}
+}
+
+// ReportVerification records the result of verifying the files of an integration against the allow-list, sending an
+// IntegrationVerificationFailure event if the verification of the file failed. A nil error clears the failure.
+func (m *Monitor) ReportVerification(integration Integration, file string, err error) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	status := m.status(integration)
+	if err == nil {
+		status.VerificationError = ""
+		m.lock.Unlock()
+		return
+	}
+	status.VerificationError = err.Error()
+	m.lock.Unlock()
+
+	if m.sendEvent != nil {
+		m.sendEvent(&VerificationEvent{
+			BaseEvent: sample.BaseEvent{
+				EventType: "IntegrationVerificationFailure",
+				Timestmp:  m.now().Unix(),
+			},
+			IntegrationName: integration.Name,
+			File:            file,
+			Error:           err.Error(),
+		}, entity.EmptyKey)
+	}
+}
+
+// Status returns the execution status of all the tracked integrations, sorted by name and configuration path.
+func (m *Monitor) Status() []IntegrationStatus {
+	if m == nil {
+		return nil
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	statuses := make([]IntegrationStatus, 0, len(m.integrations))
+	for _, status := range m.integrations {
+		statuses = append(statuses, status.copy())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Name != statuses[j].Name {
+			return statuses[i].Name < statuses[j].Name
+		}
+		if statuses[i].ConfigPath != statuses[j].ConfigPath {
+			return statuses[i].ConfigPath < statuses[j].ConfigPath
+		}
+		return statuses[i].Hash < statuses[j].Hash
+	})
+	return statuses
+}
+
+// status returns the status of the integration, creating it if it doesn't exist. Requires holding the lock.
+func (m *Monitor) status(integration Integration) *IntegrationStatus {
+	status, ok := m.integrations[integration]
+	if !ok {
+		status = &IntegrationStatus{
+			Name:       integration.Name,
+			ConfigPath: integration.ConfigPath,
+			Hash:       integration.Hash,
+		}
+		m.integrations[integration] = status
+	}
+	return status
+}
+
+func (s *IntegrationStatus) resources() *ResourceStatus {
+	if s.Resources == nil {
+		s.Resources = &ResourceStatus{}
+	}
+	return s.Resources
+}
+
+func (s *IntegrationStatus) payloads() *PayloadStatus {
+	if s.Payloads == nil {
+		s.Payloads = &PayloadStatus{}
+	}
+	return s.Payloads
+}
+
+// copy returns a copy of the status that doesn't share any mutable data with the original. The time and exit code
+// pointers and the stderr and payload error lines are replaced, never modified, so they can be shared.
+func (s *IntegrationStatus) copy() IntegrationStatus {
+	c := *s
+	if s.Resources != nil {
+		resources := *s.Resources
+		c.Resources = &resources
+	}
+	if s.Payloads != nil {
+		payloads := *s.Payloads
+		c.Payloads = &payloads
+	}
+	return c
+}
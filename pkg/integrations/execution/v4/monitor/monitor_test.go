@@ -0,0 +1,232 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/executor"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	mysql = Integration{Name: "nri-mysql", ConfigPath: "/etc/newrelic-infra/integrations.d/mysql.yml", Hash: "1a2b"}
+	redis = Integration{Name: "nri-redis", ConfigPath: "/etc/newrelic-infra/integrations.d/redis.yml", Hash: "3c4d"}
+	nginx = Integration{Name: "nri-nginx", ConfigPath: "/etc/newrelic-infra/integrations.d/nginx.yml", Hash: "5e6f"}
+)
+
+func TestMonitor_ReportResourceUsage(t *testing.T) {
+	var events []sample.Event
+	m := New(func(event sample.Event, _ entity.Key) {
+		events = append(events, event)
+	})
+	now := time.Unix(1600000000, 0)
+	m.now = func() time.Time { return now }
+
+	m.ReportResourceUsage(redis, executor.ResourceUsage{})
+	m.ReportResourceUsage(mysql, executor.ResourceUsage{OOMKills: 1})
+	m.ReportResourceUsage(mysql, executor.ResourceUsage{ThrottledPeriods: 3, ThrottledTime: 20 * time.Millisecond})
+
+	status := m.Status()
+	require.Len(t, status, 2)
+	assert.Equal(t, "nri-mysql", status[0].Name)
+	assert.Equal(t, &ResourceStatus{
+		OOMKills:         1,
+		LastOOMKill:      &now,
+		ThrottledPeriods: 3,
+		ThrottledTimeMs:  20,
+	}, status[0].Resources)
+	assert.Equal(t, "nri-redis", status[1].Name)
+	assert.Equal(t, &ResourceStatus{}, status[1].Resources)
+	assert.Equal(t, redis.ConfigPath, status[1].ConfigPath)
+	assert.Equal(t, redis.Hash, status[1].Hash)
+
+	require.Len(t, events, 2)
+	oomEvent := events[0].(*ResourceEvent)
+	assert.Equal(t, "InfrastructureEvent", oomEvent.EventType)
+	assert.Equal(t, "Integration OOM killed for exceeding its memory limit", oomEvent.Summary)
+	assert.Equal(t, "nri-mysql", oomEvent.IntegrationName)
+	assert.Equal(t, uint64(1), oomEvent.OOMKills)
+	throttledEvent := events[1].(*ResourceEvent)
+	assert.Equal(t, "Integration throttled for exceeding its CPU limit", throttledEvent.Summary)
+	assert.Equal(t, int64(20), throttledEvent.ThrottledTimeMs)
+}
+
+func TestMonitor_Nil(t *testing.T) {
+	var m *Monitor
+
+	assert.NotPanics(t, func() {
+		m.ReportResourceUsage(mysql, executor.ResourceUsage{OOMKills: 1})
+	})
+	assert.Empty(t, m.Status())
+	assert.NotPanics(t, func() {
+		m.RunQueued(mysql)
+		m.RunStarted(mysql)
+		m.RunFinished(mysql, nil, nil)
+	})
+	assert.Equal(t, Executions{}, m.Executions())
+}
+
+func TestMonitor_Executions(t *testing.T) {
+	m := New(nil)
+
+	m.RunQueued(mysql)
+	m.RunQueued(redis)
+	m.RunQueued(nginx)
+	assert.Equal(t, Executions{Queued: 3}, m.Executions())
+
+	m.RunStarted(mysql)
+	m.RunStarted(redis)
+	assert.Equal(t, Executions{Running: 2, Queued: 1}, m.Executions())
+
+	m.RunFinished(mysql, nil, nil)
+	m.RunCancelled(nginx)
+	assert.Equal(t, Executions{Running: 1}, m.Executions())
+}
+
+func TestMonitor_ReportFailure(t *testing.T) {
+	var events []sample.Event
+	m := New(func(event sample.Event, _ entity.Key) {
+		events = append(events, event)
+	})
+
+	m.ReportFailure(mysql, Failure{Attempts: 3, ExitCode: 1, Error: "exit status 1", Stderr: []string{"can't connect", "bye"}})
+
+	require.Len(t, events, 1)
+	event := events[0].(*FailureEvent)
+	assert.Equal(t, "IntegrationFailure", event.EventType)
+	assert.Equal(t, "nri-mysql", event.IntegrationName)
+	assert.Equal(t, 3, event.Attempts)
+	assert.Equal(t, 1, event.ExitCode)
+	assert.Equal(t, "exit status 1", event.Error)
+	assert.Equal(t, "can't connect\nbye", event.Stderr)
+}
+
+func TestMonitor_RunHistory(t *testing.T) {
+	m := New(nil)
+	now := time.Unix(1600000000, 0)
+	m.now = func() time.Time { return now }
+
+	m.Track(mysql)
+	m.Track(redis)
+
+	// GIVEN a successful run
+	m.RunQueued(mysql)
+	m.RunStarted(mysql)
+	now = now.Add(1500 * time.Millisecond)
+	m.RunFinished(mysql, []string{"starting"}, nil)
+	m.RunScheduled(mysql, now.Add(30*time.Second))
+
+	// AND two failed runs
+	for i := 0; i < 2; i++ {
+		now = now.Add(30 * time.Second)
+		m.RunQueued(mysql)
+		m.RunStarted(mysql)
+		now = now.Add(200 * time.Millisecond)
+		m.RunFinished(mysql, []string{"can't connect"}, &Failure{ExitCode: 2, Error: "exit status 2"})
+	}
+
+	// THEN the status of both tracked integrations is reported
+	status := m.Status()
+	require.Len(t, status, 2)
+	mysqlStatus := status[0]
+	assert.Equal(t, mysql.Name, mysqlStatus.Name)
+	assert.Equal(t, mysql.ConfigPath, mysqlStatus.ConfigPath)
+	assert.Equal(t, mysql.Hash, mysqlStatus.Hash)
+	assert.Equal(t, now.Add(-200*time.Millisecond), *mysqlStatus.LastStart)
+	assert.Equal(t, now, *mysqlStatus.LastEnd)
+	assert.Equal(t, 2, *mysqlStatus.LastExitCode)
+	assert.Equal(t, int64(200), mysqlStatus.LastDurationMs)
+	assert.Equal(t, 2, mysqlStatus.ConsecutiveFailures)
+	assert.Equal(t, "exit status 2", mysqlStatus.LastError)
+	assert.Equal(t, []string{"can't connect"}, mysqlStatus.LastStderr)
+	assert.Nil(t, mysqlStatus.NextRun)
+
+	assert.Equal(t, IntegrationStatus{Name: redis.Name, ConfigPath: redis.ConfigPath, Hash: redis.Hash}, status[1])
+
+	// AND a successful run resets the failures
+	m.RunQueued(mysql)
+	m.RunStarted(mysql)
+	m.RunFinished(mysql, nil, nil)
+	next := now.Add(time.Minute)
+	m.RunScheduled(mysql, next)
+	mysqlStatus = m.Status()[0]
+	assert.Equal(t, 0, *mysqlStatus.LastExitCode)
+	assert.Zero(t, mysqlStatus.ConsecutiveFailures)
+	assert.Empty(t, mysqlStatus.LastError)
+	assert.Equal(t, next, *mysqlStatus.NextRun)
+
+	// AND untracked integrations aren't reported anymore
+	m.Untrack(mysql)
+	status = m.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, redis.Name, status[0].Name)
+}
+
+func TestMonitor_ReportPayloadErrors(t *testing.T) {
+	var events []sample.Event
+	m := New(func(event sample.Event, _ entity.Key) {
+		events = append(events, event)
+	})
+	now := time.Unix(1600000000, 0)
+	m.now = func() time.Time { return now }
+
+	// GIVEN a payload with a rejected item
+	m.ReportPayloadErrors(mysql, []error{errors.New(`data[0].metrics[0]: metric "a": missing required 'type' field`)})
+
+	// AND a payload with more rejected items than the reported errors
+	var errs []error
+	for i := 0; i < 15; i++ {
+		errs = append(errs, fmt.Errorf("data[0].events[%d]: missing required 'summary' field", i))
+	}
+	m.ReportPayloadErrors(mysql, errs)
+
+	// THEN all the rejected items are counted, keeping only the first errors of the last payload
+	status := m.Status()
+	require.Len(t, status, 1)
+	require.NotNil(t, status[0].Payloads)
+	assert.Equal(t, uint64(16), status[0].Payloads.RejectedItems)
+	assert.Equal(t, now, *status[0].Payloads.LastRejection)
+	require.Len(t, status[0].Payloads.LastErrors, maxPayloadErrors)
+	assert.Equal(t, "data[0].events[0]: missing required 'summary' field", status[0].Payloads.LastErrors[0])
+
+	// AND an event is reported for each invalid payload
+	require.Len(t, events, 2)
+	event := events[1].(*PayloadErrorEvent)
+	assert.Equal(t, "IntegrationPayloadError", event.EventType)
+	assert.Equal(t, "nri-mysql", event.IntegrationName)
+	assert.Equal(t, 15, event.RejectedItems)
+	assert.Contains(t, event.Errors, "data[0].events[9]: missing required 'summary' field")
+	assert.NotContains(t, event.Errors, "data[0].events[10]")
+}
+
+func TestMonitor_ReportVerification(t *testing.T) {
+	var events []sample.Event
+	m := New(func(event sample.Event, _ entity.Key) {
+		events = append(events, event)
+	})
+
+	// GIVEN an integration refused by the allow-list
+	m.ReportVerification(mysql, "/usr/bin/nri-mysql", errors.New("/usr/bin/nri-mysql isn't in the integrations allow-list"))
+
+	// THEN the failure is reported in its status and as an event
+	status := m.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, "/usr/bin/nri-mysql isn't in the integrations allow-list", status[0].VerificationError)
+	require.Len(t, events, 1)
+	event := events[0].(*VerificationEvent)
+	assert.Equal(t, "IntegrationVerificationFailure", event.EventType)
+	assert.Equal(t, "nri-mysql", event.IntegrationName)
+	assert.Equal(t, "/usr/bin/nri-mysql", event.File)
+
+	// AND a successful verification clears the failure without sending events
+	m.ReportVerification(mysql, "/usr/bin/nri-mysql", nil)
+	assert.Empty(t, m.Status()[0].VerificationError)
+	assert.Len(t, events, 1)
+}
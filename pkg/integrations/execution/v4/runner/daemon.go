@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"context"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
+)
+
+// supervise keeps the process of a daemon integration running while it streams its payloads. The process is
+// restarted with exponential backoff whenever it exits or, if a timeout is set, it doesn't send any heartbeat or
+// payload within the timeout. A process running for longer than the maximum backoff resets the backoff.
+// It returns when the context is cancelled, when the "when" conditions of the integration are no longer met, when the
+// integration files are no longer in the allow-list, or after the restart attempts of the retry policy, if limited.
+// It returns false only if it stopped because the conditions are no longer met.
+func (r *runner) supervise(ctx context.Context, matches *databind.Values, pidWCh chan<- int) bool {
+	policy := r.definition.Retry
+	bo := &backoff.Backoff{
+		Factor: backoff.DefaultFactor,
+		Jitter: backoff.DefaultJitter,
+		Min:    policy.InitialBackoff,
+		Max:    policy.MaxBackoff,
+	}
+	for attempt := 0; ; attempt++ {
+		started := time.Now()
+		failure := r.execute(ctx, matches, pidWCh, nil)
+		if ctx.Err() != nil {
+			return true
+		}
+		if !r.evaluateConditions() {
+			return false
+		}
+		if r.verificationErr != "" {
+			return true
+		}
+		if time.Since(started) > policy.MaxBackoff {
+			attempt = 0
+		}
+
+		llog := r.log
+		if failure != nil {
+			llog = llog.WithField("error", failure.Error)
+		}
+		if policy.MaxAttempts > 0 && attempt == policy.MaxAttempts {
+			if failure == nil {
+				failure = &monitor.Failure{Error: "integration process exited"}
+			}
+			failure.Attempts = attempt + 1
+			llog.WithField("restarts", attempt).Warn("daemon integration stopped after all its restarts")
+			r.monitor.ReportFailure(r.integration, *failure)
+			return true
+		}
+
+		wait := bo.ForAttempt(float64(attempt))
+		llog.WithField("backoff", wait).Warn("daemon integration process finished, restarting it")
+		r.monitor.RunScheduled(r.integration, time.Now().Add(wait))
+		select {
+		case <-ctx.Done():
+			return true
+		case <-time.After(wait):
+		}
+		r.monitor.RunRestarted(r.integration)
+	}
+}
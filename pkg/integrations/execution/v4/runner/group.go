@@ -10,7 +10,9 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/cmdrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/allowlist"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
 )
 
 // Group represents a set of runnable integrations that are located in
@@ -31,6 +33,9 @@ type Group struct {
 	configHandle         configrequest.HandleFn
 	terminateDefinitionQ chan string
 	idLookup             host.IDLookup
+	monitor              *monitor.Monitor
+	scheduler            *Scheduler
+	verifier             *allowlist.Verifier
 }
 
 type runnerErrorHandler func(ctx context.Context, errs <-chan error)
@@ -47,6 +52,9 @@ func NewGroup(
 	cfgPath string,
 	terminateDefinitionQ chan string,
 	idLookup host.IDLookup,
+	monitor *monitor.Monitor,
+	scheduler *Scheduler,
+	verifier *allowlist.Verifier,
 ) (g Group, c FeaturesCache, err error) {
 
 	g, c, err = loadFn(il, passthroughEnv, cfgPath, cmdReqHandle, configHandle, terminateDefinitionQ)
@@ -56,6 +64,9 @@ func NewGroup(
 
 	g.emitter = emitter
 	g.idLookup = idLookup
+	g.monitor = monitor
+	g.scheduler = scheduler
+	g.verifier = verifier
 
 	return
 }
@@ -64,7 +75,7 @@ func NewGroup(
 // provided context
 func (g *Group) Run(ctx context.Context) (hasStartedAnyOHI bool) {
 	for _, integr := range g.integrations {
-		go NewRunner(integr, g.emitter, g.dSources, g.handleErrorsProvide, g.cmdReqHandle, g.configHandle, g.terminateDefinitionQ, g.idLookup).Run(ctx, nil, nil)
+		go NewRunner(integr, g.emitter, g.dSources, g.handleErrorsProvide, g.cmdReqHandle, g.configHandle, g.terminateDefinitionQ, g.idLookup, g.monitor, g.scheduler, g.verifier).Run(ctx, nil, nil)
 		hasStartedAnyOHI = true
 	}
 
@@ -42,6 +42,8 @@ func NewLoadFn(cfg config2.YAML, agentAndCCFeatures *Features) LoadFn {
 			if err != nil {
 				return
 			}
+			i.ConfigPath = cfgPath
+			i.TemplatePath = cfgEntry.TemplatePath
 
 			if agentAndCCFeatures == nil {
 				if cfgEntry.When.Feature == "" {
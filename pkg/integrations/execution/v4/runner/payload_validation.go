@@ -0,0 +1,47 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/emitter"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/protocol"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+var vlog = log.WithComponent("integrations.runner.PayloadValidation")
+
+// validatingEmitter validates the protocol v4 payloads against the protocol types before emitting them, reporting
+// the rejected items to the monitor. Payloads are emitted anyway, as the valid items are still processed.
+type validatingEmitter struct {
+	emitter.Emitter
+	monitor *monitor.Monitor
+}
+
+// NewValidatingEmitter wraps the emitter to validate the integration payloads.
+func NewValidatingEmitter(e emitter.Emitter, m *monitor.Monitor) emitter.Emitter {
+	return &validatingEmitter{
+		Emitter: e,
+		monitor: m,
+	}
+}
+
+func (v *validatingEmitter) Emit(definition integration.Definition, extraLabels data.Map, entityRewrite []data.EntityRewrite, integrationJSON []byte) error {
+	if version, err := protocol.VersionFromPayload(integrationJSON, true); err == nil && version == protocol.V4 {
+		if validationErrs := protocol.ValidatePayloadV4(integrationJSON); len(validationErrs) > 0 {
+			errs := make([]error, len(validationErrs))
+			for i, err := range validationErrs {
+				errs[i] = err
+			}
+			vlog.
+				WithFields(LogFields(definition)).
+				WithField("rejected_items", len(errs)).
+				WithError(errs[0]).
+				Warn("integration payload doesn't conform to the protocol v4")
+			v.monitor.ReportPayloadErrors(monitoredIntegration(definition), errs)
+		}
+	}
+	return v.Emitter.Emit(definition, extraLabels, entityRewrite, integrationJSON)
+}
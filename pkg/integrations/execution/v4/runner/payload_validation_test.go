@@ -0,0 +1,53 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingEmitter struct {
+	emitted int
+}
+
+func (e *countingEmitter) Emit(_ integration.Definition, _ data.Map, _ []data.EntityRewrite, _ []byte) error {
+	e.emitted++
+	return nil
+}
+
+func TestValidatingEmitter(t *testing.T) {
+	inner := &countingEmitter{}
+	m := monitor.New(nil)
+	e := NewValidatingEmitter(inner, m)
+	def := integration.Definition{Name: "nri-test", ConfigPath: "/etc/newrelic-infra/integrations.d/test.yml"}
+
+	// GIVEN a valid v4 payload, a v3 payload and a v4 payload with two invalid metrics
+	valid := `{"protocol_version":"4","integration":{"name":"nri-test"},"data":[{"metrics":[{"name":"a","type":"gauge","value":1}]}]}`
+	v3 := `{"name":"nri-test","protocol_version":"3","data":[{"metrics":[{"event_type":"TestSample"}]}]}`
+	invalid := `{"protocol_version":"4","integration":{"name":"nri-test"},"data":[{"metrics":[{"name":"a","type":"gauge","value":"1"},{"name":"b","value":1}]}]}`
+
+	// WHEN they are emitted
+	for _, payload := range []string{valid, v3, invalid} {
+		require.NoError(t, e.Emit(def, nil, nil, []byte(payload)))
+	}
+
+	// THEN all of them are forwarded
+	assert.Equal(t, 3, inner.emitted)
+
+	// AND the rejected items of the invalid payload are reported
+	status := m.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, def.ConfigPath, status[0].ConfigPath)
+	require.NotNil(t, status[0].Payloads)
+	assert.Equal(t, uint64(2), status[0].Payloads.RejectedItems)
+	assert.Equal(t, []string{
+		`data[0].metrics[0]: metric "a": value must be a number`,
+		`data[0].metrics[1]: metric "b": missing required 'type' field`,
+	}, status[0].Payloads.LastErrors)
+}
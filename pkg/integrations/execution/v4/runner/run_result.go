@@ -0,0 +1,69 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+
+	"github.com/newrelic/infrastructure-agent/internal/gobackfill"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
+)
+
+// unknownExitCode is reported for failures not caused by a non-zero exit status.
+const unknownExitCode = -1
+
+// runResult collects the execution errors of all the instances of an integration run.
+type runResult struct {
+	lock     sync.Mutex
+	recorded sync.WaitGroup
+	err      error
+	exitCode int
+}
+
+func (rr *runResult) record(err error) {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		rr.exitCode = gobackfill.ExitCode(exitErr)
+	} else if rr.err == nil {
+		rr.exitCode = unknownExitCode
+	}
+	rr.err = err
+}
+
+// failure waits for all the execution errors to be recorded and returns the failure of the run, if any.
+func (rr *runResult) failure(stderr []string) *monitor.Failure {
+	rr.recorded.Wait()
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+	if rr.err == nil {
+		return nil
+	}
+	return &monitor.Failure{ExitCode: rr.exitCode, Error: rr.err.Error(), Stderr: stderr}
+}
+
+// recordErrors records the execution errors in the run result while forwarding them to the returned channel, which
+// is closed when all the errors have been received.
+func recordErrors(ctx context.Context, errs <-chan error, result *runResult) <-chan error {
+	fwd := make(chan error, cap(errs))
+	result.recorded.Add(1)
+	go func() {
+		defer result.recorded.Done()
+		defer close(fwd)
+		for err := range errs {
+			result.record(err)
+			// errors are forwarded even after a timeout cancels the context, while the handler keeps reading
+			select {
+			case fwd <- err:
+			default:
+				select {
+				case fwd <- err:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+	return fwd
+}
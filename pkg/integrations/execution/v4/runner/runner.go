@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"context"
 	"github.com/newrelic/infrastructure-agent/internal/agent/instrumentation"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/constants"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/outputhandler/v4/emitter"
@@ -24,7 +25,10 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/cmdrequest/protocol"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
 	cfgprotocol "github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest/protocol"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/allowlist"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/executor"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/when"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 
@@ -40,6 +44,9 @@ var (
 	//2- map: &{any character}
 	//3- word: any character except spaces
 	logrusRegexp = regexp.MustCompile(`([^\s]*?)=(".*?[^\\]"|&{.*?}|[^\s]*)`)
+	// conditionsWatchInterval is how often the "when" conditions are evaluated while integrations with interval 0
+	// wait for them to be met, and while any integration runs, to stop it once they are no longer met.
+	conditionsWatchInterval = 15 * time.Second
 )
 
 //generic types to handle the stderr log parsing
@@ -63,10 +70,19 @@ type runner struct {
 	cache          cache.Cache
 	terminateQueue chan<- string
 	idLookup       host.IDLookup
+	monitor        *monitor.Monitor
+	integration    monitor.Integration // identifies the definition in the monitor
+	scheduler      *Scheduler
+	verifier       *allowlist.Verifier
+	// error of the last verification against the allow-list, to report when it changes
+	verificationErr string
+	// result of the last evaluation of the "when" conditions, to log when it changes
+	conditionsEvaluated bool
+	conditionsMet       bool
 }
 
 // NewRunner creates an integration runner instance.
-// args: discoverySources, handleErrorsProvide and cmdReqHandle are optional (nils allowed).
+// args: discoverySources, handleErrorsProvide, cmdReqHandle, monitor, scheduler and verifier are optional (nils allowed).
 func NewRunner(
 	intDef integration.Definition,
 	emitter emitter.Emitter,
@@ -76,6 +92,9 @@ func NewRunner(
 	configHandle configrequest.HandleFn,
 	terminateQ chan<- string,
 	idLookup host.IDLookup,
+	monitor *monitor.Monitor,
+	scheduler *Scheduler,
+	verifier *allowlist.Verifier,
 ) *runner {
 	r := &runner{
 		emitter:        emitter,
@@ -88,6 +107,12 @@ func NewRunner(
 		terminateQueue: terminateQ,
 		cache:          cache.CreateCache(),
 		idLookup:       idLookup,
+		monitor:        monitor,
+		scheduler:      scheduler,
+		verifier:       verifier,
+	}
+	if monitor != nil {
+		r.integration = monitoredIntegration(intDef)
 	}
 	if handleErrorsProvide != nil {
 		r.handleErrors = handleErrorsProvide()
@@ -101,8 +126,27 @@ func NewRunner(
 func (r *runner) Run(ctx context.Context, pidWCh, exitCodeCh chan<- int) {
 	r.log = illog.WithFields(LogFields(r.definition))
 	defer r.killChildren()
+
+	r.monitor.Track(r.integration)
+	defer func() {
+		// finished single runs are kept to report their last run
+		if ctx.Err() != nil {
+			r.monitor.Untrack(r.integration)
+		}
+	}()
+
+	if delay := r.scheduler.StartDelay(r.definition.Interval); delay > 0 {
+		r.log.WithField("delay", delay).Debug("Delaying integration first execution.")
+		r.monitor.RunScheduled(r.integration, time.Now().Add(delay))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+
 	for {
-		waitForNextExecution := time.After(r.definition.Interval)
+		start := time.Now()
 
 		// only cmd-channel run-requests require exit-code, and they only trigger a single instance
 		//var exitCodeCh chan int
@@ -110,31 +154,91 @@ func (r *runner) Run(ctx context.Context, pidWCh, exitCodeCh chan<- int) {
 		//	exitCodeCh = make(chan int, 1)
 		//}
 
-		values, err := r.applyDiscovery()
-		if err != nil {
+		waitForConditions := false
+		// files are verified before discovery and conditions, which can run commands
+		if !r.verify() {
+			r.log.Debug("Skipping integration run, as it isn't allowed.")
+		} else if values, err := r.applyDiscovery(); err != nil {
 			r.log.
 				WithError(helpers.ObfuscateSensitiveDataFromError(err)).
 				Error("can't fetch discovery items")
 		} else {
-			if when.All(r.definition.WhenConditions...) {
-				r.execute(ctx, values, pidWCh, exitCodeCh)
+			conditionsMet := r.evaluateConditions()
+			if conditionsMet {
+				if r.definition.Daemon {
+					conditionsMet = r.supervise(ctx, values, pidWCh)
+				} else {
+					r.executeWithRetries(ctx, values, pidWCh, exitCodeCh)
+				}
 			}
+			// single runs and daemons wait for their conditions to be met (again) instead of finishing
+			waitForConditions = r.definition.SingleRun() && !conditionsMet
 		}
 
-		if r.definition.SingleRun() {
+		if r.definition.SingleRun() && !waitForConditions {
 			r.log.Debug("Integration single run finished")
 			return
 		}
 
+		nextExecution := start.Add(r.definition.Interval)
+		if waitForConditions {
+			nextExecution = time.Now().Add(conditionsWatchInterval)
+		}
+		r.monitor.RunScheduled(r.integration, nextExecution)
 		select {
 		case <-ctx.Done():
 			r.log.Debug("Integration has been interrupted")
 			return
-		case <-waitForNextExecution:
+		case <-time.After(time.Until(nextExecution)):
 		}
 	}
 }
 
+// evaluateConditions returns whether the "when" conditions of the integration are met, logging whenever the result
+// changes, so integrations start and stop along with the services they depend on.
+func (r *runner) evaluateConditions() bool {
+	if len(r.definition.WhenConditions) == 0 {
+		return true
+	}
+	met := when.All(r.definition.WhenConditions...)
+	if !r.conditionsEvaluated || met != r.conditionsMet {
+		if met {
+			r.log.Info("Integration conditions are met. Executing it.")
+		} else {
+			r.log.Info("Integration conditions are not met. Waiting for them before executing it.")
+		}
+	}
+	r.conditionsEvaluated = true
+	r.conditionsMet = met
+	return met
+}
+
+// watchConditions returns a context that is cancelled when the "when" conditions of the integration are no longer
+// met, re-evaluating them every conditionsWatchInterval while the integration runs.
+func (r *runner) watchConditions(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if len(r.definition.WhenConditions) == 0 {
+		return ctx, cancel
+	}
+	go func() {
+		ticker := time.NewTicker(conditionsWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !when.All(r.definition.WhenConditions...) {
+					r.log.Debug("Stopping integration as its conditions are no longer met.")
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return ctx, cancel
+}
+
 func (r *runner) killChildren() {
 	if c := r.cache; c != nil {
 		cfgNames := c.ListConfigNames()
@@ -156,6 +260,15 @@ func (r *runner) killChildren() {
 	}
 }
 
+// monitoredIntegration identifies the definition in the integrations monitor.
+func monitoredIntegration(def integration.Definition) monitor.Integration {
+	return monitor.Integration{
+		Name:       def.Name,
+		ConfigPath: def.ConfigPath,
+		Hash:       def.Hash(),
+	}
+}
+
 func LogFields(def integration.Definition) logrus.Fields {
 	fields := logrus.Fields{
 		"integration_name": def.Name,
@@ -199,11 +312,83 @@ func (r *runner) heartBeat() {
 	r.heartBeatFunc()
 }
 
-// execute the integration and wait for all the possible instances (resulting of multiple dSources matches)
-// to finish
+// executeWithRetries executes the integration and, if it fails, re-executes it with exponential backoff as defined
+// by its retry policy. All the discovered instances are re-executed. Once the retries are exhausted, the failure is
+// reported to the monitor.
+// Runs reporting their exit code (command-channel run requests) are never retried, as a single exit code is expected.
+func (r *runner) executeWithRetries(ctx context.Context, matches *databind.Values, pidWCh, exitCodeCh chan<- int) {
+	policy := r.definition.Retry
+	if !policy.Enabled() || exitCodeCh != nil {
+		r.execute(ctx, matches, pidWCh, exitCodeCh)
+		return
+	}
+
+	bo := &backoff.Backoff{
+		Factor: backoff.DefaultFactor,
+		Jitter: backoff.DefaultJitter,
+		Min:    policy.InitialBackoff,
+		Max:    policy.MaxBackoff,
+	}
+	for attempt := 0; ; attempt++ {
+		failure := r.execute(ctx, matches, pidWCh, nil)
+		if failure == nil {
+			return
+		}
+		if attempt == policy.MaxAttempts {
+			failure.Attempts = attempt + 1
+			r.log.WithField("attempts", failure.Attempts).Warn("integration run failed after all its retries")
+			r.monitor.ReportFailure(r.integration, *failure)
+			return
+		}
+
+		wait := bo.ForAttempt(float64(attempt))
+		r.log.WithField("attempt", attempt+1).WithField("backoff", wait).Debug("Retrying failed integration run.")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// execute the integration once the scheduler provides an execution slot, returning the failure of the run, if any.
+// The run is stopped if the integration conditions are no longer met.
+func (r *runner) execute(ctx context.Context, matches *databind.Values, pidWCh, exitCodeCh chan<- int) *monitor.Failure {
+	r.monitor.RunQueued(r.integration)
+	// only periodic integrations take an execution slot, as daemon and single run integrations (interval 0) may run
+	// for as long as the agent, starving the periodic ones
+	if !r.definition.SingleRun() {
+		if !r.scheduler.Acquire(ctx) {
+			r.monitor.RunCancelled(r.integration)
+			return nil
+		}
+		defer r.scheduler.Release()
+	}
+
+	ctx, stopWatching := r.watchConditions(ctx)
+	defer stopWatching()
+
+	// files are verified right before executing them, as they can change while waiting for an execution slot
+	if !r.verify() {
+		r.monitor.RunCancelled(r.integration)
+		return nil
+	}
+
+	r.monitor.RunStarted(r.integration)
+	failure := r.executeInstances(ctx, matches, pidWCh, exitCodeCh)
+	r.monitor.RunFinished(r.integration, r.lastStderr.Since(0), failure)
+	return failure
+}
+
+// executeInstances executes the integration and wait for all the possible instances (resulting of multiple
+// dSources matches) to finish
 // For long-time running integrations, avoids starting the next
 // discover-execute cycle until all the parallel processes have ended
-func (r *runner) execute(ctx context.Context, matches *databind.Values, pidWCh, exitCodeCh chan<- int) {
+// It returns the failure of the run, if any of the instances failed. Runs interrupted by the
+// cancellation of the context aren't failures.
+func (r *runner) executeInstances(ctx context.Context, matches *databind.Values, pidWCh, exitCodeCh chan<- int) *monitor.Failure {
+	parentCtx := ctx
+	stderrMark := r.lastStderr.Mark()
 	ctx, txn := instrumentation.SelfInstrumentation.StartTransaction(ctx, "integration.v4."+r.definition.Name)
 	if hostname, ok := r.definition.ExecutorConfig.Environment["HOSTNAME"]; ok {
 		txn.AddAttribute("integration_hostname", hostname)
@@ -237,13 +422,14 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, pidWCh,
 	if err != nil {
 		txn.NoticeError(err)
 		r.log.WithError(err).Error("can't start integration")
-		return
+		return &monitor.Failure{ExitCode: unknownExitCode, Error: err.Error()}
 	}
+	result := &runResult{}
 
 	// Waits for all the integrations to finish and reads the standard output and errors
 	wg := sync.WaitGroup{}
 	waitForCurrent := make(chan struct{})
-	wg.Add(len(outputs) * 3)
+	wg.Add(len(outputs) * 4)
 	for _, out := range outputs {
 		o := out
 		go func(txn instrumentation.Transaction) {
@@ -270,9 +456,15 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, pidWCh,
 			ctx, seg := txn.StartSegment(ctx, "handleExitCode")
 			defer seg.End()
 
-			r.handleErrors(ctx, o.Receive.Errors)
+			r.handleErrors(ctx, recordErrors(ctx, o.Receive.Errors, result))
 
 		}(txn)
+
+		go func() {
+			defer wg.Done()
+
+			r.handleResources(o.Receive.Resources)
+		}()
 	}
 
 	r.log.Debug("Waiting while the integration instances run.")
@@ -284,11 +476,16 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, pidWCh,
 	select {
 	case <-ctx.Done():
 		r.log.Debug("Integration has been interrupted. Finishing.")
+		if parentCtx.Err() != nil {
+			return nil
+		}
+		// the integration timed out
+		return &monitor.Failure{ExitCode: unknownExitCode, Error: ctx.Err().Error(), Stderr: r.lastStderr.Since(stderrMark)}
 	case <-waitForCurrent:
 		r.log.Debug("Integration instances finished their execution. Waiting until next interval.")
 	}
 
-	return
+	return result.failure(r.lastStderr.Since(stderrMark))
 }
 
 func (r *runner) handleStderr(stderr <-chan []byte) {
@@ -308,6 +505,16 @@ func (r *runner) handleStderr(stderr <-chan []byte) {
 	}
 }
 
+// handleResources reports the resource usage of the integrations run with resource limits.
+func (r *runner) handleResources(resources <-chan executor.ResourceUsage) {
+	for usage := range resources {
+		if usage.OOMKills > 0 {
+			r.log.WithField("oom_kills", usage.OOMKills).Warn("integration killed for exceeding its memory limit")
+		}
+		r.monitor.ReportResourceUsage(r.integration, usage)
+	}
+}
+
 // implementation of the "handleErrors" property
 func (r *runner) logErrors(ctx context.Context, errs <-chan error) {
 	for {
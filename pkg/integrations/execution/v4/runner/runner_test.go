@@ -2,10 +2,18 @@ package runner
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/config"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
 	"sync/atomic"
 	"testing"
@@ -14,12 +22,16 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/cmdrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest/protocol"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/allowlist"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/cache"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/fixtures"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/integration"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/monitor"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/testhelp"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/testhelp/testemit"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/execution/v4/when"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
@@ -51,7 +63,7 @@ func Test_runner_Run(t *testing.T) {
 	require.NoError(t, err)
 
 	e := &testemit.RecordEmitter{}
-	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{})
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}, nil, nil, nil)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
@@ -67,6 +79,55 @@ func Test_runner_Run(t *testing.T) {
 	assert.Empty(t, dataset.Metadata.Labels)
 }
 
+func Test_runner_Run_singleRunsDontTakeExecutionSlots(t *testing.T) {
+	// GIVEN a single run integration
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.IntegrationScript, "bar"),
+		Interval:     "0",
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+	// AND a scheduler whose only execution slot is taken
+	s := NewScheduler(1, false)
+	require.True(t, s.Acquire(context.Background()))
+	defer s.Release()
+
+	e := &testemit.RecordEmitter{}
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}, nil, s, nil)
+
+	// WHEN the runner executes it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r.Run(ctx, nil, nil)
+
+	// THEN it runs without waiting for the slot
+	require.NoError(t, ctx.Err())
+	_, err = e.ReceiveFrom("foo")
+	require.NoError(t, err)
+}
+
+func Test_runner_Run_outputSocket(t *testing.T) {
+	// GIVEN an integration sending its payloads through the output socket
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.GoRun(fixtures.SocketGoFile),
+		Transport:    config.TransportSocket,
+		Interval:     "0",
+	}, integration.ErrLookup, []string{"GOCACHE", "GOPATH", "HOME", "PATH", "LOCALAPPDATA"}, nil)
+	require.NoError(t, err)
+
+	e := &testemit.RecordEmitter{}
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}, nil, nil, nil)
+
+	// WHEN the runner executes it
+	r.Run(context.Background(), nil, nil)
+
+	// THEN the payloads are emitted
+	dataset, err := e.ReceiveFrom("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "socket-entity", dataset.DataSet.Entity.Name)
+}
+
 func Test_runner_Run_noHandleForCfgProtocol(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip()
@@ -85,7 +146,7 @@ func Test_runner_Run_noHandleForCfgProtocol(t *testing.T) {
 	require.NoError(t, err)
 
 	e := &testemit.RecordEmitter{}
-	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{})
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{}, nil, nil, nil)
 
 	// WHEN the runner executes the binary and handle the payload.
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
@@ -128,7 +189,7 @@ func Test_runner_Run_failToUnMarshallCfgProtocol(t *testing.T) {
 	require.NoError(t, err)
 
 	e := &testemit.RecordEmitter{}
-	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{})
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}, nil, nil, nil)
 
 	// WHEN the runner executes the binary and handle the payload.
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
@@ -167,7 +228,7 @@ func Test_runner_Run_handlesCfgProtocol(t *testing.T) {
 		atomic.AddUint32(&called, 1)
 	}
 	e := &testemit.RecordEmitter{}
-	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, mockHandleFn, nil, host.IDLookup{})
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, mockHandleFn, nil, host.IDLookup{}, nil, nil, nil)
 
 	// WHEN the runner executes the binary and handle the payload.
 	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
@@ -182,3 +243,397 @@ func Test_runner_Run_handlesCfgProtocol(t *testing.T) {
 		return false
 	}, time.Second, 10*time.Millisecond)
 }
+
+func Test_runner_Run_retriesFailedRuns(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	// GIVEN a failing integration with a retry policy
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.ErrorCmd),
+		Interval:     "1m",
+		Retry: config.Retry{
+			MaxAttempts:    2,
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     20 * time.Millisecond,
+		},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	var events []sample.Event
+	m := monitor.New(func(event sample.Event, _ entity.Key) {
+		events = append(events, event)
+	})
+	r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{}, m, nil, nil)
+
+	// WHEN the runner executes it
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	r.Run(ctx, nil, nil)
+
+	// THEN an IntegrationFailure event is reported after all the retries
+	require.Len(t, events, 1)
+	failure, ok := events[0].(*monitor.FailureEvent)
+	require.True(t, ok)
+	assert.Equal(t, "IntegrationFailure", failure.EventType)
+	assert.Equal(t, "foo", failure.IntegrationName)
+	assert.Equal(t, 3, failure.Attempts)
+	assert.Equal(t, 3, failure.ExitCode)
+	assert.Equal(t, "very bad error", failure.Stderr)
+}
+
+func Test_runner_Run_restartsDaemons(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	// GIVEN a daemon integration whose process exits, with a limited number of restarts
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.ErrorCmd),
+		Mode:         config.ModeDaemon,
+		Retry: config.Retry{
+			MaxAttempts:    2,
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     20 * time.Millisecond,
+		},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	var events []sample.Event
+	m := monitor.New(func(event sample.Event, _ entity.Key) {
+		events = append(events, event)
+	})
+	r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{}, m, nil, nil)
+
+	// WHEN the runner supervises it
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	r.Run(ctx, nil, nil)
+
+	// THEN the process is restarted until the restarts are exhausted
+	status := m.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, uint64(2), status[0].Restarts)
+	assert.NotNil(t, status[0].LastRestart)
+	require.Len(t, events, 1)
+	failure, ok := events[0].(*monitor.FailureEvent)
+	require.True(t, ok)
+	assert.Equal(t, 3, failure.Attempts)
+	assert.Equal(t, 3, failure.ExitCode)
+}
+
+func Test_runner_Run_restartsDaemonsWithoutHeartbeats(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	// GIVEN a daemon integration that doesn't send any heartbeat within its timeout
+	timeout := 200 * time.Millisecond
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.BlockedCmd),
+		Mode:         config.ModeDaemon,
+		Timeout:      &timeout,
+		Retry: config.Retry{
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     20 * time.Millisecond,
+		},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	m := monitor.New(nil)
+	r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{}, m, nil, nil)
+
+	// WHEN the runner supervises it
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, nil, nil)
+
+	// THEN the process is restarted after timing out
+	assert.Eventually(t, func() bool {
+		status := m.Status()
+		return len(status) == 1 && status[0].Restarts >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.NotEmpty(t, m.Status()[0].LastError)
+}
+
+func Test_runner_Run_reportsRunStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	// GIVEN a failing single-run integration loaded from a config file
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.ErrorCmd),
+		Interval:     "0",
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+	def.ConfigPath = "/etc/newrelic-infra/integrations.d/foo.yml"
+
+	m := monitor.New(nil)
+	r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{}, m, nil, nil)
+
+	// WHEN the runner executes it
+	r.Run(context.Background(), nil, nil)
+
+	// THEN the monitor reports the status of its last run
+	status := m.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, "foo", status[0].Name)
+	assert.Equal(t, def.ConfigPath, status[0].ConfigPath)
+	assert.Equal(t, def.Hash(), status[0].Hash)
+	require.NotNil(t, status[0].LastStart)
+	require.NotNil(t, status[0].LastEnd)
+	require.NotNil(t, status[0].LastExitCode)
+	assert.Equal(t, 3, *status[0].LastExitCode)
+	assert.Equal(t, 1, status[0].ConsecutiveFailures)
+	assert.Equal(t, []string{"very bad error"}, status[0].LastStderr)
+	assert.Nil(t, status[0].NextRun)
+	assert.Equal(t, monitor.Executions{}, m.Executions())
+}
+
+func Test_runner_Run_followsWhenConditions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	dir, err := ioutil.TempDir("", "conditions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	serviceFile := filepath.Join(dir, "service.pid")
+	defer func(interval time.Duration) { conditionsWatchInterval = interval }(conditionsWatchInterval)
+	conditionsWatchInterval = 100 * time.Millisecond
+
+	// GIVEN a long-running integration conditioned to the existence of a file
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.BlockedCmd),
+		When:         config.EnableConditions{FileExists: serviceFile},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+	def.Interval = 100 * time.Millisecond
+
+	m := monitor.New(nil)
+	r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{}, m, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, nil, nil)
+
+	running := func(expected int) func() bool {
+		return func() bool { return m.Executions().Running == expected }
+	}
+
+	// WHEN the file doesn't exist THEN the integration isn't executed
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, monitor.Executions{}, m.Executions())
+
+	// WHEN the file is created THEN the integration is executed
+	require.NoError(t, ioutil.WriteFile(serviceFile, []byte("1234"), 0644))
+	assert.Eventually(t, running(1), 5*time.Second, 50*time.Millisecond)
+
+	// WHEN the file is removed THEN the integration is stopped
+	require.NoError(t, os.Remove(serviceFile))
+	assert.Eventually(t, running(0), 5*time.Second, 50*time.Millisecond)
+}
+
+func Test_runner_Run_singleRunsWaitForWhenConditions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conditions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	serviceFile := filepath.Join(dir, "service.pid")
+	defer func(interval time.Duration) { conditionsWatchInterval = interval }(conditionsWatchInterval)
+	conditionsWatchInterval = 100 * time.Millisecond
+
+	// GIVEN a single run integration conditioned to the existence of a file
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.IntegrationScript, "bar"),
+		Interval:     "0",
+		When:         config.EnableConditions{FileExists: serviceFile},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	e := &testemit.RecordEmitter{}
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}, nil, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	finished := make(chan struct{})
+	go func() {
+		r.Run(ctx, nil, nil)
+		close(finished)
+	}()
+
+	// WHEN the file doesn't exist THEN the runner keeps waiting
+	select {
+	case <-finished:
+		require.Fail(t, "single run finished before its conditions were met")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// WHEN the file is created THEN the integration is executed once
+	require.NoError(t, ioutil.WriteFile(serviceFile, []byte("1234"), 0644))
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		require.Fail(t, "single run didn't finish once its conditions were met")
+	}
+	_, err = e.ReceiveFrom("foo")
+	require.NoError(t, err)
+}
+
+func Test_runner_Run_followsWhenConditionsForDaemons(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	defer func(interval time.Duration) { conditionsWatchInterval = interval }(conditionsWatchInterval)
+	conditionsWatchInterval = 100 * time.Millisecond
+
+	// GIVEN a daemon integration conditioned to a running process
+	service := fmt.Sprintf("conditions-service-%d", time.Now().UnixNano())
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.BlockedCmd),
+		Mode:         config.ModeDaemon,
+		When:         config.EnableConditions{ProcessRunning: service},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	m := monitor.New(nil)
+	r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, nil, nil, host.IDLookup{}, m, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, nil, nil)
+
+	running := func(expected int) func() bool {
+		return func() bool { return m.Executions().Running == expected }
+	}
+
+	// WHEN the process isn't running THEN the daemon isn't executed
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, monitor.Executions{}, m.Executions())
+
+	// WHEN the process starts THEN the daemon is executed
+	cmd := exec.Command("/bin/sh", "-c", "sleep 60; : "+service)
+	require.NoError(t, cmd.Start())
+	defer func() { _ = cmd.Process.Kill() }()
+	assert.Eventually(t, running(1), 5*time.Second, 50*time.Millisecond)
+
+	// WHEN the process finishes THEN the daemon is stopped
+	require.NoError(t, cmd.Process.Kill())
+	_ = cmd.Wait()
+	assert.Eventually(t, running(0), 5*time.Second, 50*time.Millisecond)
+
+	// WHEN the process starts again THEN the daemon is executed again
+	cmd = exec.Command("/bin/sh", "-c", "sleep 60; : "+service)
+	require.NoError(t, cmd.Start())
+	assert.Eventually(t, running(1), 5*time.Second, 50*time.Millisecond)
+}
+
+func Test_runner_Run_refusesIntegrationsNotInAllowList(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	dir, err := ioutil.TempDir("", "allowlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.IntegrationScript, "bar"),
+		Interval:     "0",
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+	executable, err := def.Executable()
+	require.NoError(t, err)
+	contents, err := ioutil.ReadFile(executable)
+	require.NoError(t, err)
+	allowedDigest := sha256.Sum256(contents)
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	loadAllowList := func(list string) *allowlist.Verifier {
+		path := filepath.Join(dir, "allow-list.sha256")
+		require.NoError(t, ioutil.WriteFile(path, []byte(list), 0644))
+		signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(list)))
+		require.NoError(t, ioutil.WriteFile(path+allowlist.SignatureExtension, []byte(signature), 0644))
+		v, err := allowlist.Load(path, publicKey)
+		require.NoError(t, err)
+		return v
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		// GIVEN an allow-list containing the integration executable
+		v := loadAllowList(hex.EncodeToString(allowedDigest[:]) + "  " + executable + "\n")
+
+		// WHEN the runner executes the integration
+		e := &testemit.RecordEmitter{}
+		NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}, nil, nil, v).Run(context.Background(), nil, nil)
+
+		// THEN the integration runs
+		_, err := e.ReceiveFrom("foo")
+		assert.NoError(t, err)
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		// GIVEN an allow-list without the integration executable
+		otherDigest := sha256.Sum256([]byte("other"))
+		v := loadAllowList(hex.EncodeToString(otherDigest[:]) + "  /usr/bin/other\n")
+		var events []sample.Event
+		m := monitor.New(func(event sample.Event, _ entity.Key) {
+			events = append(events, event)
+		})
+
+		// WHEN the runner tries to execute the integration
+		e := &testemit.RecordEmitter{}
+		NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}, m, nil, v).Run(context.Background(), nil, nil)
+
+		// THEN the integration isn't executed
+		assert.Empty(t, m.Status()[0].LastStart)
+		assert.Contains(t, m.Status()[0].VerificationError, "isn't in the integrations allow-list")
+
+		// AND a verification failure event is reported
+		require.Len(t, events, 1)
+		event := events[0].(*monitor.VerificationEvent)
+		assert.Equal(t, "foo", event.IntegrationName)
+		assert.Equal(t, executable, event.File)
+	})
+
+	t.Run("refused before conditions", func(t *testing.T) {
+		// GIVEN an allow-list without the integration executable
+		otherDigest := sha256.Sum256([]byte("other"))
+		v := loadAllowList(hex.EncodeToString(otherDigest[:]) + "  /usr/bin/other\n")
+		// AND an integration with conditions, which can run commands
+		evaluated := false
+		condDef := def
+		condDef.WhenConditions = []when.Condition{func() bool {
+			evaluated = true
+			return true
+		}}
+
+		// WHEN the runner tries to execute the integration
+		e := &testemit.RecordEmitter{}
+		NewRunner(condDef, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}, nil, nil, v).Run(context.Background(), nil, nil)
+
+		// THEN its conditions aren't evaluated
+		assert.False(t, evaluated)
+	})
+
+	t.Run("refused template", func(t *testing.T) {
+		// GIVEN an allow-list containing the integration executable but not its configuration template
+		v := loadAllowList(hex.EncodeToString(allowedDigest[:]) + "  " + executable + "\n")
+		templateDef := def
+		templateDef.TemplatePath = filepath.Join(dir, "template.yml")
+		templateDef.ConfigTemplate = []byte("tampered: true")
+		m := monitor.New(nil)
+
+		// WHEN the runner tries to execute the integration
+		e := &testemit.RecordEmitter{}
+		NewRunner(templateDef, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}, m, nil, v).Run(context.Background(), nil, nil)
+
+		// THEN the integration isn't executed
+		assert.Empty(t, m.Status()[0].LastStart)
+		assert.Contains(t, m.Status()[0].VerificationError, templateDef.TemplatePath)
+	})
+}
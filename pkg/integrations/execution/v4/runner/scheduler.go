@@ -0,0 +1,62 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// maxStartJitter bounds the delay of the first execution of the integrations, so integrations with long intervals
+// don't take too long to report after the agent starts.
+const maxStartJitter = time.Minute
+
+// Scheduler spreads the first execution of the periodic integrations across their interval and limits how many
+// of their runs are executed concurrently. A nil Scheduler runs the integrations right away.
+type Scheduler struct {
+	slots       chan struct{} // nil when the concurrent runs aren't limited
+	startJitter bool
+}
+
+// NewScheduler creates a Scheduler allowing up to maxConcurrent integration runs at the same time (zero or
+// negative: unlimited), which delays the first execution of the periodic integrations if startJitter is set.
+func NewScheduler(maxConcurrent int, startJitter bool) *Scheduler {
+	s := &Scheduler{startJitter: startJitter}
+	if maxConcurrent > 0 {
+		s.slots = make(chan struct{}, maxConcurrent)
+	}
+	return s
+}
+
+// StartDelay returns a random delay for the first execution of an integration with the given interval.
+func (s *Scheduler) StartDelay(interval time.Duration) time.Duration {
+	if s == nil || !s.startJitter || interval <= 0 {
+		return 0
+	}
+	if interval > maxStartJitter {
+		interval = maxStartJitter
+	}
+	return time.Duration(rand.Int63n(int64(interval)))
+}
+
+// Acquire blocks until an execution slot is available. It returns false if the context is cancelled before.
+func (s *Scheduler) Acquire(ctx context.Context) bool {
+	if s == nil || s.slots == nil {
+		return true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Release frees the execution slot taken by Acquire.
+func (s *Scheduler) Release() {
+	if s == nil || s.slots == nil {
+		return
+	}
+	<-s.slots
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_Acquire(t *testing.T) {
+	s := NewScheduler(2, false)
+
+	assert.True(t, s.Acquire(context.Background()))
+	assert.True(t, s.Acquire(context.Background()))
+
+	// a third run waits until one of the previous releases its slot
+	acquired := make(chan bool)
+	go func() {
+		acquired <- s.Acquire(context.Background())
+	}()
+	select {
+	case <-acquired:
+		assert.Fail(t, "acquired more slots than the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.Release()
+	select {
+	case ok := <-acquired:
+		assert.True(t, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "slot not acquired after release")
+	}
+}
+
+func TestScheduler_Acquire_Cancelled(t *testing.T) {
+	s := NewScheduler(1, false)
+	assert.True(t, s.Acquire(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.False(t, s.Acquire(ctx))
+}
+
+func TestScheduler_Unlimited(t *testing.T) {
+	for _, s := range []*Scheduler{nil, NewScheduler(0, false)} {
+		for i := 0; i < 100; i++ {
+			assert.True(t, s.Acquire(context.Background()))
+		}
+		assert.NotPanics(t, s.Release)
+	}
+}
+
+func TestScheduler_StartDelay(t *testing.T) {
+	var noScheduler *Scheduler
+	assert.Zero(t, noScheduler.StartDelay(time.Minute))
+	assert.Zero(t, NewScheduler(0, false).StartDelay(time.Minute))
+	assert.Zero(t, NewScheduler(0, true).StartDelay(0))
+
+	s := NewScheduler(0, true)
+	for i := 0; i < 100; i++ {
+		assert.True(t, s.StartDelay(10*time.Second) < 10*time.Second)
+		// long intervals don't delay the first execution more than maxStartJitter
+		assert.True(t, s.StartDelay(time.Hour) < maxStartJitter)
+	}
+}
@@ -14,9 +14,10 @@ const (
 )
 
 type stderrQueue struct {
-	mutex    sync.Mutex
-	nextLine int
-	queue    [stderrQueueLen][]byte
+	mutex       sync.Mutex
+	nextLine    int
+	flushedLine int
+	queue       [stderrQueueLen][]byte
 }
 
 func (sq *stderrQueue) Add(line []byte) {
@@ -26,29 +27,56 @@ func (sq *stderrQueue) Add(line []byte) {
 	sq.nextLine++
 }
 
+// Flush returns the lines added since the previous flush.
 func (sq *stderrQueue) Flush() string {
 	sq.mutex.Lock()
 	defer sq.mutex.Unlock()
-	if sq.nextLine == 0 {
+	pending := sq.nextLine - sq.flushedLine
+	if pending == 0 {
 		return "(no standard error output)"
 	}
-	var start, lines int
 	joint := bytes.Buffer{}
-	if sq.nextLine < stderrQueueLen {
-		start = 0
-		lines = sq.nextLine
-	} else {
-		joint.WriteString(fmt.Sprintf("(last %d lines out of %d): ", stderrQueueLen, sq.nextLine))
-		start = sq.nextLine % stderrQueueLen
-		lines = stderrQueueLen
+	if pending > stderrQueueLen {
+		joint.WriteString(fmt.Sprintf("(last %d lines out of %d): ", stderrQueueLen, pending))
 	}
-	for i := 0; i < lines; i++ {
+	for i, line := range sq.since(sq.flushedLine) {
 		if i > 0 {
 			joint.WriteByte('\n')
 		}
-		joint.Write(sq.queue[start])
-		start = (start + 1) % stderrQueueLen
+		joint.Write(line)
 	}
-	sq.nextLine = 0
+	sq.flushedLine = sq.nextLine
 	return joint.String()
 }
+
+// Mark returns the position of the next added line, to retrieve the lines added after it with Since.
+func (sq *stderrQueue) Mark() int {
+	sq.mutex.Lock()
+	defer sq.mutex.Unlock()
+	return sq.nextLine
+}
+
+// Since returns the last lines added after the mark, no matter whether they were flushed.
+func (sq *stderrQueue) Since(mark int) []string {
+	sq.mutex.Lock()
+	defer sq.mutex.Unlock()
+	lines := sq.since(mark)
+	tail := make([]string, len(lines))
+	for i, line := range lines {
+		tail[i] = string(line)
+	}
+	return tail
+}
+
+// since returns the lines kept in the queue that were added after the mark. Requires holding the mutex.
+func (sq *stderrQueue) since(mark int) [][]byte {
+	start := mark
+	if sq.nextLine-start > stderrQueueLen {
+		start = sq.nextLine - stderrQueueLen
+	}
+	lines := make([][]byte, 0, sq.nextLine-start)
+	for i := start; i < sq.nextLine; i++ {
+		lines = append(lines, sq.queue[i%stderrQueueLen])
+	}
+	return lines
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStderrQueue_Flush(t *testing.T) {
+	var sq stderrQueue
+	assert.Equal(t, "(no standard error output)", sq.Flush())
+
+	sq.Add([]byte("first"))
+	sq.Add([]byte("second"))
+	assert.Equal(t, "first\nsecond", sq.Flush())
+	assert.Equal(t, "(no standard error output)", sq.Flush())
+
+	for i := 0; i < stderrQueueLen+2; i++ {
+		sq.Add([]byte(fmt.Sprint(i)))
+	}
+	assert.Equal(t, "(last 10 lines out of 12): 2\n3\n4\n5\n6\n7\n8\n9\n10\n11", sq.Flush())
+}
+
+func TestStderrQueue_Since(t *testing.T) {
+	var sq stderrQueue
+	sq.Add([]byte("previous run"))
+
+	mark := sq.Mark()
+	assert.Empty(t, sq.Since(mark))
+
+	sq.Add([]byte("first"))
+	sq.Add([]byte("second"))
+	sq.Flush()
+	assert.Equal(t, []string{"first", "second"}, sq.Since(mark))
+
+	for i := 0; i < stderrQueueLen; i++ {
+		sq.Add([]byte(fmt.Sprint(i)))
+	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}, sq.Since(mark))
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"fmt"
+)
+
+// verify returns whether the executable and configuration files of the integration are in the allow-list, if any.
+// Refused integrations aren't executed. The result is logged and reported to the monitor whenever it changes.
+func (r *runner) verify() bool {
+	if r.verifier == nil {
+		return true
+	}
+	file, err := r.verifyFiles()
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	}
+	if errMsg != r.verificationErr {
+		if err != nil {
+			r.log.WithError(err).WithField("file", file).Error("integration refused by the integrations allow-list")
+		} else {
+			r.log.Info("integration allowed by the integrations allow-list")
+		}
+		r.monitor.ReportVerification(r.integration, file, err)
+	}
+	r.verificationErr = errMsg
+	return err == nil
+}
+
+// verifyFiles verifies the integration files, returning the first one that isn't allowed. The configuration
+// template is verified by the contents loaded from its file, which are the ones passed to the integration.
+func (r *runner) verifyFiles() (file string, err error) {
+	executable, err := r.definition.Executable()
+	if err != nil {
+		return r.definition.Name, fmt.Errorf("can't find integration executable: %s", err)
+	}
+	for _, file := range []string{executable, r.definition.ConfigPath, r.definition.DefinitionPath} {
+		if file == "" {
+			continue
+		}
+		if err := r.verifier.Verify(file); err != nil {
+			return file, err
+		}
+	}
+	if r.definition.TemplatePath != "" {
+		if err := r.verifier.VerifyContent(r.definition.TemplatePath, r.definition.ConfigTemplate); err != nil {
+			return r.definition.TemplatePath, err
+		}
+	}
+	return "", nil
+}
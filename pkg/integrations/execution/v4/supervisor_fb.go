@@ -93,6 +93,10 @@ func buildFbExecutor(fbIntCfg FBSupervisorConfig, cfgLoader *logs2.CfgLoader) fu
 	return func() (Executor, error) {
 
 		cfgContent, externalCfg, cErr := cfgLoader.LoadAndFormat()
+		if cErr == logs2.ErrNoFluentBitConfigs {
+			// the supervisor waits for the logging configs to change before trying again
+			sFBLogger.Debug("All the logs are forwarded by the native forwarder. Not running Fluent Bit.")
+		}
 		if cErr != nil {
 			return nil, cErr
 		}
@@ -2,7 +2,24 @@
 // SPDX-License-Identifier: Apache-2.0
 package when
 
-import "os"
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+var clog = log.WithComponent("integrations.Conditions")
+
+// commandTimeout is the timeout of the command_succeeds condition.
+var commandTimeout = 10 * time.Second
 
 // Condition is any function that can return true or false
 type Condition func() bool
@@ -32,6 +49,67 @@ func EnvExists(envVars map[string]string) Condition {
 	}
 }
 
+// FileContains creates a Condition returning true when the content of the passed file matches the regular
+// expression.
+func FileContains(path string, regex *regexp.Regexp) Condition {
+	return func() bool {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return false
+		}
+		return regex.Match(content)
+	}
+}
+
+// ProcessRunning creates a Condition returning true when the name or the command line of any running process
+// matches the regular expression.
+func ProcessRunning(regex *regexp.Regexp) Condition {
+	return func() bool {
+		procs, err := process.Processes()
+		if err != nil {
+			return false
+		}
+		for _, proc := range procs {
+			if name, err := proc.Name(); err == nil && regex.MatchString(name) {
+				return true
+			}
+			if cmdLine, err := proc.Cmdline(); err == nil && regex.MatchString(cmdLine) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PortListening creates a Condition returning true when a TCP socket is listening on the passed port. Sockets are
+// looked up instead of connecting to them, so the services aren't bothered by the evaluations.
+func PortListening(port int) Condition {
+	return func() bool {
+		if port <= 0 || port > math.MaxUint16 {
+			return false
+		}
+		listening, err := tcpPortListening(uint16(port))
+		if err != nil {
+			clog.WithError(err).WithField("port", port).Debug("Cannot look up the listening sockets.")
+			return false
+		}
+		return listening
+	}
+}
+
+// CommandSucceeds creates a Condition returning true when the passed command, with its arguments, exits with a
+// zero status.
+func CommandSucceeds(command []string) Condition {
+	return func() bool {
+		if len(command) == 0 {
+			return false
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		return exec.CommandContext(ctx, command[0], command[1:]...).Run() == nil
+	}
+}
+
 // All returns true if and only if all the passed conditions are true.
 // If an empty conditions list is passed, it also returns true.
 func All(conditions ...Condition) bool {
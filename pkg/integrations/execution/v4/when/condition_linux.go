@@ -0,0 +1,9 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package when
+
+import "github.com/newrelic/infrastructure-agent/pkg/metrics/process"
+
+func tcpPortListening(port uint16) (bool, error) {
+	return process.TCPPortListening(port)
+}
@@ -0,0 +1,21 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !linux
+// +build !linux
+
+package when
+
+import "github.com/shirou/gopsutil/v3/net"
+
+func tcpPortListening(port uint16) (bool, error) {
+	connections, err := net.Connections("tcp")
+	if err != nil {
+		return false, err
+	}
+	for _, c := range connections {
+		if c.Status == "LISTEN" && c.Laddr.Port == uint32(port) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -4,7 +4,11 @@ package when
 
 import (
 	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -102,3 +106,48 @@ func TestAll(t *testing.T) {
 		})
 	}
 }
+
+func TestFileContains(t *testing.T) {
+	f, err := ioutil.TempFile("", "conditions")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("ssl: enabled\nport: 5432\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.True(t, FileContains(f.Name(), regexp.MustCompile(`(?m)^port: 5432$`))())
+	assert.False(t, FileContains(f.Name(), regexp.MustCompile(`ssl: disabled`))())
+	assert.False(t, FileContains("some-unexisting-file", regexp.MustCompile(`.*`))())
+}
+
+func TestProcessRunning(t *testing.T) {
+	// GIVEN the test process, which is running
+	self := regexp.QuoteMeta(filepath.Base(os.Args[0]))
+
+	assert.True(t, ProcessRunning(regexp.MustCompile(self))())
+	assert.False(t, ProcessRunning(regexp.MustCompile(`^some-unexisting-process-[0-9a-f]{16}$`))())
+}
+
+func TestPortListening(t *testing.T) {
+	// GIVEN a listening port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// THEN the PortListening condition returns true
+	assert.True(t, PortListening(port)())
+
+	// AND returns false once the port is closed
+	require.NoError(t, listener.Close())
+	assert.False(t, PortListening(port)())
+}
+
+func TestCommandSucceeds(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	assert.True(t, CommandSucceeds([]string{"sh", "-c", "exit 0"})())
+	assert.False(t, CommandSucceeds([]string{"sh", "-c", "exit 3"})())
+	assert.False(t, CommandSucceeds([]string{"some-unexisting-command"})())
+	assert.False(t, CommandSucceeds(nil)())
+}
@@ -216,37 +216,43 @@ func emitInventory(
 }
 
 func emitEvent(emitter agent.PluginEmitter, metadata integration.Definition, dataSet protocol.Dataset, labels map[string]string, annotations map[string]string, entityID entity.ID) {
-	sharedOpts := []func(protocol.EventData){
+	for _, event := range dataSet.Events {
+		e, err := BuildEvent(metadata, dataSet, event, labels, annotations, entityID)
+		if err != nil {
+			elog.WithFields(logrus.Fields{
+				"payload": event,
+				"error":   err,
+			}).Warn("discarding event, failed building event data.")
+			continue
+		}
+
+		emitter.EmitEvent(e, entity.Key(dataSet.Entity.Name))
+	}
+}
+
+// BuildEvent decorates an event of the dataset with the integration labels, annotations, user and entity, as it is
+// sent to New Relic. Entity decoration is skipped for empty entity IDs.
+func BuildEvent(metadata integration.Definition, dataSet protocol.Dataset, event protocol.EventData, labels map[string]string, annotations map[string]string, entityID entity.ID) (protocol.EventData, error) {
+	opts := []func(protocol.EventData){
 		protocol.WithLabels(labels),
 		// add extra annotations
 		protocol.WithAnnotations(annotations),
 	}
 
 	if !entityID.IsEmpty() {
-		sharedOpts = append(sharedOpts, protocol.WithEntity(entity.New(entity.Key(dataSet.Entity.Name), entityID)))
+		opts = append(opts, protocol.WithEntity(entity.New(entity.Key(dataSet.Entity.Name), entityID)))
 	}
 
 	u := metadata.ExecutorConfig.User
 	if u != "" {
-		sharedOpts = append(sharedOpts, protocol.WithIntegrationUser(u))
+		opts = append(opts, protocol.WithIntegrationUser(u))
 	}
 
-	for _, event := range dataSet.Events {
-		opts := append(sharedOpts, protocol.WithEvents(event))
-
-		attributesFromEvent(event, &opts)
+	opts = append(opts, protocol.WithEvents(event))
 
-		e, err := protocol.NewEventData(opts...)
-		if err != nil {
-			elog.WithFields(logrus.Fields{
-				"payload": event,
-				"error":   err,
-			}).Warn("discarding event, failed building event data.")
-			continue
-		}
+	attributesFromEvent(event, &opts)
 
-		emitter.EmitEvent(e, entity.Key(dataSet.Entity.Name))
-	}
+	return protocol.NewEventData(opts...)
 }
 
 func attributesFromEvent(event protocol.EventData, builder *[]func(protocol.EventData)) {
@@ -0,0 +1,139 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ValidationError describes an item of a protocol v4 payload that doesn't conform to the protocol types, and is
+// rejected by the agent.
+type ValidationError struct {
+	// Path locates the rejected item in the payload, e.g. "data[0].metrics[2]".
+	Path   string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Reason
+}
+
+// ValidatePayloadV4 checks a protocol v4 payload against the protocol types, returning an error for each rejected
+// item: the whole payload, a dataset, a metric or an event. Only the first issue of each item is reported.
+func ValidatePayloadV4(raw []byte) []ValidationError {
+	var data DataV4
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return []ValidationError{{Path: "payload", Reason: "invalid JSON: " + err.Error()}}
+	}
+
+	if data.Integration.Name == "" {
+		return []ValidationError{{Path: "integration", Reason: "missing required 'name' field"}}
+	}
+
+	var errs []ValidationError
+	for i, ds := range data.DataSets {
+		path := fmt.Sprintf("data[%d]", i)
+		if reason := validateDataset(ds); reason != "" {
+			errs = append(errs, ValidationError{Path: path, Reason: reason})
+			continue
+		}
+		for j, metric := range ds.Metrics {
+			if reason := validateMetric(metric); reason != "" {
+				errs = append(errs, ValidationError{Path: fmt.Sprintf("%s.metrics[%d]", path, j), Reason: reason})
+			}
+		}
+		for j, event := range ds.Events {
+			if reason := validateEvent(event); reason != "" {
+				errs = append(errs, ValidationError{Path: fmt.Sprintf("%s.events[%d]", path, j), Reason: reason})
+			}
+		}
+	}
+	return errs
+}
+
+// validateDataset returns the reason why the dataset is rejected, or an empty string if it's valid.
+func validateDataset(ds Dataset) string {
+	// entities without name refer to the agent entity
+	if !ds.IgnoreEntity && ds.Entity.Name != "" && ds.Entity.Type == "" {
+		return fmt.Sprintf("missing required 'entity.type' field for entity %q", ds.Entity.Name)
+	}
+	return ""
+}
+
+// validateMetric returns the reason why the metric is rejected, or an empty string if it's valid.
+func validateMetric(m Metric) string {
+	if m.Name == "" {
+		return "missing required 'name' field"
+	}
+	if len(m.Value) == 0 {
+		return fmt.Sprintf("metric %q: missing required 'value' field", m.Name)
+	}
+
+	var reason string
+	switch m.Type {
+	case MetricTypeGauge, MetricTypeCount, MetricTypeRate, "cumulative-rate", "cumulative-count":
+		if _, err := m.NumericValue(); err != nil {
+			reason = "value must be a number"
+		}
+	case MetricTypeSummary:
+		reason = validateSummary(m.Value)
+	case MetricTypePrometheusSummary:
+		if _, err := m.GetPrometheusSummaryValue(); err != nil {
+			reason = "value must be an object with 'sample_count', 'sample_sum' and 'quantiles' fields"
+		}
+	case MetricTypePrometheusHistogram:
+		reason = validateHistogram(m)
+	case "":
+		reason = "missing required 'type' field"
+	default:
+		reason = fmt.Sprintf("unknown metric type %q", m.Type)
+	}
+	if reason != "" {
+		return fmt.Sprintf("metric %q: %s", m.Name, reason)
+	}
+	return ""
+}
+
+func validateSummary(raw json.RawMessage) string {
+	var fields map[string]*float64
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return "value must be an object with numeric 'count', 'sum', 'min' and 'max' fields"
+	}
+	for _, field := range []string{"count", "sum", "min", "max"} {
+		if fields[field] == nil {
+			return fmt.Sprintf("missing required 'value.%s' field", field)
+		}
+	}
+	return ""
+}
+
+func validateHistogram(m Metric) string {
+	value, err := m.GetPrometheusHistogramValue()
+	if err != nil {
+		return "value must be an object with 'sample_count', 'sample_sum' and 'buckets' fields"
+	}
+	if value.SampleCount == nil {
+		return "missing required 'value.sample_count' field"
+	}
+	for i, b := range value.Buckets {
+		if b == nil || b.CumulativeCount == nil || b.UpperBound == nil {
+			return fmt.Sprintf("bucket %d requires 'cumulative_count' and 'upper_bound' fields", i)
+		}
+	}
+	return ""
+}
+
+// validateEvent returns the reason why the event is rejected, or an empty string if it's valid.
+func validateEvent(e EventData) string {
+	if summary, ok := e["summary"].(string); !ok || summary == "" {
+		return "missing required 'summary' field"
+	}
+	if attrs, ok := e["attributes"]; ok {
+		// attributes that aren't an object are discarded by the emitter
+		if _, ok := attrs.(map[string]interface{}); !ok {
+			return "'attributes' must be an object"
+		}
+	}
+	return ""
+}
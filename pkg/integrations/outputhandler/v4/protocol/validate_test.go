@@ -0,0 +1,85 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePayloadV4(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected []string
+	}{
+		{
+			name: "valid",
+			payload: `{"protocol_version":"4","integration":{"name":"nri-test","version":"1.0"},"data":[{
+				"entity":{"name":"redis:6379","type":"RedisInstance"},
+				"common":{"attributes":{"env":"prod"}},
+				"metrics":[
+					{"name":"connections","type":"gauge","value":3,"attributes":{"db":"0","primary":true,"tags":["a","b"],"owner":null}},
+					{"name":"latency","type":"summary","value":{"count":1,"sum":2,"min":2,"max":2}},
+					{"name":"requests","type":"prometheus-histogram","value":{"sample_count":2,"sample_sum":3,"buckets":[{"cumulative_count":2,"upper_bound":5}]}},
+					{"name":"response","type":"prometheus-summary","value":{"sample_count":2,"sample_sum":3,"quantiles":[{"quantile":0.5,"value":1}]}}
+				],
+				"events":[{"summary":"restarted","attributes":{"reason":"upgrade"}}]
+			},{"ignore_entity":true,"metrics":[{"name":"uptime","type":"cumulative-count","value":10}]}]}`,
+		},
+		{
+			name:     "invalid JSON",
+			payload:  `{"protocol_version":"4",`,
+			expected: []string{"payload: invalid JSON: unexpected end of JSON input"},
+		},
+		{
+			name:     "missing integration name",
+			payload:  `{"protocol_version":"4","integration":{"version":"1.0"},"data":[]}`,
+			expected: []string{"integration: missing required 'name' field"},
+		},
+		{
+			name:     "entity without type",
+			payload:  `{"protocol_version":"4","integration":{"name":"nri-test"},"data":[{"entity":{"name":"redis:6379"},"metrics":[{"name":"foo"}]}]}`,
+			expected: []string{`data[0]: missing required 'entity.type' field for entity "redis:6379"`},
+		},
+		{
+			name: "invalid metrics",
+			payload: `{"protocol_version":"4","integration":{"name":"nri-test"},"data":[{"metrics":[
+				{"type":"gauge","value":1},
+				{"name":"a","value":1},
+				{"name":"b","type":"histogram","value":1},
+				{"name":"c","type":"gauge","value":"high"},
+				{"name":"d","type":"gauge"},
+				{"name":"e","type":"summary","value":{"count":1,"sum":2}},
+				{"name":"f","type":"prometheus-histogram","value":{"sample_sum":3}},
+				{"name":"g","type":"prometheus-histogram","value":{"sample_count":2,"buckets":[{"upper_bound":5}]}},
+				{"name":"ok","type":"count","value":1}
+			]}]}`,
+			expected: []string{
+				"data[0].metrics[0]: missing required 'name' field",
+				`data[0].metrics[1]: metric "a": missing required 'type' field`,
+				`data[0].metrics[2]: metric "b": unknown metric type "histogram"`,
+				`data[0].metrics[3]: metric "c": value must be a number`,
+				`data[0].metrics[4]: metric "d": missing required 'value' field`,
+				`data[0].metrics[5]: metric "e": missing required 'value.min' field`,
+				`data[0].metrics[6]: metric "f": missing required 'value.sample_count' field`,
+				`data[0].metrics[7]: metric "g": bucket 0 requires 'cumulative_count' and 'upper_bound' fields`,
+			},
+		},
+		{
+			name:     "invalid events",
+			payload:  `{"protocol_version":"4","integration":{"name":"nri-test"},"data":[{"events":[{"category":"notifications"},{"summary":"foo","attributes":"bar"}]}]}`,
+			expected: []string{"data[0].events[0]: missing required 'summary' field", "data[0].events[1]: 'attributes' must be an object"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []string
+			for _, err := range ValidatePayloadV4([]byte(tt.payload)) {
+				errs = append(errs, err.Error())
+			}
+			assert.Equal(t, tt.expected, errs)
+		})
+	}
+}
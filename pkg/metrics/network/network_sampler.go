@@ -42,6 +42,9 @@ type NetworkSample struct {
 	TransmitPacketsPerSec *float64 `json:"transmitPacketsPerSecond,omitempty"`
 	TransmitErrorsPerSec  *float64 `json:"transmitErrorsPerSecond,omitempty"`
 	TransmitDroppedPerSec *float64 `json:"transmitDroppedPerSecond,omitempty"`
+
+	// host-wide TCP/UDP counters, only reported on Linux
+	*ProtocolSample
 }
 
 func NewNetworkSampler(context agent.AgentContext) *NetworkSampler {
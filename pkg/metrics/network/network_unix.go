@@ -25,6 +25,7 @@ type NetworkSampler struct {
 	context         agent.AgentContext
 	lastRun         time.Time
 	lastNetStats    map[string]net.IOCountersStat
+	lastProtocol    protocolCounters
 	hasBootstrapped bool
 	stopChannel     chan bool
 	waitForCleanup  *sync.WaitGroup
@@ -151,6 +152,16 @@ func (ss *NetworkSampler) Sample() (results sample.EventBatch, err error) {
 	}
 	ss.lastNetStats = nextNetStats
 
+	protocol, err := readProtocolCounters()
+	if err != nil {
+		nslog.WithError(err).Debug("Cannot read network protocol counters.")
+	} else if protocol != nil {
+		if sample := protocolCountersSample(results); sample != nil && ss.lastProtocol != nil {
+			sample.ProtocolSample = newProtocolSample(protocol, ss.lastProtocol, elapsedSeconds)
+		}
+		ss.lastProtocol = protocol
+	}
+
 	if ss.Debug() {
 		for _, sample := range results {
 			helpers.LogStructureDetails(nslog, sample.(*NetworkSample), "NetworkSample", "final", nil)
@@ -0,0 +1,137 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package network
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/acquire"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// ProtocolSample contains the host-wide TCP/UDP protocol counters as rates. They aren't bound to any interface, so
+// they are reported once per sampling cycle, by the NetworkSample returned by protocolCountersSample.
+type ProtocolSample struct {
+	TCPActiveOpensPerSec     *float64 `json:"tcpActiveOpensPerSecond,omitempty"`
+	TCPPassiveOpensPerSec    *float64 `json:"tcpPassiveOpensPerSecond,omitempty"`
+	TCPAttemptFailsPerSec    *float64 `json:"tcpAttemptFailsPerSecond,omitempty"`
+	TCPEstabResetsPerSec     *float64 `json:"tcpEstablishedResetsPerSecond,omitempty"`
+	TCPCurrentEstablished    *float64 `json:"tcpCurrentEstablished,omitempty"`
+	TCPInSegmentsPerSec      *float64 `json:"tcpReceiveSegmentsPerSecond,omitempty"`
+	TCPOutSegmentsPerSec     *float64 `json:"tcpTransmitSegmentsPerSecond,omitempty"`
+	TCPRetransSegmentsPerSec *float64 `json:"tcpRetransmitSegmentsPerSecond,omitempty"`
+	TCPInErrorsPerSec        *float64 `json:"tcpReceiveErrorsPerSecond,omitempty"`
+	TCPOutResetsPerSec       *float64 `json:"tcpTransmitResetsPerSecond,omitempty"`
+	TCPListenOverflowsPerSec *float64 `json:"tcpListenOverflowsPerSecond,omitempty"`
+	TCPListenDropsPerSec     *float64 `json:"tcpListenDropsPerSecond,omitempty"`
+
+	UDPInDatagramsPerSec  *float64 `json:"udpReceiveDatagramsPerSecond,omitempty"`
+	UDPOutDatagramsPerSec *float64 `json:"udpTransmitDatagramsPerSecond,omitempty"`
+	UDPNoPortsPerSec      *float64 `json:"udpNoPortsPerSecond,omitempty"`
+	UDPInErrorsPerSec     *float64 `json:"udpReceiveErrorsPerSecond,omitempty"`
+	UDPRcvbufErrorsPerSec *float64 `json:"udpReceiveBufferErrorsPerSecond,omitempty"`
+	UDPSndbufErrorsPerSec *float64 `json:"udpTransmitBufferErrorsPerSecond,omitempty"`
+}
+
+// protocolCounters maps the counters read from /proc/net/{snmp,netstat} by "<Protocol>.<Counter>" names, e.g.
+// "Tcp.RetransSegs".
+type protocolCounters map[string]uint64
+
+// parseProtocolCounters reads the counters from a /proc/net/snmp or /proc/net/netstat file, where each protocol
+// is described by a header line followed by a values line:
+//
+//	Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens ...
+//	Tcp: 1 200 120000 -1 274 189 ...
+//
+// Counters that aren't unsigned integers (e.g. MaxConn, which can be -1) are ignored.
+func parseProtocolCounters(r io.Reader, counters protocolCounters) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		header := strings.Fields(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		values := strings.Fields(scanner.Text())
+		if len(header) == 0 || len(header) != len(values) || header[0] != values[0] {
+			return fmt.Errorf("mismatching protocol counters header and values: %q, %q", header, values)
+		}
+
+		protocol := strings.TrimSuffix(header[0], ":")
+		for i := 1; i < len(header); i++ {
+			value, err := strconv.ParseUint(values[i], 10, 64)
+			if err != nil {
+				continue
+			}
+			counters[protocol+"."+header[i]] = value
+		}
+	}
+	return scanner.Err()
+}
+
+// newProtocolSample calculates the rates of the protocol counters since the previous sampling cycle. Counters
+// missing in any of both cycles are not reported.
+func newProtocolSample(current, last protocolCounters, elapsedSeconds float64) *ProtocolSample {
+	rate := func(name string) *float64 {
+		currentValue, ok := current[name]
+		if !ok {
+			return nil
+		}
+		lastValue, ok := last[name]
+		if !ok {
+			return nil
+		}
+		delta := acquire.CalculateSafeDelta(currentValue, lastValue, elapsedSeconds)
+		return &delta
+	}
+
+	s := &ProtocolSample{
+		TCPActiveOpensPerSec:     rate("Tcp.ActiveOpens"),
+		TCPPassiveOpensPerSec:    rate("Tcp.PassiveOpens"),
+		TCPAttemptFailsPerSec:    rate("Tcp.AttemptFails"),
+		TCPEstabResetsPerSec:     rate("Tcp.EstabResets"),
+		TCPInSegmentsPerSec:      rate("Tcp.InSegs"),
+		TCPOutSegmentsPerSec:     rate("Tcp.OutSegs"),
+		TCPRetransSegmentsPerSec: rate("Tcp.RetransSegs"),
+		TCPInErrorsPerSec:        rate("Tcp.InErrs"),
+		TCPOutResetsPerSec:       rate("Tcp.OutRsts"),
+		TCPListenOverflowsPerSec: rate("TcpExt.ListenOverflows"),
+		TCPListenDropsPerSec:     rate("TcpExt.ListenDrops"),
+
+		UDPInDatagramsPerSec:  rate("Udp.InDatagrams"),
+		UDPOutDatagramsPerSec: rate("Udp.OutDatagrams"),
+		UDPNoPortsPerSec:      rate("Udp.NoPorts"),
+		UDPInErrorsPerSec:     rate("Udp.InErrors"),
+		UDPRcvbufErrorsPerSec: rate("Udp.RcvbufErrors"),
+		UDPSndbufErrorsPerSec: rate("Udp.SndbufErrors"),
+	}
+	// CurrEstab is a gauge
+	if established, ok := current["Tcp.CurrEstab"]; ok {
+		value := float64(established)
+		s.TCPCurrentEstablished = &value
+	}
+	return s
+}
+
+// protocolCountersSample returns the NetworkSample reporting the protocol counters of the sampling cycle: the first
+// interface that is up, or the first interface if none is, so the host-wide counters aren't summed once per
+// interface.
+func protocolCountersSample(samples sample.EventBatch) *NetworkSample {
+	var first *NetworkSample
+	for _, s := range samples {
+		networkSample, ok := s.(*NetworkSample)
+		if !ok {
+			continue
+		}
+		if networkSample.State == STATE_UP {
+			return networkSample
+		}
+		if first == nil {
+			first = networkSample
+		}
+	}
+	return first
+}
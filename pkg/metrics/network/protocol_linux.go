@@ -0,0 +1,27 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package network
+
+import (
+	"os"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// readProtocolCounters reads the TCP/UDP counters of the host network namespace. The files are read through
+// the host init process, as /proc/net would resolve to the agent network namespace when running in a container.
+func readProtocolCounters() (protocolCounters, error) {
+	counters := protocolCounters{}
+	for _, file := range []string{"snmp", "netstat"} {
+		f, err := os.Open(helpers.HostProc("1", "net", file))
+		if err != nil {
+			return nil, err
+		}
+		err = parseProtocolCounters(f, counters)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counters, nil
+}
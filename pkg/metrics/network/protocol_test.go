@@ -0,0 +1,104 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package network
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const snmpContent = `Ip: Forwarding DefaultTTL InReceives
+Ip: 2 64 11798
+Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 274 189 18 143 4 11740 12348 30 2 92 0
+Udp: InDatagrams NoPorts InErrors OutDatagrams RcvbufErrors SndbufErrors InCsumErrors IgnoredMulti MemErrors
+Udp: 58 1 3 60 2 0 0 0 0
+`
+
+const netstatContent = `TcpExt: SyncookiesSent ListenOverflows ListenDrops
+TcpExt: 0 10 12
+IpExt: InNoRoutes InTruncatedPkts
+IpExt: 0 0
+`
+
+func TestParseProtocolCounters(t *testing.T) {
+	counters := protocolCounters{}
+	require.NoError(t, parseProtocolCounters(strings.NewReader(snmpContent), counters))
+	require.NoError(t, parseProtocolCounters(strings.NewReader(netstatContent), counters))
+
+	assert.Equal(t, uint64(30), counters["Tcp.RetransSegs"])
+	assert.Equal(t, uint64(92), counters["Tcp.OutRsts"])
+	assert.Equal(t, uint64(2), counters["Udp.RcvbufErrors"])
+	assert.Equal(t, uint64(10), counters["TcpExt.ListenOverflows"])
+	assert.Equal(t, uint64(11798), counters["Ip.InReceives"])
+	assert.NotContains(t, counters, "Tcp.MaxConn")
+}
+
+func TestParseProtocolCounters_MismatchingLines(t *testing.T) {
+	err := parseProtocolCounters(strings.NewReader("Tcp: ActiveOpens PassiveOpens\nTcp: 1\n"), protocolCounters{})
+
+	assert.Error(t, err)
+}
+
+func TestNewProtocolSample(t *testing.T) {
+	last := protocolCounters{
+		"Tcp.RetransSegs":        100,
+		"Tcp.OutRsts":            10,
+		"TcpExt.ListenOverflows": 5,
+		"Udp.RcvbufErrors":       0,
+	}
+	current := protocolCounters{
+		"Tcp.RetransSegs":        120,
+		"Tcp.OutRsts":            10,
+		"Tcp.CurrEstab":          7,
+		"TcpExt.ListenOverflows": 15,
+		"Udp.RcvbufErrors":       4,
+		"Udp.NoPorts":            3,
+	}
+
+	s := newProtocolSample(current, last, 2)
+
+	assert.Equal(t, 10.0, *s.TCPRetransSegmentsPerSec)
+	assert.Equal(t, 0.0, *s.TCPOutResetsPerSec)
+	assert.Equal(t, 5.0, *s.TCPListenOverflowsPerSec)
+	assert.Equal(t, 2.0, *s.UDPRcvbufErrorsPerSec)
+	assert.Equal(t, 7.0, *s.TCPCurrentEstablished)
+	// missing in the previous cycle
+	assert.Nil(t, s.UDPNoPortsPerSec)
+	// missing in the current cycle
+	assert.Nil(t, s.TCPActiveOpensPerSec)
+}
+
+func TestNetworkSample_ProtocolCounters(t *testing.T) {
+	retrans := 10.0
+	s := &NetworkSample{InterfaceName: "eth0", ProtocolSample: &ProtocolSample{TCPRetransSegmentsPerSec: &retrans}}
+	s.Type("NetworkSample")
+
+	serialized, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.Contains(t, string(serialized), `"eventType":"NetworkSample"`)
+	assert.Contains(t, string(serialized), `"tcpRetransmitSegmentsPerSecond":10`)
+	assert.NotContains(t, string(serialized), `udpNoPortsPerSecond`)
+
+	serialized, err = json.Marshal(&NetworkSample{InterfaceName: "eth0"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(serialized), `tcpRetransmitSegmentsPerSecond`)
+}
+
+func TestProtocolCountersSample(t *testing.T) {
+	lo := &NetworkSample{InterfaceName: "lo", State: STATE_DOWN}
+	eth0 := &NetworkSample{InterfaceName: "eth0", State: STATE_UP}
+	eth1 := &NetworkSample{InterfaceName: "eth1", State: STATE_UP}
+
+	// the counters are reported by the first interface that is up
+	assert.Same(t, eth0, protocolCountersSample(sample.EventBatch{lo, eth0, eth1}))
+	// or by the first one if none is up
+	assert.Same(t, lo, protocolCountersSample(sample.EventBatch{lo}))
+	assert.Nil(t, protocolCountersSample(sample.EventBatch{}))
+}
@@ -0,0 +1,258 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PressureSample contains the Pressure Stall Information (PSI) of the host. "some" metrics report the share of time
+// in which at least one task was stalled on the resource, "full" metrics the share of time in which all non-idle
+// tasks were stalled simultaneously. Averages are percentages over the last 10, 60 and 300 seconds, totals are
+// the accumulated stall time in microseconds.
+// Pointers are used as nil values represent no data, e.g. CPU "full" metrics are not available on kernels prior
+// to 5.13.
+type PressureSample struct {
+	CPUSomeAvg10     *float64 `json:"cpuPressureSomeAvg10,omitempty"`
+	CPUSomeAvg60     *float64 `json:"cpuPressureSomeAvg60,omitempty"`
+	CPUSomeAvg300    *float64 `json:"cpuPressureSomeAvg300,omitempty"`
+	CPUSomeTotal     *uint64  `json:"cpuPressureSomeTotalUsec,omitempty"`
+	CPUFullAvg10     *float64 `json:"cpuPressureFullAvg10,omitempty"`
+	CPUFullAvg60     *float64 `json:"cpuPressureFullAvg60,omitempty"`
+	CPUFullAvg300    *float64 `json:"cpuPressureFullAvg300,omitempty"`
+	CPUFullTotal     *uint64  `json:"cpuPressureFullTotalUsec,omitempty"`
+	MemorySomeAvg10  *float64 `json:"memoryPressureSomeAvg10,omitempty"`
+	MemorySomeAvg60  *float64 `json:"memoryPressureSomeAvg60,omitempty"`
+	MemorySomeAvg300 *float64 `json:"memoryPressureSomeAvg300,omitempty"`
+	MemorySomeTotal  *uint64  `json:"memoryPressureSomeTotalUsec,omitempty"`
+	MemoryFullAvg10  *float64 `json:"memoryPressureFullAvg10,omitempty"`
+	MemoryFullAvg60  *float64 `json:"memoryPressureFullAvg60,omitempty"`
+	MemoryFullAvg300 *float64 `json:"memoryPressureFullAvg300,omitempty"`
+	MemoryFullTotal  *uint64  `json:"memoryPressureFullTotalUsec,omitempty"`
+	IOSomeAvg10      *float64 `json:"ioPressureSomeAvg10,omitempty"`
+	IOSomeAvg60      *float64 `json:"ioPressureSomeAvg60,omitempty"`
+	IOSomeAvg300     *float64 `json:"ioPressureSomeAvg300,omitempty"`
+	IOSomeTotal      *uint64  `json:"ioPressureSomeTotalUsec,omitempty"`
+	IOFullAvg10      *float64 `json:"ioPressureFullAvg10,omitempty"`
+	IOFullAvg60      *float64 `json:"ioPressureFullAvg60,omitempty"`
+	IOFullAvg300     *float64 `json:"ioPressureFullAvg300,omitempty"`
+	IOFullTotal      *uint64  `json:"ioPressureFullTotalUsec,omitempty"`
+}
+
+// CgroupSample contains the cgroup v2 cpu.stat and memory.stat statistics of the monitored cgroup, identified by its
+// path relative to the cgroup v2 mount point. A single cgroup is reported, not one per container. memory.current and
+// memory.max aren't reported, as the root cgroup monitored by default has none. Pointers are used as nil values
+// represent no data.
+type CgroupSample struct {
+	Path                  string  `json:"cgroupPath,omitempty"`
+	CPUUsage              *uint64 `json:"cgroupCpuUsageUsec,omitempty"`
+	CPUUser               *uint64 `json:"cgroupCpuUserUsec,omitempty"`
+	CPUSystem             *uint64 `json:"cgroupCpuSystemUsec,omitempty"`
+	CPUPeriods            *uint64 `json:"cgroupCpuPeriods,omitempty"`
+	CPUThrottledPeriods   *uint64 `json:"cgroupCpuThrottledPeriods,omitempty"`
+	CPUThrottled          *uint64 `json:"cgroupCpuThrottledUsec,omitempty"`
+	MemoryAnon            *uint64 `json:"cgroupMemoryAnonBytes,omitempty"`
+	MemoryFile            *uint64 `json:"cgroupMemoryFileBytes,omitempty"`
+	MemoryKernelStack     *uint64 `json:"cgroupMemoryKernelStackBytes,omitempty"`
+	MemorySlab            *uint64 `json:"cgroupMemorySlabBytes,omitempty"`
+	MemorySock            *uint64 `json:"cgroupMemorySockBytes,omitempty"`
+	MemoryShmem           *uint64 `json:"cgroupMemoryShmemBytes,omitempty"`
+	MemoryFileDirty       *uint64 `json:"cgroupMemoryFileDirtyBytes,omitempty"`
+	MemoryFileWriteback   *uint64 `json:"cgroupMemoryFileWritebackBytes,omitempty"`
+	MemoryPageFaults      *uint64 `json:"cgroupMemoryPageFaults,omitempty"`
+	MemoryMajorPageFaults *uint64 `json:"cgroupMemoryMajorPageFaults,omitempty"`
+}
+
+// PressureMonitor samples the host Pressure Stall Information. It's disabled when pressureDir is empty.
+type PressureMonitor struct {
+	pressureDir string
+}
+
+// CgroupMonitor samples the cgroup v2 statistics of a cgroup. It's disabled when cgroupDir is empty.
+type CgroupMonitor struct {
+	cgroupDir  string
+	cgroupPath string
+}
+
+// Sample returns the Pressure Stall Information of the host, or nil if the kernel does not support it.
+func (m *PressureMonitor) Sample() (*PressureSample, error) {
+	if m.pressureDir == "" {
+		return nil, nil
+	}
+
+	sample := &PressureSample{}
+	found := false
+	for _, resource := range []string{"cpu", "memory", "io"} {
+		lines, err := readLines(filepath.Join(m.pressureDir, resource))
+		if err != nil {
+			// PSI is not available or has been disabled through the psi=0 boot parameter
+			continue
+		}
+		stats, err := parsePressure(lines)
+		if err != nil {
+			return nil, err
+		}
+		sample.setPressure(resource, stats)
+		found = true
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return sample, nil
+}
+
+// Sample returns the cgroup v2 statistics of the monitored cgroup, or nil when they are not available.
+func (m *CgroupMonitor) Sample() (*CgroupSample, error) {
+	if m.cgroupDir == "" {
+		return nil, nil
+	}
+
+	sample := &CgroupSample{Path: m.cgroupPath}
+	if lines, err := readLines(filepath.Join(m.cgroupDir, "cpu.stat")); err == nil {
+		stat, err := parseFlatKeyed(lines)
+		if err != nil {
+			return nil, err
+		}
+		sample.setCPUStat(stat)
+	}
+	if lines, err := readLines(filepath.Join(m.cgroupDir, "memory.stat")); err == nil {
+		stat, err := parseFlatKeyed(lines)
+		if err != nil {
+			return nil, err
+		}
+		sample.setMemoryStat(stat)
+	}
+
+	return sample, nil
+}
+
+func readLines(path string) ([]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSpace(string(content)), "\n"), nil
+}
+
+// pressureStats contains a "some" or "full" line of a PSI file.
+type pressureStats struct {
+	avg10  float64
+	avg60  float64
+	avg300 float64
+	total  uint64
+}
+
+// parsePressure parses the content of a PSI file (/proc/pressure/{cpu,memory,io}), whose lines look like:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//
+// Returned stats are indexed by their kind ("some" or "full").
+func parsePressure(lines []string) (map[string]pressureStats, error) {
+	result := map[string]pressureStats{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var stats pressureStats
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid pressure field %q", field)
+			}
+			var err error
+			switch kv[0] {
+			case "avg10":
+				stats.avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				stats.avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				stats.avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				stats.total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid pressure field %q: %v", field, err)
+			}
+		}
+		result[fields[0]] = stats
+	}
+	return result, nil
+}
+
+// setPressure stores the stats of the given resource ("cpu", "memory" or "io") into the sample.
+func (s *PressureSample) setPressure(resource string, stats map[string]pressureStats) {
+	for kind, st := range stats {
+		avg10, avg60, avg300, total := st.avg10, st.avg60, st.avg300, st.total
+		switch resource + "/" + kind {
+		case "cpu/some":
+			s.CPUSomeAvg10, s.CPUSomeAvg60, s.CPUSomeAvg300, s.CPUSomeTotal = &avg10, &avg60, &avg300, &total
+		case "cpu/full":
+			s.CPUFullAvg10, s.CPUFullAvg60, s.CPUFullAvg300, s.CPUFullTotal = &avg10, &avg60, &avg300, &total
+		case "memory/some":
+			s.MemorySomeAvg10, s.MemorySomeAvg60, s.MemorySomeAvg300, s.MemorySomeTotal = &avg10, &avg60, &avg300, &total
+		case "memory/full":
+			s.MemoryFullAvg10, s.MemoryFullAvg60, s.MemoryFullAvg300, s.MemoryFullTotal = &avg10, &avg60, &avg300, &total
+		case "io/some":
+			s.IOSomeAvg10, s.IOSomeAvg60, s.IOSomeAvg300, s.IOSomeTotal = &avg10, &avg60, &avg300, &total
+		case "io/full":
+			s.IOFullAvg10, s.IOFullAvg60, s.IOFullAvg300, s.IOFullTotal = &avg10, &avg60, &avg300, &total
+		}
+	}
+}
+
+// parseFlatKeyed parses the content of a flat keyed cgroup file, like cpu.stat or memory.stat, whose lines are
+// "<key> <value>" pairs.
+func parseFlatKeyed(lines []string) (map[string]uint64, error) {
+	result := map[string]uint64{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %q: %v", fields[0], err)
+		}
+		result[fields[0]] = value
+	}
+	return result, nil
+}
+
+// setCPUStat stores the cgroup cpu.stat values into the sample.
+func (s *CgroupSample) setCPUStat(stat map[string]uint64) {
+	s.CPUUsage = statValue(stat, "usage_usec")
+	s.CPUUser = statValue(stat, "user_usec")
+	s.CPUSystem = statValue(stat, "system_usec")
+	s.CPUPeriods = statValue(stat, "nr_periods")
+	s.CPUThrottledPeriods = statValue(stat, "nr_throttled")
+	s.CPUThrottled = statValue(stat, "throttled_usec")
+}
+
+// setMemoryStat stores the cgroup memory.stat values into the sample.
+func (s *CgroupSample) setMemoryStat(stat map[string]uint64) {
+	s.MemoryAnon = statValue(stat, "anon")
+	s.MemoryFile = statValue(stat, "file")
+	s.MemoryKernelStack = statValue(stat, "kernel_stack")
+	s.MemorySlab = statValue(stat, "slab")
+	s.MemorySock = statValue(stat, "sock")
+	s.MemoryShmem = statValue(stat, "shmem")
+	s.MemoryFileDirty = statValue(stat, "file_dirty")
+	s.MemoryFileWriteback = statValue(stat, "file_writeback")
+	s.MemoryPageFaults = statValue(stat, "pgfault")
+	s.MemoryMajorPageFaults = statValue(stat, "pgmajfault")
+}
+
+func statValue(stat map[string]uint64, key string) *uint64 {
+	value, ok := stat[key]
+	if !ok {
+		return nil
+	}
+	return &value
+}
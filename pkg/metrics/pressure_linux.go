@@ -0,0 +1,35 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// NewPressureMonitor returns a monitor of the host Pressure Stall Information.
+func NewPressureMonitor() *PressureMonitor {
+	return &PressureMonitor{pressureDir: helpers.HostProc("pressure")}
+}
+
+// NewCgroupMonitor returns a monitor of the cgroup v2 statistics of the given cgroup path, relative to the host
+// cgroup v2 mount point. The root cgroup, which accounts for the whole host workload, is monitored when the path is
+// empty. The agent own cgroup is not used, as it would only report the usage of the agent container. The monitor is
+// disabled when the agent is not containerized or the host does not use cgroup v2.
+func NewCgroupMonitor(isContainerized bool, cgroupPath string) *CgroupMonitor {
+	if !isContainerized {
+		return &CgroupMonitor{}
+	}
+
+	cgroupRoot := helpers.HostSys("fs", "cgroup")
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		syslog.WithError(err).Debug("cgroup v2 not available, container cgroup metrics won't be reported.")
+		return &CgroupMonitor{}
+	}
+
+	cgroupPath = path.Clean("/" + cgroupPath)
+	return &CgroupMonitor{cgroupDir: filepath.Join(cgroupRoot, cgroupPath), cgroupPath: cgroupPath}
+}
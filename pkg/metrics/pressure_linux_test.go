@@ -0,0 +1,41 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCgroupMonitor_NotContainerized(t *testing.T) {
+	assert.Empty(t, NewCgroupMonitor(false, "").cgroupDir)
+}
+
+func TestNewCgroupMonitor_ReadsHostCgroup(t *testing.T) {
+	hostSys := t.TempDir()
+	cgroupRoot := filepath.Join(hostSys, "fs", "cgroup")
+	require.NoError(t, os.MkdirAll(cgroupRoot, 0755))
+	writeFiles(t, cgroupRoot, map[string]string{"cgroup.controllers": "cpu memory io\n"})
+	require.NoError(t, os.Setenv("HOST_SYS", hostSys))
+	defer os.Unsetenv("HOST_SYS")
+
+	// the root cgroup by default
+	m := NewCgroupMonitor(true, "")
+	assert.Equal(t, cgroupRoot, m.cgroupDir)
+	assert.Equal(t, "/", m.cgroupPath)
+
+	m = NewCgroupMonitor(true, "system.slice/docker-abc.scope/")
+	assert.Equal(t, filepath.Join(cgroupRoot, "system.slice", "docker-abc.scope"), m.cgroupDir)
+	assert.Equal(t, "/system.slice/docker-abc.scope", m.cgroupPath)
+}
+
+func TestNewCgroupMonitor_CgroupV2NotAvailable(t *testing.T) {
+	require.NoError(t, os.Setenv("HOST_SYS", t.TempDir()))
+	defer os.Unsetenv("HOST_SYS")
+
+	assert.Empty(t, NewCgroupMonitor(true, "").cgroupDir)
+}
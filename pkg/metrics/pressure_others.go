@@ -0,0 +1,16 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !linux
+// +build !linux
+
+package metrics
+
+// NewPressureMonitor returns a disabled monitor, as Pressure Stall Information is only available on Linux.
+func NewPressureMonitor() *PressureMonitor {
+	return &PressureMonitor{}
+}
+
+// NewCgroupMonitor returns a disabled monitor, as cgroups are only available on Linux.
+func NewCgroupMonitor(_ bool, _ string) *CgroupMonitor {
+	return &CgroupMonitor{}
+}
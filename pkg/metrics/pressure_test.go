@@ -0,0 +1,96 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestPressureMonitor_Sample(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pressure")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		// CPU "full" line not reported by kernels prior to 5.13
+		"cpu":    "some avg10=1.50 avg60=0.75 avg300=0.10 total=123456\n",
+		"memory": "some avg10=0.00 avg60=0.00 avg300=0.00 total=10\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=5\n",
+		"io":     "some avg10=12.00 avg60=6.00 avg300=3.00 total=999\nfull avg10=10.00 avg60=5.00 avg300=2.50 total=888\n",
+	})
+
+	sample, err := (&PressureMonitor{pressureDir: dir}).Sample()
+	require.NoError(t, err)
+	require.NotNil(t, sample)
+
+	assert.Equal(t, 1.5, *sample.CPUSomeAvg10)
+	assert.Equal(t, 0.75, *sample.CPUSomeAvg60)
+	assert.Equal(t, 0.1, *sample.CPUSomeAvg300)
+	assert.Equal(t, uint64(123456), *sample.CPUSomeTotal)
+	assert.Nil(t, sample.CPUFullAvg10)
+	assert.Equal(t, uint64(5), *sample.MemoryFullTotal)
+	assert.Equal(t, 2.5, *sample.IOFullAvg300)
+	assert.Equal(t, uint64(999), *sample.IOSomeTotal)
+
+	serialized, err := json.Marshal(SystemSample{PressureSample: sample})
+	require.NoError(t, err)
+	assert.Contains(t, string(serialized), `"ioPressureFullAvg10":10`)
+	assert.NotContains(t, string(serialized), `cpuPressureFull`)
+}
+
+func TestPressureMonitor_NotAvailable(t *testing.T) {
+	sample, err := (&PressureMonitor{pressureDir: "/non/existing/path"}).Sample()
+	assert.NoError(t, err)
+	assert.Nil(t, sample)
+
+	sample, err = (&PressureMonitor{}).Sample()
+	assert.NoError(t, err)
+	assert.Nil(t, sample)
+}
+
+func TestParsePressure_Invalid(t *testing.T) {
+	_, err := parsePressure([]string{"some avg10=1.50 avg60 avg300=0.10 total=123456"})
+	assert.Error(t, err)
+
+	_, err = parsePressure([]string{"some avg10=abc avg60=0.75 avg300=0.10 total=123456"})
+	assert.Error(t, err)
+}
+
+func TestCgroupMonitor_Sample(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"cpu.stat":    "usage_usec 1000\nuser_usec 600\nsystem_usec 400\nnr_periods 10\nnr_throttled 2\nthrottled_usec 50\n",
+		"memory.stat": "anon 4096\nfile 8192\nkernel_stack 16384\nslab 1024\nsock 0\nshmem 0\nfile_dirty 12\nfile_writeback 0\npgfault 300\npgmajfault 3\n",
+	})
+
+	sample, err := (&CgroupMonitor{cgroupDir: dir}).Sample()
+	require.NoError(t, err)
+	require.NotNil(t, sample)
+
+	assert.Equal(t, uint64(1000), *sample.CPUUsage)
+	assert.Equal(t, uint64(2), *sample.CPUThrottledPeriods)
+	assert.Equal(t, uint64(50), *sample.CPUThrottled)
+	assert.Equal(t, uint64(4096), *sample.MemoryAnon)
+	assert.Equal(t, uint64(3), *sample.MemoryMajorPageFaults)
+}
+
+func TestCgroupMonitor_Disabled(t *testing.T) {
+	sample, err := (&CgroupMonitor{}).Sample()
+	assert.NoError(t, err)
+	assert.Nil(t, sample)
+}
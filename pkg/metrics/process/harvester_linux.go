@@ -26,12 +26,19 @@ func newHarvester(ctx agent.AgentContext, cache *cache) *linuxHarvester {
 	disableZeroRSSFilter := cfg != nil && cfg.DisableZeroRSSFilter
 	stripCommandLine := (cfg != nil && cfg.StripCommandLine) || (cfg == nil && config.DefaultStripCommandLine)
 
+	var sockets *socketsCache
+	// sockets are mapped to processes through their file descriptors, which requires privileges
+	if privileged && cfg != nil && cfg.EnableProcessNetworkMetrics {
+		sockets = newSocketsCache()
+	}
+
 	return &linuxHarvester{
 		privileged:           privileged,
 		disableZeroRSSFilter: disableZeroRSSFilter,
 		stripCommandLine:     stripCommandLine,
 		serviceForPid:        ctx.GetServiceForPid,
 		cache:                cache,
+		sockets:              sockets,
 	}
 }
 
@@ -42,12 +49,18 @@ type linuxHarvester struct {
 	stripCommandLine     bool
 	cache                *cache
 	serviceForPid        func(int) (string, bool)
+	// sockets is nil when the process network metrics are disabled
+	sockets *socketsCache
 }
 
 var _ Harvester = (*linuxHarvester)(nil) // static interface assertion
 
-// Pids returns a slice of process IDs that are running now
-func (*linuxHarvester) Pids() ([]int32, error) {
+// Pids returns a slice of process IDs that are running now. As it's invoked at the beginning of each sampling cycle,
+// it also discards the sockets read in the previous cycle.
+func (ps *linuxHarvester) Pids() ([]int32, error) {
+	if ps.sockets != nil {
+		ps.sockets.reset()
+	}
 	return process.Pids()
 }
 
@@ -93,6 +106,12 @@ func (ps *linuxHarvester) Do(pid int32, elapsedSeconds float64) (*types.ProcessS
 		return nil, errors.Wrap(err, "can't fetch deltas")
 	}
 
+	if ps.sockets != nil {
+		if err := ps.sockets.populate(sample); err != nil {
+			mplog.WithError(err).WithField("processID", pid).Debug("Can't get network data for process.")
+		}
+	}
+
 	// This must happen every time, even if we already had a cached sample for the process, because
 	// the available process name metadata may have changed underneath us (if we pick up a new
 	// service/PID association, etc)
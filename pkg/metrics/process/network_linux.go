@@ -0,0 +1,277 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package process
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+)
+
+// TCP states as reported in the "st" column of /proc/net/tcp, see include/net/tcp_states.h in the kernel sources.
+const (
+	tcpEstablished = 0x01
+	tcpSynSent     = 0x02
+	tcpSynRecv     = 0x03
+	tcpFinWait1    = 0x04
+	tcpFinWait2    = 0x05
+	tcpTimeWait    = 0x06
+	tcpClose       = 0x07
+	tcpCloseWait   = 0x08
+	tcpLastAck     = 0x09
+	tcpListen      = 0x0A
+	tcpClosing     = 0x0B
+)
+
+const (
+	protocolTCP = "tcp"
+	protocolUDP = "udp"
+)
+
+// socketTables lists the /proc/<pid>/net files the sockets are read from.
+var socketTables = []struct {
+	file     string
+	protocol string
+}{
+	{"tcp", protocolTCP},
+	{"tcp6", protocolTCP},
+	{"udp", protocolUDP},
+	{"udp6", protocolUDP},
+}
+
+type socketInfo struct {
+	protocol  string
+	localPort uint16
+	state     uint8
+	connected bool
+}
+
+// socketsByInode maps the sockets of a network namespace by their inode.
+type socketsByInode map[uint64]socketInfo
+
+// socketsCache stores the sockets of each network namespace, so /proc/<pid>/net files are read once per namespace
+// and sampling cycle. Sockets are mapped to processes through the "socket:[inode]" links in /proc/<pid>/fd.
+type socketsCache struct {
+	byNamespace map[string]socketsByInode
+}
+
+func newSocketsCache() *socketsCache {
+	return &socketsCache{byNamespace: map[string]socketsByInode{}}
+}
+
+// reset discards the sockets read in the previous sampling cycle.
+func (c *socketsCache) reset() {
+	c.byNamespace = map[string]socketsByInode{}
+}
+
+// populate fills the sample with the network statistics of the process.
+func (c *socketsCache) populate(sample *types.ProcessSample) error {
+	pid := strconv.Itoa(int(sample.ProcessID))
+
+	sockets, err := c.namespaceSockets(pid)
+	if err != nil {
+		return err
+	}
+
+	inodes, err := socketInodes(pid)
+	if err != nil {
+		return err
+	}
+
+	var udpCount, tcpCount int32
+	tcpStates := map[uint8]int32{}
+	listening := map[string]struct{}{}
+	for _, inode := range inodes {
+		socket, ok := sockets[inode]
+		if !ok {
+			continue
+		}
+		switch socket.protocol {
+		case protocolTCP:
+			tcpStates[socket.state]++
+			// listening sockets aren't connections
+			if socket.state == tcpListen {
+				listening[fmt.Sprintf("%s:%d", protocolTCP, socket.localPort)] = struct{}{}
+			} else {
+				tcpCount++
+			}
+		case protocolUDP:
+			udpCount++
+			if !socket.connected && socket.localPort != 0 {
+				listening[fmt.Sprintf("%s:%d", protocolUDP, socket.localPort)] = struct{}{}
+			}
+		}
+	}
+
+	ports := make([]string, 0, len(listening))
+	for port := range listening {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+
+	sample.ListeningPorts = strings.Join(ports, ",")
+	sample.UDPSocketCount = &udpCount
+	sample.TCPConnectionCount = &tcpCount
+	sample.TCPEstablishedCount = stateCount(tcpStates, tcpEstablished)
+	sample.TCPSynSentCount = stateCount(tcpStates, tcpSynSent)
+	sample.TCPSynRecvCount = stateCount(tcpStates, tcpSynRecv)
+	sample.TCPFinWait1Count = stateCount(tcpStates, tcpFinWait1)
+	sample.TCPFinWait2Count = stateCount(tcpStates, tcpFinWait2)
+	sample.TCPTimeWaitCount = stateCount(tcpStates, tcpTimeWait)
+	sample.TCPCloseCount = stateCount(tcpStates, tcpClose)
+	sample.TCPCloseWaitCount = stateCount(tcpStates, tcpCloseWait)
+	sample.TCPLastAckCount = stateCount(tcpStates, tcpLastAck)
+	sample.TCPListenCount = stateCount(tcpStates, tcpListen)
+	sample.TCPClosingCount = stateCount(tcpStates, tcpClosing)
+
+	return nil
+}
+
+func stateCount(states map[uint8]int32, state uint8) *int32 {
+	count := states[state]
+	return &count
+}
+
+// namespaceSockets returns the sockets of the network namespace the process belongs to.
+func (c *socketsCache) namespaceSockets(pid string) (socketsByInode, error) {
+	namespace, err := os.Readlink(helpers.HostProc(pid, "ns", "net"))
+	if err != nil {
+		return nil, err
+	}
+	if sockets, ok := c.byNamespace[namespace]; ok {
+		return sockets, nil
+	}
+
+	sockets := socketsByInode{}
+	for _, table := range socketTables {
+		f, err := os.Open(helpers.HostProc(pid, "net", table.file))
+		if err != nil {
+			// e.g. IPv6 disabled
+			continue
+		}
+		err = parseSocketTable(f, table.protocol, sockets)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.byNamespace[namespace] = sockets
+	return sockets, nil
+}
+
+// TCPPortListening returns whether a TCP socket of the agent network namespace is listening on the port, reading the
+// /proc/net/{tcp,tcp6} tables.
+func TCPPortListening(port uint16) (bool, error) {
+	sockets := socketsByInode{}
+	for _, table := range socketTables {
+		if table.protocol != protocolTCP {
+			continue
+		}
+		f, err := os.Open(helpers.HostProc("net", table.file))
+		if err != nil {
+			// e.g. IPv6 disabled
+			continue
+		}
+		err = parseSocketTable(f, table.protocol, sockets)
+		f.Close()
+		if err != nil {
+			return false, err
+		}
+	}
+	for _, socket := range sockets {
+		if socket.state == tcpListen && socket.localPort == port {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseSocketTable reads the sockets from a /proc/net/{tcp,tcp6,udp,udp6} file, whose lines look like:
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//	0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 27712 1 ...
+func parseSocketTable(r io.Reader, protocol string, sockets socketsByInode) error {
+	scanner := bufio.NewScanner(r)
+	// skip header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		localPort, err := addressPort(fields[1])
+		if err != nil {
+			return err
+		}
+		remotePort, err := addressPort(fields[2])
+		if err != nil {
+			return err
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid socket state %q: %v", fields[3], err)
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid socket inode %q: %v", fields[9], err)
+		}
+
+		sockets[inode] = socketInfo{
+			protocol:  protocol,
+			localPort: localPort,
+			state:     uint8(state),
+			connected: remotePort != 0,
+		}
+	}
+	return scanner.Err()
+}
+
+// addressPort returns the port of an hexadecimal "address:port" socket address.
+func addressPort(address string) (uint16, error) {
+	i := strings.LastIndexByte(address, ':')
+	if i < 0 {
+		return 0, fmt.Errorf("invalid socket address %q", address)
+	}
+	port, err := strconv.ParseUint(address[i+1:], 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid socket address %q: %v", address, err)
+	}
+	return uint16(port), nil
+}
+
+// socketInodes returns the inodes of the sockets opened by the process.
+func socketInodes(pid string) ([]uint64, error) {
+	fdPath := helpers.HostProc(pid, "fd")
+	d, err := os.Open(fdPath)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	fds, err := d.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var inodes []uint64
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(fdPath, fd))
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			// the file descriptor may have been closed in the meantime
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+		if err == nil {
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes, nil
+}
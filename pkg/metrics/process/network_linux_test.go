@@ -0,0 +1,77 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package process
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+)
+
+func TestParseSocketTable(t *testing.T) {
+	table := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 27712 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0CEA 0100007F:D2C4 01 00000000:00000000 00:00000000 00000000   999        0 27713 1 0000000000000000 20 4 30 10 -1
+`
+	sockets := socketsByInode{}
+	require.NoError(t, parseSocketTable(strings.NewReader(table), protocolTCP, sockets))
+
+	assert.Equal(t, socketsByInode{
+		27712: {protocol: protocolTCP, localPort: 3306, state: tcpListen},
+		27713: {protocol: protocolTCP, localPort: 3306, state: tcpEstablished, connected: true},
+	}, sockets)
+}
+
+func TestParseSocketTable_Invalid(t *testing.T) {
+	table := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 27712 1
+`
+	assert.Error(t, parseSocketTable(strings.NewReader(table), protocolTCP, socketsByInode{}))
+}
+
+func TestSocketsCache_Populate(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	accepted, err := listener.Accept()
+	require.NoError(t, err)
+	defer accepted.Close()
+
+	sample := &types.ProcessSample{ProcessID: int32(os.Getpid())}
+	require.NoError(t, newSocketsCache().populate(sample))
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	assert.Contains(t, strings.Split(sample.ListeningPorts, ","), fmt.Sprintf("tcp:%d", port))
+	require.NotNil(t, sample.TCPListenCount)
+	assert.True(t, *sample.TCPListenCount >= 1)
+	require.NotNil(t, sample.TCPEstablishedCount)
+	assert.True(t, *sample.TCPEstablishedCount >= 1)
+	require.NotNil(t, sample.TCPConnectionCount)
+	assert.True(t, *sample.TCPConnectionCount >= 2)
+}
+
+func TestTCPPortListening(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+
+	listening, err := TCPPortListening(port)
+	require.NoError(t, err)
+	assert.True(t, listening)
+
+	require.NoError(t, listener.Close())
+	listening, err = TCPPortListening(port)
+	require.NoError(t, err)
+	assert.False(t, listening)
+}
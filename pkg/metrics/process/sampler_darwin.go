@@ -24,6 +24,8 @@ type processSampler struct {
 	lastRun          time.Time
 	hasAlreadyRun    bool
 	interval         time.Duration
+	aggregateBy      string
+	ctx              agent.AgentContext
 }
 
 var (
@@ -38,11 +40,13 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 	ttlSecs := config.DefaultContainerCacheMetadataLimit
 	apiVersion := ""
 	interval := config.FREQ_INTERVAL_FLOOR_PROCESS_METRICS
+	aggregateBy := ""
 	if hasConfig {
 		cfg := ctx.Config()
 		ttlSecs = cfg.ContainerMetadataCacheLimit
 		apiVersion = cfg.DockerApiVersion
 		interval = cfg.MetricsProcessSampleRate
+		aggregateBy = cfg.MetricsProcessAggregateBy
 	}
 	harvester := newHarvester(ctx)
 	dockerSampler := metrics.NewDockerSampler(time.Duration(ttlSecs)*time.Second, apiVersion)
@@ -51,6 +55,8 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 		harvest:          harvester,
 		containerSampler: dockerSampler,
 		interval:         time.Second * time.Duration(interval),
+		aggregateBy:      aggregateBy,
+		ctx:              ctx,
 	}
 
 }
@@ -103,6 +109,7 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 		}
 	}
 
+	var aggregated []*types.ProcessSample
 	for _, pid := range pids {
 		var processSample *types.ProcessSample
 		var err error
@@ -117,10 +124,20 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 			dockerDecorator.Decorate(processSample)
 		}
 
+		if ps.aggregateBy != "" {
+			// every process is aggregated, as the groups are reported even with the process metrics disabled
+			aggregated = append(aggregated, processSample)
+			continue
+		}
+
 		results = append(results, ps.normalizeSample(processSample))
 	}
 
 	ps.hasAlreadyRun = true
+
+	if ps.aggregateBy != "" {
+		return metrics.AggregateProcessSamples(aggregated, ps.aggregateBy), nil
+	}
 	return results, nil
 }
 
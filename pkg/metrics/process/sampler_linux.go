@@ -24,6 +24,8 @@ type processSampler struct {
 	lastRun          time.Time
 	hasAlreadyRun    bool
 	interval         time.Duration
+	aggregateBy      string
+	ctx              agent.AgentContext
 	cache            *cache
 }
 
@@ -39,11 +41,13 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 	ttlSecs := config.DefaultContainerCacheMetadataLimit
 	apiVersion := ""
 	interval := config.FREQ_INTERVAL_FLOOR_PROCESS_METRICS
+	aggregateBy := ""
 	if hasConfig {
 		cfg := ctx.Config()
 		ttlSecs = cfg.ContainerMetadataCacheLimit
 		apiVersion = cfg.DockerApiVersion
 		interval = cfg.MetricsProcessSampleRate
+		aggregateBy = cfg.MetricsProcessAggregateBy
 	}
 	cache := newCache()
 	harvest := newHarvester(ctx, &cache)
@@ -54,6 +58,8 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 		containerSampler: dockerSampler,
 		cache:            &cache,
 		interval:         time.Second * time.Duration(interval),
+		aggregateBy:      aggregateBy,
+		ctx:              ctx,
 	}
 
 }
@@ -106,6 +112,7 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 		}
 	}
 
+	var aggregated []*types.ProcessSample
 	for _, pid := range pids {
 		var processSample *types.ProcessSample
 		var err error
@@ -120,11 +127,21 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 			dockerDecorator.Decorate(processSample)
 		}
 
+		if ps.aggregateBy != "" {
+			// every process is aggregated, as the groups are reported even with the process metrics disabled
+			aggregated = append(aggregated, processSample)
+			continue
+		}
+
 		results = append(results, ps.normalizeSample(processSample))
 	}
 
 	ps.cache.items.RemoveUntilLen(len(pids))
 	ps.hasAlreadyRun = true
+
+	if ps.aggregateBy != "" {
+		return metrics.AggregateProcessSamples(aggregated, ps.aggregateBy), nil
+	}
 	return results, nil
 }
 
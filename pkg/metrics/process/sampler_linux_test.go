@@ -65,6 +65,37 @@ func TestProcessSampler_DockerDecorator(t *testing.T) {
 	}
 }
 
+func TestProcessSampler_Aggregate(t *testing.T) {
+	// Given a Process Sampler aggregating by command name
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{MetricsProcessAggregateBy: config.ProcessAggregateByCommandName})
+	ctx.On("GetServiceForPid", mock.Anything).Return("", false)
+	// And the process metrics disabled
+	ctx.On("IncludeEvent", mock.Anything).Return(false)
+	ps := NewProcessSampler(ctx).(*processSampler)
+	ps.harvest = &harvesterMock{samples: map[int32]*types.ProcessSample{
+		1: {ProcessID: 1, CommandName: "gcc", CPUPercent: 1},
+		2: {ProcessID: 2, CommandName: "gcc", CPUPercent: 2},
+		3: {ProcessID: 3, CommandName: "gcc", CPUPercent: 4},
+		4: {ProcessID: 4, CommandName: "ld", CPUPercent: 8},
+	}}
+	ps.containerSampler = &fakeContainerSampler{}
+
+	// When asking for the process samples
+	samples, err := ps.Sample()
+	require.NoError(t, err)
+
+	// A group sample is returned per command name, aggregating all the processes
+	require.Len(t, samples, 2)
+	gcc := samples[0].(*types.ProcessGroupSample)
+	assert.Equal(t, "gcc", gcc.CommandName)
+	assert.Equal(t, 3, gcc.ProcessCount)
+	assert.Equal(t, float64(7), gcc.CPUPercent)
+	ld := samples[1].(*types.ProcessGroupSample)
+	assert.Equal(t, "ld", ld.CommandName)
+	assert.Equal(t, 1, ld.ProcessCount)
+}
+
 type harvesterMock struct {
 	samples map[int32]*types.ProcessSample
 }
@@ -141,6 +172,10 @@ func (*dummyAgentContext) SendData(agent.PluginOutput) {}
 
 func (*dummyAgentContext) SendEvent(event sample.Event, entityKey entity.Key) {}
 
+func (*dummyAgentContext) IncludeEvent(interface{}) bool {
+	return true
+}
+
 func (*dummyAgentContext) Unregister(ids.PluginID) {}
 
 func (*dummyAgentContext) Version() string {
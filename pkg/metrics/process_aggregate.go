@@ -0,0 +1,99 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"sort"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const processGroupSampleType = "ProcessGroupSample"
+
+// groupKey returns the value of the attribute the process samples are aggregated by.
+func groupKey(s *types.ProcessSample, groupBy string) string {
+	switch groupBy {
+	case config.ProcessAggregateByContainerID:
+		return s.ContainerID
+	case config.ProcessAggregateByUserName:
+		return s.User
+	default:
+		return s.CommandName
+	}
+}
+
+// AggregateProcessSamples rolls up the process samples into a ProcessGroupSample per distinct value of the groupBy
+// attribute, summing their resources usage. Groups are returned sorted by their key.
+func AggregateProcessSamples(samples []*types.ProcessSample, groupBy string) (results sample.EventBatch) {
+	groups := map[string]*types.ProcessGroupSample{}
+	var keys []string
+
+	for _, s := range samples {
+		key := groupKey(s, groupBy)
+		group, ok := groups[key]
+		if !ok {
+			group = newProcessGroupSample(s, groupBy)
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		addToGroup(group, s)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		results = append(results, groups[key])
+	}
+	return results
+}
+
+func newProcessGroupSample(s *types.ProcessSample, groupBy string) *types.ProcessGroupSample {
+	group := &types.ProcessGroupSample{GroupBy: groupBy}
+	group.Type(processGroupSampleType)
+
+	switch groupBy {
+	case config.ProcessAggregateByContainerID:
+		group.ContainerID = s.ContainerID
+		group.ContainerName = s.ContainerName
+		group.ContainerImageName = s.ContainerImageName
+	case config.ProcessAggregateByUserName:
+		group.User = s.User
+	default:
+		group.CommandName = s.CommandName
+	}
+	return group
+}
+
+func addToGroup(group *types.ProcessGroupSample, s *types.ProcessSample) {
+	group.ProcessCount++
+	group.ThreadCount += int64(s.ThreadCount)
+	group.MemoryRSSBytes += s.MemoryRSSBytes
+	group.MemoryVMSBytes += s.MemoryVMSBytes
+	group.CPUPercent += s.CPUPercent
+	group.CPUUserPercent += s.CPUUserPercent
+	group.CPUSystemPercent += s.CPUSystemPercent
+
+	if s.FdCount != nil {
+		if group.FdCount == nil {
+			group.FdCount = new(int64)
+		}
+		*group.FdCount += int64(*s.FdCount)
+	}
+	group.IOReadCountPerSecond = addRate(group.IOReadCountPerSecond, s.IOReadCountPerSecond)
+	group.IOWriteCountPerSecond = addRate(group.IOWriteCountPerSecond, s.IOWriteCountPerSecond)
+	group.IOReadBytesPerSecond = addRate(group.IOReadBytesPerSecond, s.IOReadBytesPerSecond)
+	group.IOWriteBytesPerSecond = addRate(group.IOWriteBytesPerSecond, s.IOWriteBytesPerSecond)
+}
+
+// addRate sums value into total, keeping nil when no value has been reported.
+func addRate(total, value *float64) *float64 {
+	if value == nil {
+		return total
+	}
+	if total == nil {
+		total = new(float64)
+	}
+	*total += *value
+	return total
+}
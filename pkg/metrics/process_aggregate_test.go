@@ -0,0 +1,84 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
+func testProcessSamples() []*types.ProcessSample {
+	return []*types.ProcessSample{
+		{ProcessID: 1, CommandName: "gcc", User: "build", CPUPercent: 10, MemoryRSSBytes: 100, ThreadCount: 1, FdCount: int32Ptr(3), IOReadBytesPerSecond: floatPtr(5)},
+		{ProcessID: 2, CommandName: "gcc", User: "build", CPUPercent: 20, MemoryRSSBytes: 200, ThreadCount: 2, IOReadBytesPerSecond: floatPtr(7)},
+		{ProcessID: 3, CommandName: "ld", User: "build", ContainerID: "abc", ContainerName: "linker", CPUPercent: 5, MemoryRSSBytes: 50, ThreadCount: 1},
+		{ProcessID: 4, CommandName: "sshd", User: "root", ContainerID: "abc", ContainerName: "linker", CPUPercent: 1, MemoryRSSBytes: 10, ThreadCount: 1},
+	}
+}
+
+func TestAggregateSamples_ByCommandName(t *testing.T) {
+	results := AggregateProcessSamples(testProcessSamples(), config.ProcessAggregateByCommandName)
+	require.Len(t, results, 3)
+
+	gcc := results[0].(*types.ProcessGroupSample)
+	assert.Equal(t, "ProcessGroupSample", gcc.EventType)
+	assert.Equal(t, "commandName", gcc.GroupBy)
+	assert.Equal(t, "gcc", gcc.CommandName)
+	assert.Empty(t, gcc.User)
+	assert.Equal(t, 2, gcc.ProcessCount)
+	assert.Equal(t, float64(30), gcc.CPUPercent)
+	assert.Equal(t, int64(300), gcc.MemoryRSSBytes)
+	assert.Equal(t, int64(3), gcc.ThreadCount)
+	require.NotNil(t, gcc.FdCount)
+	assert.Equal(t, int64(3), *gcc.FdCount)
+	require.NotNil(t, gcc.IOReadBytesPerSecond)
+	assert.Equal(t, float64(12), *gcc.IOReadBytesPerSecond)
+	assert.Nil(t, gcc.IOWriteBytesPerSecond)
+
+	ld := results[1].(*types.ProcessGroupSample)
+	assert.Equal(t, "ld", ld.CommandName)
+	assert.Equal(t, 1, ld.ProcessCount)
+	assert.Nil(t, ld.FdCount)
+}
+
+func TestAggregateSamples_ByContainerID(t *testing.T) {
+	results := AggregateProcessSamples(testProcessSamples(), config.ProcessAggregateByContainerID)
+	require.Len(t, results, 2)
+
+	host := results[0].(*types.ProcessGroupSample)
+	assert.Empty(t, host.ContainerID)
+	assert.Equal(t, 2, host.ProcessCount)
+
+	container := results[1].(*types.ProcessGroupSample)
+	assert.Equal(t, "abc", container.ContainerID)
+	assert.Equal(t, "linker", container.ContainerName)
+	assert.Empty(t, container.CommandName)
+	assert.Equal(t, 2, container.ProcessCount)
+	assert.Equal(t, int64(60), container.MemoryRSSBytes)
+}
+
+func TestAggregateSamples_ByUserName(t *testing.T) {
+	results := AggregateProcessSamples(testProcessSamples(), config.ProcessAggregateByUserName)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, "build", results[0].(*types.ProcessGroupSample).User)
+	assert.Equal(t, 3, results[0].(*types.ProcessGroupSample).ProcessCount)
+	assert.Equal(t, "root", results[1].(*types.ProcessGroupSample).User)
+}
+
+func TestAggregateSamples_Empty(t *testing.T) {
+	assert.Empty(t, AggregateProcessSamples(nil, config.ProcessAggregateByCommandName))
+}
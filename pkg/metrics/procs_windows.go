@@ -339,10 +339,11 @@ type ProcsMonitor struct {
 	getUsername          func(int32) (string, error)
 	getTimes             func(int32) (*SystemTimes, error)
 	getCommandLine       func(uint32) (string, error)
+	aggregateBy          string
 }
 
 func NewProcsMonitor(context agent.AgentContext) *ProcsMonitor {
-	var apiVersion string
+	var apiVersion, aggregateBy string
 	ttlSecs := config.DefaultContainerCacheMetadataLimit
 	if context != nil && context.Config() != nil {
 		if len(context.Config().AllowedListProcessSample) > 0 {
@@ -353,6 +354,7 @@ func NewProcsMonitor(context agent.AgentContext) *ProcsMonitor {
 		}
 		ttlSecs = context.Config().ContainerMetadataCacheLimit
 		apiVersion = context.Config().DockerApiVersion
+		aggregateBy = context.Config().MetricsProcessAggregateBy
 	}
 	return &ProcsMonitor{
 		context:              context,
@@ -367,6 +369,7 @@ func NewProcsMonitor(context agent.AgentContext) *ProcsMonitor {
 		getUsername:          getProcessUsername,
 		getTimes:             getProcessTimes,
 		getCommandLine:       getProcessCommandLineWMI,
+		aggregateBy:          aggregateBy,
 	}
 }
 
@@ -578,6 +581,7 @@ func (self *ProcsMonitor) Sample() (results sample.EventBatch, err error) {
 	}()
 
 	elapsedSeconds := self.calcElapsedTimeInSeconds()
+	var aggregated []*types.ProcessSample
 
 	self.currentSystemTime, err = getSystemTimes()
 	if err != nil {
@@ -807,6 +811,11 @@ func (self *ProcsMonitor) Sample() (results sample.EventBatch, err error) {
 					dockerDecorator.Decorate(sample)
 				}
 				procCacheEntry.lastSample = sample
+				if self.aggregateBy != "" {
+					// every process is aggregated, as the groups are reported even with the process metrics disabled
+					aggregated = append(aggregated, sample)
+					continue
+				}
 				results = append(results, sample)
 			}
 		}
@@ -840,6 +849,10 @@ func (self *ProcsMonitor) Sample() (results sample.EventBatch, err error) {
 			helpers.LogStructureDetails(pslog, sample.(*types.ProcessSample), "ProcessSample", "final", nil)
 		}
 	}
+
+	if self.aggregateBy != "" {
+		results = AggregateProcessSamples(aggregated, self.aggregateBy)
+	}
 	return
 }
 
@@ -0,0 +1,691 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/newrelic/infrastructure-agent/pkg/trace"
+)
+
+// ExpressionKey is the include_matching_metrics key whose values are boolean expressions evaluated against every
+// sample type, instead of literal or regex values for a single attribute. E.g.:
+//
+//	include_matching_metrics:
+//	  expression:
+//	    - eventType != "ProcessSample" or cpuPercent > 5
+//	    - processDisplayName =~ "^java" and not (userName == "nobody")
+//
+// Expressions support:
+//   - attributes, referenced by their sample name (e.g. cpuPercent, memoryResidentSizeBytes, eventType) or by
+//     the include_matching_metrics dimension names (process.name, process.executable)
+//   - number (1, 2.5, 1e9), string ("foo" or 'foo') and boolean (true, false) literals
+//   - comparisons: ==, !=, >, >=, <, <=, and regex matching against a string literal: =~, !~
+//   - logical operators: and (&&), or (||), not (!), and parentheses
+//   - functions: contains(s, sub), startsWith(s, prefix), endsWith(s, suffix), matches(s, "regex"), lower(s),
+//     upper(s), len(s) and exists(attribute)
+//
+// Comparisons involving an attribute not present in the sample are false.
+//
+// A process sample is included when it matches either an attribute rule (e.g. process.name) or an expression.
+// Attribute rules do not apply to other sample types, so these are included only when they match an expression.
+// Invalid expressions make the agent fail to start.
+const ExpressionKey = "expression"
+
+// expressionMatcher evaluates a compiled boolean expression against samples.
+type expressionMatcher struct {
+	source string
+	root   exprNode
+}
+
+// CompileExpression compiles a boolean expression into an ExpressionMatcher.
+func CompileExpression(expr string) (ExpressionMatcher, error) {
+	p := &exprParser{lexer: newExprLexer(expr)}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %v", expr, err)
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("invalid expression '%s': unexpected '%s' at position %d", expr, p.tok.text, p.tok.pos)
+	}
+	return expressionMatcher{source: expr, root: root}, nil
+}
+
+func (m expressionMatcher) Evaluate(event interface{}) bool {
+	isMatch := m.root.eval(event) == true
+	trace.MetricMatch("sample matches expression '%s': %v", m.source, isMatch)
+	return isMatch
+}
+
+func (m expressionMatcher) String() string {
+	return m.source
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOperator
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type exprLexer struct {
+	input []rune
+	pos   int
+}
+
+func newExprLexer(input string) *exprLexer {
+	return &exprLexer{input: []rune(input)}
+}
+
+var twoCharOperators = []string{"==", "!=", ">=", "<=", "=~", "!~", "&&", "||"}
+
+func (l *exprLexer) next() (token, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(l.input[l.pos]) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	c := l.input[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return token{kind: tokLParen, text: "(", pos: start}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokRParen, text: ")", pos: start}, nil
+	case c == ',':
+		l.pos++
+		return token{kind: tokComma, text: ",", pos: start}, nil
+	case c == '"' || c == '\'':
+		return l.lexString(c)
+	case unicode.IsDigit(c) || (c == '.' && l.pos+1 < len(l.input) && unicode.IsDigit(l.input[l.pos+1])):
+		return l.lexNumber()
+	case unicode.IsLetter(c) || c == '_':
+		for l.pos < len(l.input) && isIdentRune(l.input[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: string(l.input[start:l.pos]), pos: start}, nil
+	}
+
+	if l.pos+1 < len(l.input) {
+		pair := string(l.input[l.pos : l.pos+2])
+		for _, op := range twoCharOperators {
+			if pair == op {
+				l.pos += 2
+				return token{kind: tokOperator, text: op, pos: start}, nil
+			}
+		}
+	}
+	if c == '>' || c == '<' || c == '!' {
+		l.pos++
+		return token{kind: tokOperator, text: string(c), pos: start}, nil
+	}
+
+	return token{}, fmt.Errorf("unexpected character '%c' at position %d", c, start)
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
+}
+
+func (l *exprLexer) lexString(quote rune) (token, error) {
+	start := l.pos
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		if c == '\\' && l.pos+1 < len(l.input) && (l.input[l.pos+1] == quote || l.input[l.pos+1] == '\\') {
+			sb.WriteRune(l.input[l.pos+1])
+			l.pos += 2
+			continue
+		}
+		l.pos++
+		if c == quote {
+			return token{kind: tokString, text: sb.String(), pos: start}, nil
+		}
+		sb.WriteRune(c)
+	}
+	return token{}, fmt.Errorf("unterminated string at position %d", start)
+}
+
+func (l *exprLexer) lexNumber() (token, error) {
+	start := l.pos
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		isExpSign := (c == '+' || c == '-') && (l.input[l.pos-1] == 'e' || l.input[l.pos-1] == 'E')
+		if !unicode.IsDigit(c) && c != '.' && c != 'e' && c != 'E' && !isExpSign {
+			break
+		}
+		l.pos++
+	}
+	text := string(l.input[start:l.pos])
+	if _, err := strconv.ParseFloat(text, 64); err != nil {
+		return token{}, fmt.Errorf("invalid number '%s' at position %d", text, start)
+	}
+	return token{kind: tokNumber, text: text, pos: start}, nil
+}
+
+// Parser
+
+type exprParser struct {
+	lexer *exprLexer
+	tok   token
+}
+
+func (p *exprParser) next() (err error) {
+	p.tok, err = p.lexer.next()
+	return
+}
+
+func (p *exprParser) isKeyword(keyword string) bool {
+	return p.tok.kind == tokIdent && strings.EqualFold(p.tok.text, keyword)
+}
+
+func (p *exprParser) isOperator(op string) bool {
+	return p.tok.kind == tokOperator && p.tok.text == op
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") || p.isOperator("||") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") || p.isOperator("&&") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.isKeyword("not") || p.isOperator("!") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokOperator {
+		return left, nil
+	}
+
+	op := p.tok.text
+	switch op {
+	case "==", "!=", ">", ">=", "<", "<=":
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return comparisonNode{op: op, left: left, right: right}, nil
+	case "=~", "!~":
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		re, err := p.parseRegexLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return regexNode{operand: left, regex: re, negate: op == "!~"}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseRegexLiteral() (*regexp.Regexp, error) {
+	if p.tok.kind != tokString {
+		return nil, fmt.Errorf("expected a regex string at position %d", p.tok.pos)
+	}
+	re, err := regexp.Compile(p.tok.text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex '%s': %v", p.tok.text, err)
+	}
+	return re, p.next()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		value, _ := strconv.ParseFloat(tok.text, 64)
+		return literalNode{value: value}, p.next()
+	case tokString:
+		return literalNode{value: tok.text}, p.next()
+	case tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, fmt.Errorf("expected ')' at position %d", p.tok.pos)
+		}
+		return inner, p.next()
+	case tokIdent:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		}
+		if p.tok.kind == tokLParen {
+			return p.parseCall(tok)
+		}
+		return newAttributeNode(tok.text), nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected '%s' at position %d", tok.text, tok.pos)
+}
+
+func (p *exprParser) parseCall(name token) (exprNode, error) {
+	fn, ok := exprFunctions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s' at position %d", name.text, name.pos)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	var args []exprNode
+	for p.tok.kind != tokRParen {
+		if len(args) > 0 {
+			if p.tok.kind != tokComma {
+				return nil, fmt.Errorf("expected ',' at position %d", p.tok.pos)
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		// matches second argument is compiled once, as the regex operators do.
+		if name.text == "matches" && len(args) == 1 {
+			re, err := p.parseRegexLiteral()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, literalNode{value: re})
+			continue
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("function '%s' expects %d arguments, got %d", name.text, fn.arity, len(args))
+	}
+	return callNode{fn: fn.eval, args: args}, p.next()
+}
+
+// Evaluation
+
+type exprNode interface {
+	eval(sample interface{}) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(interface{}) interface{} {
+	return n.value
+}
+
+type attributeNode struct {
+	names []string
+}
+
+// newAttributeNode creates a node for the attribute, also supporting the include_matching_metrics dimension names
+// (e.g. process.name).
+func newAttributeNode(name string) attributeNode {
+	if mapped, ok := attrCache[name]; ok {
+		return attributeNode{names: mapped}
+	}
+	return attributeNode{names: []string{name}}
+}
+
+func (n attributeNode) eval(sample interface{}) interface{} {
+	for _, name := range n.names {
+		if value := attributeValue(sample, name); value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+type notNode struct {
+	operand exprNode
+}
+
+func (n notNode) eval(sample interface{}) interface{} {
+	return n.operand.eval(sample) != true
+}
+
+type andNode struct {
+	left, right exprNode
+}
+
+func (n andNode) eval(sample interface{}) interface{} {
+	return n.left.eval(sample) == true && n.right.eval(sample) == true
+}
+
+type orNode struct {
+	left, right exprNode
+}
+
+func (n orNode) eval(sample interface{}) interface{} {
+	return n.left.eval(sample) == true || n.right.eval(sample) == true
+}
+
+type comparisonNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n comparisonNode) eval(sample interface{}) interface{} {
+	left, right := n.left.eval(sample), n.right.eval(sample)
+	if left == nil || right == nil {
+		return false
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		cmp = compareFloats(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(l, r)
+	case bool:
+		r, ok := right.(bool)
+		if !ok || (n.op != "==" && n.op != "!=") {
+			return false
+		}
+		if l != r {
+			cmp = 1
+		}
+	default:
+		return false
+	}
+
+	switch n.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+func compareFloats(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+type regexNode struct {
+	operand exprNode
+	regex   *regexp.Regexp
+	negate  bool
+}
+
+func (n regexNode) eval(sample interface{}) interface{} {
+	value := n.operand.eval(sample)
+	if value == nil {
+		return false
+	}
+	return n.regex.MatchString(fmt.Sprint(value)) != n.negate
+}
+
+type callNode struct {
+	fn   func(args []interface{}) interface{}
+	args []exprNode
+}
+
+func (n callNode) eval(sample interface{}) interface{} {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(sample)
+	}
+	return n.fn(args)
+}
+
+type exprFunction struct {
+	arity int
+	eval  func(args []interface{}) interface{}
+}
+
+var exprFunctions = map[string]exprFunction{
+	"contains":   {2, stringPredicate(strings.Contains)},
+	"startsWith": {2, stringPredicate(strings.HasPrefix)},
+	"endsWith":   {2, stringPredicate(strings.HasSuffix)},
+	"matches": {2, func(args []interface{}) interface{} {
+		s, ok := args[0].(string)
+		return ok && args[1].(*regexp.Regexp).MatchString(s)
+	}},
+	"lower": {1, stringTransform(strings.ToLower)},
+	"upper": {1, stringTransform(strings.ToUpper)},
+	"len": {1, func(args []interface{}) interface{} {
+		if s, ok := args[0].(string); ok {
+			return float64(len(s))
+		}
+		return nil
+	}},
+	"exists": {1, func(args []interface{}) interface{} {
+		return args[0] != nil
+	}},
+}
+
+func stringPredicate(fn func(s, arg string) bool) func(args []interface{}) interface{} {
+	return func(args []interface{}) interface{} {
+		s, ok := args[0].(string)
+		arg, argOk := args[1].(string)
+		return ok && argOk && fn(s, arg)
+	}
+}
+
+func stringTransform(fn func(s string) string) func(args []interface{}) interface{} {
+	return func(args []interface{}) interface{} {
+		if s, ok := args[0].(string); ok {
+			return fn(s)
+		}
+		return nil
+	}
+}
+
+// Sample attributes access
+
+// fieldPaths caches, per sample type, the index path of the struct field of each attribute name.
+var fieldPaths sync.Map // map[reflect.Type]map[string][]int
+
+// attributeValue returns the value of a sample attribute, looked up by its JSON name or its field name, normalized
+// to float64, string or bool. It returns nil if the attribute is not present.
+func attributeValue(sample interface{}, name string) interface{} {
+	v := reflect.ValueOf(sample)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return nil
+		}
+		return normalizeValue(value)
+	case reflect.Struct:
+		path, ok := structFieldPaths(v.Type())[name]
+		if !ok {
+			return nil
+		}
+		for _, i := range path {
+			for v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return nil
+				}
+				v = v.Elem()
+			}
+			v = v.Field(i)
+		}
+		return normalizeValue(v)
+	}
+	return nil
+}
+
+func structFieldPaths(t reflect.Type) map[string][]int {
+	if paths, ok := fieldPaths.Load(t); ok {
+		return paths.(map[string][]int)
+	}
+	paths := map[string][]int{}
+	collectFieldPaths(t, nil, paths)
+	fieldPaths.Store(t, paths)
+	return paths
+}
+
+func collectFieldPaths(t reflect.Type, parent []int, paths map[string][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		path := append(append([]int{}, parent...), i)
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct {
+			collectFieldPaths(ft, path, paths)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		// outer fields take precedence over the embedded ones
+		if jsonName != "" {
+			if _, ok := paths[jsonName]; !ok || len(paths[jsonName]) > len(path) {
+				paths[jsonName] = path
+			}
+		}
+		if _, ok := paths[f.Name]; !ok || len(paths[f.Name]) > len(path) {
+			paths[f.Name] = path
+		}
+	}
+}
+
+func normalizeValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/network"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sampler"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+)
+
+func newProcessSample() *types.ProcessSample {
+	fds := int32(12)
+	s := &types.ProcessSample{
+		ProcessDisplayName: "java",
+		ProcessID:          1234,
+		CommandName:        "java",
+		User:               "app",
+		MemoryRSSBytes:     2e9,
+		CPUPercent:         12.5,
+		CmdLine:            "/usr/bin/java -jar app.jar",
+		FdCount:            &fds,
+	}
+	s.Type("ProcessSample")
+	return s
+}
+
+func TestCompileExpression_Evaluate(t *testing.T) {
+	process := newProcessSample()
+	flatProcess := &types.FlatProcessSample{
+		"eventType":          "ProcessSample",
+		"processDisplayName": "nginx",
+		"cpuPercent":         0.5,
+		"userName":           "nobody",
+	}
+	system := &metrics.SystemSample{CPUSample: &metrics.CPUSample{CPUPercent: 80}}
+	system.Type("SystemSample")
+
+	tests := []struct {
+		expr   string
+		sample interface{}
+		want   bool
+	}{
+		{`cpuPercent > 5`, process, true},
+		{`cpuPercent > 5`, flatProcess, false},
+		{`cpuPercent > 5`, system, true},
+		{`memoryResidentSizeBytes > 1e9`, process, true},
+		{`memoryResidentSizeBytes >= 2000000000 && memoryResidentSizeBytes <= 2e9`, process, true},
+		{`fileDescriptorCount == 12`, process, true},
+		{`processDisplayName == "java" and userName != 'nobody'`, process, true},
+		{`processDisplayName == "java" and userName != 'nobody'`, flatProcess, false},
+		{`not (userName == "nobody")`, flatProcess, false},
+		{`!(userName == "nobody") || cpuPercent < 1`, flatProcess, true},
+		{`eventType != "ProcessSample" or cpuPercent > 5`, system, true},
+		{`eventType != "ProcessSample" or cpuPercent > 5`, flatProcess, false},
+		{`commandLine =~ "-jar\\s+app"`, process, true},
+		{`process.executable =~ "^/usr/bin/"`, process, true},
+		{`process.name !~ "^ngi"`, flatProcess, false},
+		{`contains(commandLine, "app.jar")`, process, true},
+		{`startsWith(lower(processDisplayName), "JA")`, process, false},
+		{`startsWith(upper(processDisplayName), "JA")`, process, true},
+		{`endsWith(processDisplayName, "va")`, process, true},
+		{`matches(processDisplayName, "^(java|nginx)$")`, flatProcess, true},
+		{`len(processDisplayName) == 4`, process, true},
+		{`exists(containerId)`, flatProcess, false},
+		{`exists(cpuPercent)`, flatProcess, true},
+		// comparisons with missing attributes or mismatching types are false
+		{`unknownAttribute < 1`, process, false},
+		{`unknownAttribute != 1`, process, false},
+		{`processDisplayName > 1`, process, false},
+		{`interfaceName == "eth0"`, &network.NetworkSample{InterfaceName: "eth0"}, true},
+		{`true`, process, true},
+		{`cpuPercent`, process, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m, err := sampler.CompileExpression(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Evaluate(tt.sample))
+		})
+	}
+}
+
+func TestCompileExpression_Errors(t *testing.T) {
+	exprs := []string{
+		``,
+		`cpuPercent >`,
+		`(cpuPercent > 5`,
+		`cpuPercent > 5)`,
+		`cpuPercent = 5`,
+		`processDisplayName == "java`,
+		`processDisplayName =~ "(java"`,
+		`processDisplayName =~ userName`,
+		`unknownFunction(processDisplayName)`,
+		`contains(processDisplayName)`,
+		`cpuPercent > 1e`,
+	}
+
+	for _, expr := range exprs {
+		t.Run(expr, func(t *testing.T) {
+			_, err := sampler.CompileExpression(expr)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewMatcherChain_WithExpressions(t *testing.T) {
+	ec := sampler.NewMatcherChain(config.IncludeMetricsMap{
+		sampler.ExpressionKey: {
+			`eventType == "ProcessSample" and cpuPercent > 50`,
+			`eventType == "SystemSample"`,
+		},
+	})
+	system := &metrics.SystemSample{CPUSample: &metrics.CPUSample{CPUPercent: 80}}
+	system.Type("SystemSample")
+	networkSample := &network.NetworkSample{}
+	networkSample.Type("NetworkSample")
+
+	assert.True(t, ec.Enabled)
+	assert.False(t, ec.Evaluate(newProcessSample()))
+	assert.True(t, ec.Evaluate(system))
+	assert.False(t, ec.Evaluate(networkSample))
+}
+
+func TestNewMatcherChain_InvalidExpressionIsSkipped(t *testing.T) {
+	ec := sampler.NewMatcherChain(config.IncludeMetricsMap{
+		sampler.ExpressionKey: {`cpuPercent >`, `eventType == "SystemSample"`},
+	})
+	system := &metrics.SystemSample{}
+	system.Type("SystemSample")
+
+	assert.Len(t, ec.Matchers[sampler.ExpressionKey], 1)
+	assert.True(t, ec.Evaluate(system))
+	assert.False(t, ec.Evaluate(newProcessSample()))
+}
+
+func TestValidateMatchers(t *testing.T) {
+	valid := config.IncludeMetricsMap{
+		"process.name":        {"java"},
+		sampler.ExpressionKey: {`cpuPercent > 5`},
+	}
+	invalid := config.IncludeMetricsMap{sampler.ExpressionKey: {`cpuPercent >`}}
+
+	assert.NoError(t, sampler.ValidateMatchers(valid, valid))
+	assert.NoError(t, sampler.ValidateMatchers(nil, nil))
+	assert.Error(t, sampler.ValidateMatchers(invalid, nil))
+	assert.Error(t, sampler.ValidateMatchers(nil, invalid))
+}
+
+func TestNewMatcherChain_AttributeRulesOnlyApplyToProcessSamples(t *testing.T) {
+	ec := sampler.NewMatcherChain(config.IncludeMetricsMap{
+		"process.name":        {"python"},
+		sampler.ExpressionKey: {`cpuPercent > 50`},
+	})
+	lowCPU := &metrics.SystemSample{CPUSample: &metrics.CPUSample{CPUPercent: 10}}
+	highCPU := &metrics.SystemSample{CPUSample: &metrics.CPUSample{CPUPercent: 80}}
+	busyJava := newProcessSample()
+	busyJava.CPUPercent = 80
+	python := newProcessSample()
+	python.ProcessDisplayName = "python"
+
+	// non process samples are only evaluated against the expressions
+	assert.False(t, ec.Evaluate(lowCPU))
+	assert.True(t, ec.Evaluate(highCPU))
+	// process samples match either the attribute rules or the expressions
+	assert.False(t, ec.Evaluate(newProcessSample()))
+	assert.True(t, ec.Evaluate(busyJava))
+	assert.True(t, ec.Evaluate(python))
+}
+
+func TestNewSampleMatchFn_ExpressionsWithProcessMetricsDisabled(t *testing.T) {
+	disabled := false
+	matchFn := sampler.NewSampleMatchFn(&disabled, config.IncludeMetricsMap{
+		sampler.ExpressionKey: {`eventType == "ProcessSample" or cpuPercent > 50`},
+	}, nil, nil)
+
+	lowCPU := &metrics.SystemSample{CPUSample: &metrics.CPUSample{CPUPercent: 10}}
+	highCPU := &metrics.SystemSample{CPUSample: &metrics.CPUSample{CPUPercent: 80}}
+
+	assert.False(t, matchFn(newProcessSample()))
+	assert.False(t, matchFn(lowCPU))
+	assert.True(t, matchFn(highCPU))
+}
@@ -174,6 +174,19 @@ func build(dimensionName string, expr string) ExpressionMatcher {
 	return eval
 }
 
+// ValidateMatchers returns an error if any of the include or exclude expressions cannot be compiled, so a
+// configuration typo is reported at startup instead of silently changing which samples are sent.
+func ValidateMatchers(includeMetricsMatchers, excludeMetricsMatchers config.IncludeMetricsMap) error {
+	for _, matchers := range []config.IncludeMetricsMap{includeMetricsMatchers, excludeMetricsMatchers} {
+		for _, expr := range matchers[ExpressionKey] {
+			if _, err := CompileExpression(expr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func cacheRegex(pattern string) error {
 	//if not cached yet, cache it
 	if _, ok := regexCache[pattern]; !ok {
@@ -221,7 +234,16 @@ func NewMatcherChain(expressions config.IncludeMetricsMap) MatcherChain {
 
 		evs := chain.Matchers[prop]
 		for _, expr := range exprs {
-			e := newExpressionMatcher(prop, expr)
+			if prop != ExpressionKey {
+				evs = append(evs, newExpressionMatcher(prop, expr))
+				continue
+			}
+			// invalid expressions are skipped, as a never matching rule would drop every sample type
+			e, err := CompileExpression(expr)
+			if err != nil {
+				mlog.WithError(err).Error("skipping invalid expression in metrics matchers configuration")
+				continue
+			}
 			evs = append(evs, e)
 		}
 		chain.Matchers[prop] = evs
@@ -235,9 +257,16 @@ func NewMatcherChain(expressions config.IncludeMetricsMap) MatcherChain {
 //  - true, if event match with evaluator criteria chain
 //  - false, if event do not match with evaluator criteria chain
 // If there is no matchers will return true.
+// Attribute rules (e.g. process.name) only target process samples, while expressions target every sample type.
+// Hence, when there are expressions, samples other than process samples are only evaluated against them.
 func (ec MatcherChain) Evaluate(event interface{}) bool {
+	onlyExpressions := len(ec.Matchers[ExpressionKey]) > 0 && skipSample(event, typesToEvaluate)
+
 	var result = true
-	for _, es := range ec.Matchers {
+	for prop, es := range ec.Matchers {
+		if onlyExpressions && prop != ExpressionKey {
+			continue
+		}
 		for _, e := range es {
 			result = e.Evaluate(event)
 			if result {
@@ -248,6 +277,43 @@ func (ec MatcherChain) Evaluate(event interface{}) bool {
 	return result
 }
 
+// excludeMatcher decorates an attribute matcher for exclusion rules. Attribute rules only apply to process
+// samples, so any other sample is never excluded by them.
+type excludeMatcher struct {
+	matcher ExpressionMatcher
+}
+
+func (m excludeMatcher) Evaluate(event interface{}) bool {
+	if skipSample(event, typesToEvaluate) {
+		return false
+	}
+	return m.matcher.Evaluate(event)
+}
+
+// NewExcludeMatcherChain creates a new chain of matchers for exclusion rules.
+// It accepts the same expressions as NewMatcherChain, but the chain evaluates to true only when the sample
+// matches any of the rules, so samples not targeted by the rules are never excluded.
+func NewExcludeMatcherChain(expressions config.IncludeMetricsMap) MatcherChain {
+	// properties without rules would make the chain match everything
+	rules := config.IncludeMetricsMap{}
+	for prop, exprs := range expressions {
+		if len(exprs) > 0 {
+			rules[prop] = exprs
+		}
+	}
+
+	chain := NewMatcherChain(rules)
+	for prop, evs := range chain.Matchers {
+		if prop == ExpressionKey {
+			continue
+		}
+		for i := range evs {
+			evs[i] = excludeMatcher{matcher: evs[i]}
+		}
+	}
+	return chain
+}
+
 type constantMatcher struct {
 	value bool
 }
@@ -261,7 +327,28 @@ func (ne constantMatcher) String() string {
 
 // NewSampleMatchFn creates new includeSampleMatchFn func, enableProcessMetrics might be nil when
 // value was not set.
-func NewSampleMatchFn(enableProcessMetrics *bool, includeMetricsMatchers config.IncludeMetricsMap, ffRetriever feature_flags.Retriever) IncludeSampleMatchFn {
+// Exclusion rules take precedence over everything else: a sample matching any of the excludeMetricsMatchers
+// is dropped, no matter the include rules, enableProcessMetrics or the feature flag. Samples not matching
+// any exclusion rule are then evaluated as usual.
+func NewSampleMatchFn(enableProcessMetrics *bool, includeMetricsMatchers, excludeMetricsMatchers config.IncludeMetricsMap, ffRetriever feature_flags.Retriever) IncludeSampleMatchFn {
+	includeFn := newIncludeSampleMatchFn(enableProcessMetrics, includeMetricsMatchers, ffRetriever)
+
+	exclude := NewExcludeMatcherChain(excludeMetricsMatchers)
+	if !exclude.Enabled {
+		return includeFn
+	}
+
+	trace.MetricMatch("Exclusion rules ARE defined, matching metrics will be DISABLED regardless of include rules")
+	return func(sample interface{}) bool {
+		if exclude.Evaluate(sample) {
+			trace.MetricMatch("Got a sample of type '%s' matching an exclusion rule so excluding sample.", reflect.TypeOf(sample).String())
+			return false
+		}
+		return includeFn(sample)
+	}
+}
+
+func newIncludeSampleMatchFn(enableProcessMetrics *bool, includeMetricsMatchers config.IncludeMetricsMap, ffRetriever feature_flags.Retriever) IncludeSampleMatchFn {
 	// configuration option always takes precedence over FF and matchers configuration
 	if enableProcessMetrics == nil {
 		// if config option is not set, check if we have rules defined. those take precedence over the FF
@@ -290,6 +377,8 @@ func NewSampleMatchFn(enableProcessMetrics *bool, includeMetricsMatchers config.
 
 	if excludeProcessMetrics(enableProcessMetrics) {
 		trace.MetricMatch("EnableProcessMetrics is FALSE, process metrics will be DISABLED")
+		// expressions still apply to the rest of the samples
+		ec := NewMatcherChain(config.IncludeMetricsMap{ExpressionKey: includeMetricsMatchers[ExpressionKey]})
 		return func(sample interface{}) bool {
 			switch sample.(type) {
 			case *types.ProcessSample:
@@ -301,6 +390,9 @@ func NewSampleMatchFn(enableProcessMetrics *bool, includeMetricsMatchers config.
 				// no flat process samples are included
 				return false
 			default:
+				if len(ec.Matchers[ExpressionKey]) > 0 {
+					return ec.Evaluate(sample)
+				}
 				trace.MetricMatch("Got a sample of type '%s' that should not be excluded.", reflect.TypeOf(sample).String())
 				// other samples are included
 				return true
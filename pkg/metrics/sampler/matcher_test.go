@@ -743,7 +743,101 @@ func TestNewSampleMatchFn(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			matchFn := sampler.NewSampleMatchFn(tt.args.enableProcessMetrics, tt.args.includeMetricsMatchers, tt.args.ffRetriever)
+			matchFn := sampler.NewSampleMatchFn(tt.args.enableProcessMetrics, tt.args.includeMetricsMatchers, nil, tt.args.ffRetriever)
+			assert.Equal(t, tt.include, matchFn(tt.args.sample))
+		})
+	}
+}
+
+func TestNewSampleMatchFn_ExcludeRules(t *testing.T) {
+	trueVar := true
+	falseVar := false
+
+	type args struct {
+		enableProcessMetrics   *bool
+		includeMetricsMatchers config.IncludeMetricsMap
+		excludeMetricsMatchers config.IncludeMetricsMap
+		ffRetriever            feature_flags.Retriever
+		sample                 interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		include bool
+	}{
+		{
+			name: "process samples matching exclusion rules are not included",
+			args: args{
+				enableProcessMetrics:   &trueVar,
+				excludeMetricsMatchers: config.IncludeMetricsMap{"process.name": []string{"regex \"^fo\"", "kworker"}},
+				ffRetriever:            testFF.EmptyFFRetriever,
+				sample:                 &fixture.ProcessSample,
+			},
+			include: false,
+		},
+		{
+			name: "process samples not matching exclusion rules are included",
+			args: args{
+				enableProcessMetrics:   &trueVar,
+				excludeMetricsMatchers: config.IncludeMetricsMap{"process.name": []string{"kworker"}},
+				ffRetriever:            testFF.EmptyFFRetriever,
+				sample:                 &fixture.ProcessSample,
+			},
+			include: true,
+		},
+		{
+			name: "exclusion rules take precedence over include rules",
+			args: args{
+				includeMetricsMatchers: config.IncludeMetricsMap{"process.name": []string{"foo"}},
+				excludeMetricsMatchers: config.IncludeMetricsMap{"process.executable": []string{"regex \"^/usr/bin/\""}},
+				ffRetriever:            testFF.EmptyFFRetriever,
+				sample:                 &fixture.FlatProcessSample,
+			},
+			include: false,
+		},
+		{
+			name: "exclusion rules take precedence over feature flag",
+			args: args{
+				excludeMetricsMatchers: config.IncludeMetricsMap{sampler.ExpressionKey: []string{`userName == "baz"`}},
+				ffRetriever:            testFF.NewFFRetrieverReturning(true, true),
+				sample:                 &fixture.FlatProcessSample,
+			},
+			include: false,
+		},
+		{
+			name: "non process samples are not excluded by attribute rules",
+			args: args{
+				enableProcessMetrics:   &trueVar,
+				excludeMetricsMatchers: config.IncludeMetricsMap{"process.name": []string{"regex \".*\""}},
+				ffRetriever:            testFF.EmptyFFRetriever,
+				sample:                 &fixture.NetworkSample,
+			},
+			include: true,
+		},
+		{
+			name: "non process samples matching exclusion expressions are not included",
+			args: args{
+				enableProcessMetrics:   &falseVar,
+				excludeMetricsMatchers: config.IncludeMetricsMap{sampler.ExpressionKey: []string{`interfaceName == "eth0"`}},
+				ffRetriever:            testFF.EmptyFFRetriever,
+				sample:                 &fixture.NetworkSample,
+			},
+			include: false,
+		},
+		{
+			name: "exclusion attributes without rules are ignored",
+			args: args{
+				enableProcessMetrics:   &trueVar,
+				excludeMetricsMatchers: config.IncludeMetricsMap{"process.name": []string{}},
+				ffRetriever:            testFF.EmptyFFRetriever,
+				sample:                 &fixture.ProcessSample,
+			},
+			include: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchFn := sampler.NewSampleMatchFn(tt.args.enableProcessMetrics, tt.args.includeMetricsMatchers, tt.args.excludeMetricsMatchers, tt.args.ffRetriever)
 			assert.Equal(t, tt.include, matchFn(tt.args.sample))
 		})
 	}
@@ -223,25 +223,8 @@ func (ss *Sampler) Sample() (samples sample.EventBatch, err error) {
 
 		helpers.LogStructureDetails(sslog, fsUsage, "PartitionUsage", "raw", nil)
 
-		if cfg != nil && len(cfg.FileDevicesIgnored) > 0 {
-			found := false
-			fileDevicesIgnored := cfg.FileDevicesIgnored
-			sslog.WithField("fileDevicesIgnored", fileDevicesIgnored).Debug("Using file device ignored.")
-			for _, deviceName := range fileDevicesIgnored {
-				if strings.Contains(p.Device, deviceName) {
-					sslog.WithFieldsF(func() logrus.Fields {
-						return logrus.Fields{
-							"fileDeviceIgnored": deviceName,
-							"skippedDevice":     p.Device,
-						}
-					}).Debug("Skipping ignored device.")
-					found = true
-					break
-				}
-			}
-			if found {
-				continue
-			}
+		if isIgnoredDevice(p.Device, cfg) {
+			continue
 		}
 
 		s := &Sample{}
@@ -309,6 +292,12 @@ func (ss *Sampler) Sample() (samples sample.EventBatch, err error) {
 					}).Debug("No device mapping.")
 				}
 			}
+
+			if cfg != nil && cfg.EnableRawBlockDevicesMetrics {
+				for _, s := range ss.rawDeviceSamples(ioCounters, deviceToLogical, elapsedMs, cfg) {
+					dev2Samples[s.Device] = append(dev2Samples[s.Device], s)
+				}
+			}
 		}
 		ss.lastDiskStats = ioCounters
 	}
@@ -327,6 +316,56 @@ func (ss *Sampler) Sample() (samples sample.EventBatch, err error) {
 	return samples, nil
 }
 
+// rawDeviceSamples returns the samples of the block devices that are not mounted, like raw LVM or Ceph volumes,
+// which only report IO metrics.
+func (ss *Sampler) rawDeviceSamples(ioCounters map[string]IOCountersStat, deviceToLogical map[string]string, elapsedMs int64, cfg *config.Config) (samples []*Sample) {
+	for deviceKey, counter := range ioCounters {
+		if _, mounted := deviceToLogical[deviceKey]; mounted {
+			continue
+		}
+		lastStats, ok := ss.lastDiskStats[deviceKey]
+		if !ok {
+			continue
+		}
+		device, readOnly, ok := rawBlockDevice(deviceKey)
+		if !ok || isIgnoredDevice(device, cfg) {
+			continue
+		}
+
+		s := &Sample{}
+		s.Type("StorageSample")
+		s.ElapsedSampleDeltaMs = elapsedMs
+		s.Device = device
+		s.IsReadOnly = strconv.FormatBool(readOnly)
+		s.HasDelta = true
+		s.CountersSource = counter.Source()
+		populateSample(ss.storageUtilities.CalculateSampleValues(counter, lastStats, elapsedMs), s)
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+// isIgnoredDevice returns whether the device matches any of the file_devices_ignored configuration entries.
+func isIgnoredDevice(device string, cfg *config.Config) bool {
+	if cfg == nil || len(cfg.FileDevicesIgnored) == 0 {
+		return false
+	}
+	fileDevicesIgnored := cfg.FileDevicesIgnored
+	sslog.WithField("fileDevicesIgnored", fileDevicesIgnored).Debug("Using file device ignored.")
+	for _, deviceName := range fileDevicesIgnored {
+		if strings.Contains(device, deviceName) {
+			sslog.WithFieldsF(func() logrus.Fields {
+				return logrus.Fields{
+					"fileDeviceIgnored": deviceName,
+					"skippedDevice":     device,
+				}
+			}).Debug("Skipping ignored device.")
+			return true
+		}
+	}
+	return false
+}
+
 // PartitionsCache avoids polling for partitions on each sample, since they do not change so frequently
 type PartitionsCache struct {
 	ttl             time.Duration
@@ -127,3 +127,8 @@ func CalculateDeviceMapping(_ map[string]bool, _ bool) (deviceMap map[string]str
 	//intentionally left empty, IO per partition not supported yet in darwin
 	return
 }
+
+// rawBlockDevice always returns false, as block devices without file system are only reported on Linux.
+func rawBlockDevice(_ string) (device string, readOnly bool, ok bool) {
+	return "", false, false
+}
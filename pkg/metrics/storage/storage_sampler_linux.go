@@ -10,8 +10,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -56,6 +58,20 @@ type Sample struct {
 	InodesFree        *uint64  `json:"inodesFree,omitempty"`
 	InodesTotal       *uint64  `json:"inodesTotal,omitempty"`
 	InodesUsedPercent *float64 `json:"inodesUsedPercent,omitempty"`
+	// Average time, in milliseconds, spent by each operation completed during the sample period
+	ReadAwaitMs  *float64 `json:"readAwaitMs,omitempty"`
+	WriteAwaitMs *float64 `json:"writeAwaitMs,omitempty"`
+	// IOInProgress is the number of requests in flight when the sample was taken
+	IOInProgress *float64 `json:"ioInProgress,omitempty"`
+	// AverageQueueLength is the average number of requests queued or being served during the sample period
+	AverageQueueLength *float64 `json:"averageQueueLength,omitempty"`
+	// Discard metrics, reported by kernels 4.18+
+	DiscardsPerSec     *float64 `json:"discardIoPerSecond,omitempty"`
+	DiscardBytesPerSec *float64 `json:"discardBytesPerSecond,omitempty"`
+	DiscardAwaitMs     *float64 `json:"discardAwaitMs,omitempty"`
+	// Flush metrics, reported by kernels 5.5+
+	FlushesPerSec *float64 `json:"flushIoPerSecond,omitempty"`
+	FlushAwaitMs  *float64 `json:"flushAwaitMs,omitempty"`
 }
 
 // Enhanced from GOPSUtil, Adding Utilization
@@ -70,6 +86,15 @@ type LinuxIoCountersStat struct {
 	WriteTime               uint64 `json:"writeTime"`
 	IopsInProgress          uint64 `json:"iopsInProgress"`
 	IoTime                  uint64 `json:"ioTime"`
+	WeightedIoTime          uint64 `json:"weightedIoTime"`
+	DiscardCount            uint64 `json:"discardCount"`
+	MergedDiscardCount      uint64 `json:"mergedDiscardCount"`
+	DiscardBytes            uint64 `json:"discardBytes"`
+	DiscardTime             uint64 `json:"discardTime"`
+	FlushCount              uint64 `json:"flushCount"`
+	FlushTime               uint64 `json:"flushTime"`
+	HasDiscards             bool   `json:"-"`
+	HasFlushes              bool   `json:"-"`
 	Name                    string `json:"name"`
 	SerialNumber            string `json:"serialNumber"`
 	TotalUtilizationPercent uint64 `json:"totalUtilizationPercent"`
@@ -148,7 +173,16 @@ func NewStorageSampleWrapper(cfg *config.Config) SampleWrapper {
 
 // populateSampleOS complements the populateSample function by copying into the destinations the fields from the source
 // that are exclusive of Linux Storage Samples
-func populateSampleOS(_, _ *Sample) {
+func populateSampleOS(source, dest *Sample) {
+	dest.ReadAwaitMs = asValidFloatPtr(source.ReadAwaitMs)
+	dest.WriteAwaitMs = asValidFloatPtr(source.WriteAwaitMs)
+	dest.IOInProgress = asValidFloatPtr(source.IOInProgress)
+	dest.AverageQueueLength = asValidFloatPtr(source.AverageQueueLength)
+	dest.DiscardsPerSec = asValidFloatPtr(source.DiscardsPerSec)
+	dest.DiscardBytesPerSec = asValidFloatPtr(source.DiscardBytesPerSec)
+	dest.DiscardAwaitMs = asValidFloatPtr(source.DiscardAwaitMs)
+	dest.FlushesPerSec = asValidFloatPtr(source.FlushesPerSec)
+	dest.FlushAwaitMs = asValidFloatPtr(source.FlushAwaitMs)
 }
 
 // populateUsage copies the Usage Stats inside the destination sample, for those metrics that are exclusive of Linux
@@ -193,6 +227,27 @@ func CalculateSampleValues(ioCounter IOCountersStat, ioLastStats IOCountersStat,
 			result.WriteUtilizationPercent = &writeUtilizationPercent
 		}
 		result.TotalUtilizationPercent = &percentUtilized
+
+		averageQueueLength := float64(counter.WeightedIoTime-lastStats.WeightedIoTime) / float64(elapsedMs)
+		result.AverageQueueLength = &averageQueueLength
+	}
+
+	result.ReadAwaitMs = awaitMs(readTimeDelta, readCountDelta)
+	result.WriteAwaitMs = awaitMs(writeTimeDelta, writeCountDelta)
+	ioInProgress := float64(counter.IopsInProgress)
+	result.IOInProgress = &ioInProgress
+
+	if counter.HasDiscards && lastStats.HasDiscards {
+		discardsPerSec := acquire.CalculateSafeDelta(counter.DiscardCount, lastStats.DiscardCount, elapsedSeconds)
+		discardBytesPerSec := acquire.CalculateSafeDelta(counter.DiscardBytes, lastStats.DiscardBytes, elapsedSeconds)
+		result.DiscardsPerSec = &discardsPerSec
+		result.DiscardBytesPerSec = &discardBytesPerSec
+		result.DiscardAwaitMs = awaitMs(counter.DiscardTime-lastStats.DiscardTime, counter.DiscardCount-lastStats.DiscardCount)
+	}
+	if counter.HasFlushes && lastStats.HasFlushes {
+		flushesPerSec := acquire.CalculateSafeDelta(counter.FlushCount, lastStats.FlushCount, elapsedSeconds)
+		result.FlushesPerSec = &flushesPerSec
+		result.FlushAwaitMs = awaitMs(counter.FlushTime-lastStats.FlushTime, counter.FlushCount-lastStats.FlushCount)
 	}
 
 	readsPerSec := acquire.CalculateSafeDelta(counter.ReadCount, lastStats.ReadCount, elapsedSeconds)
@@ -210,6 +265,19 @@ func CalculateSampleValues(ioCounter IOCountersStat, ioLastStats IOCountersStat,
 	return result
 }
 
+// awaitMs returns the average time spent by each operation completed during the period. Counters may go back when
+// they overflow or the device is reset, in which case no value is returned.
+func awaitMs(timeDelta, countDelta uint64) *float64 {
+	if int64(timeDelta) < 0 || int64(countDelta) < 0 {
+		return nil
+	}
+	await := float64(0)
+	if countDelta > 0 {
+		await = float64(timeDelta) / float64(countDelta)
+	}
+	return &await
+}
+
 func parseMountFile(filename string, line string) (mi MountInfoStat, err error) {
 	switch filename {
 	case mountInfo:
@@ -593,6 +661,10 @@ func fetchIoCounters() (map[string]IOCountersStat, error) {
 		if err != nil {
 			return ret, err
 		}
+		weightedIoTime, err := strconv.ParseUint(fields[13], 10, 64)
+		if err != nil {
+			return ret, err
+		}
 		d := LinuxIoCountersStat{
 			ReadBytes:        rbytes * SectorSize,
 			WriteBytes:       wbytes * SectorSize,
@@ -604,6 +676,22 @@ func fetchIoCounters() (map[string]IOCountersStat, error) {
 			WriteTime:        wtime,
 			IopsInProgress:   iopsInProgress,
 			IoTime:           iotime,
+			WeightedIoTime:   weightedIoTime,
+		}
+		// discard stats are available since kernel 4.18
+		if len(fields) >= 18 {
+			if err := parseUints(fields[14:18], &d.DiscardCount, &d.MergedDiscardCount, &d.DiscardBytes, &d.DiscardTime); err != nil {
+				return ret, err
+			}
+			d.DiscardBytes *= SectorSize
+			d.HasDiscards = true
+		}
+		// flush stats are available since kernel 5.5
+		if len(fields) >= 20 {
+			if err := parseUints(fields[18:20], &d.FlushCount, &d.FlushTime); err != nil {
+				return ret, err
+			}
+			d.HasFlushes = true
 		}
 		if d == empty {
 			continue
@@ -616,6 +704,51 @@ func fetchIoCounters() (map[string]IOCountersStat, error) {
 	return ret, nil
 }
 
+// rawBlockDevice returns the device path of the diskstats device if it's a block device that holds data by itself,
+// e.g. a raw LVM or Ceph volume. Virtual devices (loop, ram), devices holding other devices, like LVM physical
+// volumes, and disks split in partitions are discarded, as their IO is reported through the devices on top of them.
+func rawBlockDevice(name string) (device string, readOnly bool, ok bool) {
+	if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+		return "", false, false
+	}
+
+	sysPath := helpers.HostSys("class", "block", name)
+	entries, err := ioutil.ReadDir(sysPath)
+	if err != nil {
+		return "", false, false
+	}
+	if holders, err := ioutil.ReadDir(filepath.Join(sysPath, "holders")); err == nil && len(holders) > 0 {
+		return "", false, false
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), name) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(sysPath, entry.Name(), "partition")); err == nil {
+			return "", false, false
+		}
+	}
+
+	device = "/dev/" + name
+	if dmName, err := ioutil.ReadFile(filepath.Join(sysPath, "dm", "name")); err == nil {
+		device = "/dev/mapper/" + strings.TrimSpace(string(dmName))
+	}
+	if ro, err := ioutil.ReadFile(filepath.Join(sysPath, "ro")); err == nil {
+		readOnly = strings.TrimSpace(string(ro)) == "1"
+	}
+	return device, readOnly, true
+}
+
+// parseUints parses each of the fields into the destination with the same index.
+func parseUints(fields []string, dest ...*uint64) (err error) {
+	for i, field := range fields {
+		if *dest[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetDiskSerialNumber returns Serial Number of given device or empty string
 // on error. Name of device is expected, eg. /dev/sda
 func GetDiskSerialNumber(name string) string {
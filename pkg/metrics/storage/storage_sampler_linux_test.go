@@ -441,3 +441,131 @@ func TestIgnoredDevice(t *testing.T) {
 	assert.EqualValues(t, usageTotal1, *sample.TotalBytes)
 	assert.EqualValues(t, usageFree1, *sample.FreeBytes)
 }
+
+func TestCalculateSampleValues_LatencyAndQueue(t *testing.T) {
+	lastStats := &LinuxIoCountersStat{
+		ReadCount: 100, WriteCount: 100, ReadTime: 1000, WriteTime: 1000, WeightedIoTime: 5000,
+		DiscardCount: 10, DiscardBytes: 4096, DiscardTime: 100, HasDiscards: true,
+		FlushCount: 5, FlushTime: 50, HasFlushes: true,
+	}
+	counter := &LinuxIoCountersStat{
+		ReadCount: 150, WriteCount: 110, ReadTime: 1500, WriteTime: 1200, WeightedIoTime: 7000, IopsInProgress: 3,
+		DiscardCount: 20, DiscardBytes: 8192, DiscardTime: 300, HasDiscards: true,
+		FlushCount: 5, FlushTime: 50, HasFlushes: true,
+	}
+
+	ioSample := CalculateSampleValues(counter, lastStats, 1000)
+
+	assert.Equal(t, float64(10), *ioSample.ReadAwaitMs)
+	assert.Equal(t, float64(20), *ioSample.WriteAwaitMs)
+	assert.Equal(t, float64(3), *ioSample.IOInProgress)
+	assert.Equal(t, float64(2), *ioSample.AverageQueueLength)
+	assert.Equal(t, float64(10), *ioSample.DiscardsPerSec)
+	assert.Equal(t, float64(4096), *ioSample.DiscardBytesPerSec)
+	assert.Equal(t, float64(20), *ioSample.DiscardAwaitMs)
+	assert.Equal(t, float64(0), *ioSample.FlushesPerSec)
+	assert.Equal(t, float64(0), *ioSample.FlushAwaitMs)
+
+	dest := &Sample{}
+	populateSample(ioSample, dest)
+	assert.Equal(t, ioSample.ReadAwaitMs, dest.ReadAwaitMs)
+	assert.Equal(t, ioSample.DiscardAwaitMs, dest.DiscardAwaitMs)
+}
+
+func TestCalculateSampleValues_OldKernel(t *testing.T) {
+	ioSample := CalculateSampleValues(&LinuxIoCountersStat{ReadCount: 10}, &LinuxIoCountersStat{}, 1000)
+
+	assert.Nil(t, ioSample.DiscardsPerSec)
+	assert.Nil(t, ioSample.DiscardAwaitMs)
+	assert.Nil(t, ioSample.FlushesPerSec)
+	assert.Equal(t, float64(0), *ioSample.ReadAwaitMs)
+}
+
+func TestFetchIoCounters_NewerKernelFields(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tmpproc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	diskstats := "   8       0 sda 100 1 800 50 200 2 1600 70 3 90 120\n" +
+		" 253       0 dm-0 100 1 800 50 200 2 1600 70 0 90 120 10 0 64 5\n" +
+		" 259       0 nvme0n1 100 1 800 50 200 2 1600 70 0 90 120 10 0 64 5 7 9\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, "diskstats"), []byte(diskstats), 0666))
+	os.Setenv("HOST_PROC", tmpDir)
+	defer os.Unsetenv("HOST_PROC")
+
+	counters, err := fetchIoCounters()
+	assert.NoError(t, err)
+	assert.Len(t, counters, 3)
+
+	sda := counters["sda"].(*LinuxIoCountersStat)
+	assert.Equal(t, uint64(3), sda.IopsInProgress)
+	assert.Equal(t, uint64(120), sda.WeightedIoTime)
+	assert.False(t, sda.HasDiscards)
+	assert.False(t, sda.HasFlushes)
+
+	dm := counters["dm-0"].(*LinuxIoCountersStat)
+	assert.True(t, dm.HasDiscards)
+	assert.False(t, dm.HasFlushes)
+	assert.Equal(t, uint64(10), dm.DiscardCount)
+	assert.Equal(t, uint64(64*SectorSize), dm.DiscardBytes)
+	assert.Equal(t, uint64(5), dm.DiscardTime)
+
+	nvme := counters["nvme0n1"].(*LinuxIoCountersStat)
+	assert.True(t, nvme.HasFlushes)
+	assert.Equal(t, uint64(7), nvme.FlushCount)
+	assert.Equal(t, uint64(9), nvme.FlushTime)
+}
+
+func TestRawBlockDevice(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tmpsys")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	block := filepath.Join(tmpDir, "class", "block")
+	mkdir := func(path ...string) {
+		assert.NoError(t, os.MkdirAll(filepath.Join(append([]string{block}, path...)...), 0755))
+	}
+	write := func(content string, path ...string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(append([]string{block}, path...)...), []byte(content), 0644))
+	}
+	// disk with partitions
+	mkdir("sda", "sda1")
+	write("1\n", "sda", "sda1", "partition")
+	mkdir("sda1", "holders")
+	write("1\n", "sda1", "partition")
+	// LVM physical volume
+	mkdir("sdb", "holders", "dm-0")
+	// LVM logical volume
+	mkdir("dm-0", "dm")
+	mkdir("dm-0", "holders")
+	write("vg-raw\n", "dm-0", "dm", "name")
+	write("1\n", "dm-0", "ro")
+	// Ceph block device
+	mkdir("rbd0", "holders")
+	write("0\n", "rbd0", "ro")
+
+	os.Setenv("HOST_SYS", tmpDir)
+	defer os.Unsetenv("HOST_SYS")
+
+	_, _, ok := rawBlockDevice("sda")
+	assert.False(t, ok)
+	_, _, ok = rawBlockDevice("sdb")
+	assert.False(t, ok)
+	_, _, ok = rawBlockDevice("loop0")
+	assert.False(t, ok)
+	_, _, ok = rawBlockDevice("unknown")
+	assert.False(t, ok)
+
+	device, readOnly, ok := rawBlockDevice("sda1")
+	assert.True(t, ok)
+	assert.Equal(t, "/dev/sda1", device)
+	assert.False(t, readOnly)
+
+	device, readOnly, ok = rawBlockDevice("dm-0")
+	assert.True(t, ok)
+	assert.Equal(t, "/dev/mapper/vg-raw", device)
+	assert.True(t, readOnly)
+
+	device, _, ok = rawBlockDevice("rbd0")
+	assert.True(t, ok)
+	assert.Equal(t, "/dev/rbd0", device)
+}
@@ -193,3 +193,8 @@ func populateSampleOS(source, dest *Sample) {
 // populateUsage copies the Usage Stats inside the destination sample, for those metrics that are exclusive of Windows
 func populateUsageOS(fsUsage *disk.UsageStat, dest *Sample) {
 }
+
+// rawBlockDevice always returns false, as block devices without file system are only reported on Linux.
+func rawBlockDevice(_ string) (device string, readOnly bool, ok bool) {
+	return "", false, false
+}
@@ -30,27 +30,33 @@ type SystemSample struct {
 	*LoadSample
 	*MemorySample
 	*DiskSample
+	*PressureSample
+	*CgroupSample
 }
 
 type SystemSampler struct {
-	CpuMonitor     *CPUMonitor
-	DiskMonitor    *DiskMonitor
-	LoadMonitor    *LoadMonitor
-	MemoryMonitor  *MemoryMonitor
-	context        agent.AgentContext
-	stopChannel    chan bool
-	waitForCleanup *sync.WaitGroup
+	CpuMonitor      *CPUMonitor
+	DiskMonitor     *DiskMonitor
+	LoadMonitor     *LoadMonitor
+	MemoryMonitor   *MemoryMonitor
+	PressureMonitor *PressureMonitor
+	CgroupMonitor   *CgroupMonitor
+	context         agent.AgentContext
+	stopChannel     chan bool
+	waitForCleanup  *sync.WaitGroup
 }
 
 func NewSystemSampler(context agent.AgentContext, storageSampler *storage.Sampler) *SystemSampler {
 	cfg := context.Config()
 	return &SystemSampler{
-		CpuMonitor:     NewCPUMonitor(context),
-		DiskMonitor:    NewDiskMonitor(storageSampler),
-		LoadMonitor:    NewLoadMonitor(),
-		MemoryMonitor:  NewMemoryMonitor(cfg.IgnoreReclaimable),
-		context:        context,
-		waitForCleanup: &sync.WaitGroup{},
+		CpuMonitor:      NewCPUMonitor(context),
+		DiskMonitor:     NewDiskMonitor(storageSampler),
+		LoadMonitor:     NewLoadMonitor(),
+		MemoryMonitor:   NewMemoryMonitor(cfg.IgnoreReclaimable),
+		PressureMonitor: NewPressureMonitor(),
+		CgroupMonitor:   NewCgroupMonitor(cfg.IsContainerized, cfg.CgroupPath),
+		context:         context,
+		waitForCleanup:  &sync.WaitGroup{},
 	}
 }
 
@@ -128,6 +134,22 @@ func (s *SystemSampler) Sample() (results sample.EventBatch, err error) {
 	}
 	seg.End()
 
+	ctx, seg = trx.StartSegment(ctx, "pressure sample")
+	if pressureSample, err := s.PressureMonitor.Sample(); err != nil {
+		syslog.WithError(err).Debug("Can't get pressure stall information.")
+	} else {
+		sample.PressureSample = pressureSample
+	}
+	seg.End()
+
+	ctx, seg = trx.StartSegment(ctx, "cgroup sample")
+	if cgroupSample, err := s.CgroupMonitor.Sample(); err != nil {
+		syslog.WithError(err).Debug("Can't get container cgroup stats.")
+	} else {
+		sample.CgroupSample = cgroupSample
+	}
+	seg.End()
+
 	if s.Debug() {
 		helpers.LogStructureDetails(syslog, sample, "SystemSample", "final", nil)
 	}
@@ -0,0 +1,33 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// ProcessGroupSample data type storing the aggregated data of the processes sharing the same value
+// for the GroupBy attribute.
+// Pointers are used as nil values represent no data.
+type ProcessGroupSample struct {
+	sample.BaseEvent
+	GroupBy               string   `json:"groupBy"`
+	CommandName           string   `json:"commandName,omitempty"`
+	User                  string   `json:"userName,omitempty"`
+	ContainerID           string   `json:"containerId,omitempty"`
+	ContainerName         string   `json:"containerName,omitempty"`
+	ContainerImageName    string   `json:"containerImageName,omitempty"`
+	ProcessCount          int      `json:"processCount"`
+	ThreadCount           int64    `json:"threadCount"`
+	MemoryRSSBytes        int64    `json:"memoryResidentSizeBytes"`
+	MemoryVMSBytes        int64    `json:"memoryVirtualSizeBytes"`
+	CPUPercent            float64  `json:"cpuPercent"`
+	CPUUserPercent        float64  `json:"cpuUserPercent"`
+	CPUSystemPercent      float64  `json:"cpuSystemPercent"`
+	FdCount               *int64   `json:"fileDescriptorCount,omitempty"`
+	IOReadCountPerSecond  *float64 `json:"ioReadCountPerSecond,omitempty"`
+	IOWriteCountPerSecond *float64 `json:"ioWriteCountPerSecond,omitempty"`
+	IOReadBytesPerSecond  *float64 `json:"ioReadBytesPerSecond,omitempty"`
+	IOWriteBytesPerSecond *float64 `json:"ioWriteBytesPerSecond,omitempty"`
+}
@@ -41,6 +41,21 @@ type ProcessSample struct {
 	IOTotalWriteCount     *uint64  `json:"ioTotalWriteCount,omitempty"`
 	IOTotalReadBytes      *uint64  `json:"ioTotalReadBytes,omitempty"`
 	IOTotalWriteBytes     *uint64  `json:"ioTotalWriteBytes,omitempty"`
+	// Network data, only harvested on Linux when enable_process_network_metrics is set
+	ListeningPorts      string `json:"listeningPorts,omitempty"`
+	UDPSocketCount      *int32 `json:"udpSocketCount,omitempty"`
+	TCPConnectionCount  *int32 `json:"tcpConnectionCount,omitempty"` // non-listening TCP sockets
+	TCPEstablishedCount *int32 `json:"tcpEstablishedCount,omitempty"`
+	TCPSynSentCount     *int32 `json:"tcpSynSentCount,omitempty"`
+	TCPSynRecvCount     *int32 `json:"tcpSynRecvCount,omitempty"`
+	TCPFinWait1Count    *int32 `json:"tcpFinWait1Count,omitempty"`
+	TCPFinWait2Count    *int32 `json:"tcpFinWait2Count,omitempty"`
+	TCPTimeWaitCount    *int32 `json:"tcpTimeWaitCount,omitempty"`
+	TCPCloseCount       *int32 `json:"tcpCloseCount,omitempty"`
+	TCPCloseWaitCount   *int32 `json:"tcpCloseWaitCount,omitempty"`
+	TCPLastAckCount     *int32 `json:"tcpLastAckCount,omitempty"`
+	TCPListenCount      *int32 `json:"tcpListenCount,omitempty"`
+	TCPClosingCount     *int32 `json:"tcpClosingCount,omitempty"`
 	// Auxiliary values, not to be reported
 	LastIOCounters  *process.IOCountersStat `json:"-"`
 	ContainerLabels map[string]string       `json:"-"`
@@ -0,0 +1,153 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sample
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot holds the attributes of the latest sample recorded for a given source.
+type Snapshot struct {
+	EventType  string
+	Attributes map[string]interface{}
+}
+
+type storeEntry struct {
+	snapshot Snapshot
+	updated  time.Time
+}
+
+// Store keeps the latest sample of each source (e.g. each process or each mount point), so they can be
+// exposed locally. Sources are identified by the event type, the entity key and a set of identity attributes
+// per event type. Samples not updated within the TTL are considered stale and are no longer returned.
+type Store struct {
+	lock       sync.Mutex
+	ttl        time.Duration
+	identities map[string][]string
+	entries    map[string]storeEntry
+	now        func() time.Time
+}
+
+// NewStore creates a store recording the event types present in the identities map, which holds the
+// attributes identifying each source for every event type.
+func NewStore(ttl time.Duration, identities map[string][]string) *Store {
+	return &Store{
+		ttl:        ttl,
+		identities: identities,
+		entries:    make(map[string]storeEntry),
+		now:        time.Now,
+	}
+}
+
+// Record stores the event as the latest sample of its source. Events of non tracked types are ignored
+// before being serialized.
+func (s *Store) Record(event Event) {
+	if eventType, ok := eventTypeOf(event); ok {
+		if _, tracked := s.identities[eventType]; !tracked {
+			return
+		}
+	}
+
+	content, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	var attributes map[string]interface{}
+	if err = json.Unmarshal(content, &attributes); err != nil {
+		return
+	}
+
+	eventType, _ := attributes["eventType"].(string)
+	identity, ok := s.identities[eventType]
+	if !ok {
+		return
+	}
+
+	key := []string{eventType, toString(attributes["entityKey"])}
+	for _, attr := range identity {
+		key = append(key, toString(attributes[attr]))
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.entries[strings.Join(key, "\x00")] = storeEntry{
+		snapshot: Snapshot{EventType: eventType, Attributes: attributes},
+		updated:  s.now(),
+	}
+}
+
+// Identity returns the attributes identifying the sources of an event type.
+func (s *Store) Identity(eventType string) []string {
+	return s.identities[eventType]
+}
+
+// Snapshots returns the latest non-stale samples, sorted by event type and source. Stale samples are removed.
+func (s *Store) Snapshots() []Snapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	keys := make([]string, 0, len(s.entries))
+	for key, entry := range s.entries {
+		if s.ttl > 0 && now.Sub(entry.updated) > s.ttl {
+			delete(s.entries, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	snapshots := make([]Snapshot, 0, len(keys))
+	for _, key := range keys {
+		snapshots = append(snapshots, s.entries[key].snapshot)
+	}
+	return snapshots
+}
+
+// eventTypeOf returns the event type of the events holding it in an EventType field (e.g. through BaseEvent) or
+// in an "eventType" map key. It returns false if the event type can only be known by serializing the event.
+func eventTypeOf(event Event) (string, bool) {
+	v := reflect.ValueOf(event)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field := v.FieldByName("EventType")
+		if field.IsValid() && field.Kind() == reflect.String {
+			return field.String(), true
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return "", false
+		}
+		value := v.MapIndex(reflect.ValueOf("eventType").Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return "", true
+		}
+		eventType, ok := value.Interface().(string)
+		return eventType, ok
+	}
+	return "", false
+}
+
+func toString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
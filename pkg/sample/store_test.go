@@ -0,0 +1,97 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sample
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+)
+
+type testEvent struct {
+	BaseEvent
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+func newTestEvent(eventType, name string, value float64) *testEvent {
+	e := &testEvent{Name: name, Value: value}
+	e.Type(eventType)
+	e.Entity("host")
+	return e
+}
+
+func TestStore_KeepsLatestSamplePerSource(t *testing.T) {
+	s := NewStore(0, map[string][]string{"TestSample": {"name"}})
+
+	s.Record(newTestEvent("TestSample", "a", 1))
+	s.Record(newTestEvent("TestSample", "b", 2))
+	s.Record(newTestEvent("TestSample", "a", 3))
+	s.Record(newTestEvent("IgnoredSample", "a", 4))
+
+	snapshots := s.Snapshots()
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, "TestSample", snapshots[0].EventType)
+	assert.Equal(t, "a", snapshots[0].Attributes["name"])
+	assert.Equal(t, float64(3), snapshots[0].Attributes["value"])
+	assert.Equal(t, "b", snapshots[1].Attributes["name"])
+}
+
+func TestStore_RemovesStaleSamples(t *testing.T) {
+	s := NewStore(time.Minute, map[string][]string{"TestSample": {"name"}})
+
+	now := time.Now()
+	s.now = func() time.Time { return now.Add(-2 * time.Minute) }
+	s.Record(newTestEvent("TestSample", "a", 1))
+	s.now = func() time.Time { return now }
+	s.Record(newTestEvent("TestSample", "b", 2))
+
+	snapshots := s.Snapshots()
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, "b", snapshots[0].Attributes["name"])
+}
+
+type countingEvent struct {
+	testEvent
+	marshalled *int
+}
+
+func (e *countingEvent) MarshalJSON() ([]byte, error) {
+	*e.marshalled++
+	return json.Marshal(e.testEvent)
+}
+
+type mapTestEvent map[string]interface{}
+
+func (m mapTestEvent) Type(eventType string)     { m["eventType"] = eventType }
+func (m mapTestEvent) Entity(key entity.Key)     { m["entityKey"] = key }
+func (m mapTestEvent) Timestamp(timestamp int64) { m["timestamp"] = timestamp }
+
+func TestStore_DoesNotSerializeIgnoredEvents(t *testing.T) {
+	s := NewStore(0, map[string][]string{"TestSample": {"name"}})
+
+	var marshalled int
+	s.Record(&countingEvent{testEvent: *newTestEvent("IgnoredSample", "a", 1), marshalled: &marshalled})
+	assert.Equal(t, 0, marshalled)
+	assert.Empty(t, s.Snapshots())
+
+	s.Record(&countingEvent{testEvent: *newTestEvent("TestSample", "a", 1), marshalled: &marshalled})
+	assert.Equal(t, 1, marshalled)
+	assert.Len(t, s.Snapshots(), 1)
+}
+
+func TestStore_RecordsMapEvents(t *testing.T) {
+	s := NewStore(0, map[string][]string{"TestSample": {"name"}})
+
+	s.Record(mapTestEvent{"eventType": "TestSample", "name": "a"})
+	s.Record(mapTestEvent{"eventType": "IgnoredSample", "name": "b"})
+	s.Record(mapTestEvent{"name": "c"})
+
+	snapshots := s.Snapshots()
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, "a", snapshots[0].Attributes["name"])
+}
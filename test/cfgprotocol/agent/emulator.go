@@ -118,7 +118,7 @@ func (ae *Emulator) RunAgent() error {
 	il := newInstancesLookup(ae.integrationCfg)
 	integrationEmitter := emitter.NewIntegrationEmittor(ae.agent, dmEmitter, ffManager)
 	pluginRegistry := &config2.PluginRegistry{}
-	integrationManager := v4.NewManager(ae.integrationCfg, integrationEmitter, il, definitionQ, terminateDefinitionQ, configEntryQ, tracker, ae.agent.Context.IDLookup(), pluginRegistry)
+	integrationManager := v4.NewManager(ae.integrationCfg, integrationEmitter, il, definitionQ, terminateDefinitionQ, configEntryQ, tracker, ae.agent.Context.IDLookup(), pluginRegistry, nil)
 
 	// Start all plugins we want the agent to run.
 	if err = plugins.RegisterPlugins(ae.agent); err != nil {